@@ -1,20 +1,41 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 
+	"WalletApp/commons"
 	"WalletApp/models/requests"
+	"WalletApp/models/responses"
 	"WalletApp/services"
 )
 
 // API grouping
 func setupAPIGroups(app *fiber.App, walletService services.WalletService, userService services.UserService) {
 	walletEndpoints := app.Group("/wallet/v1")
+	walletEndpoints.Post("/", createWalletHandler(walletService))
+	walletEndpoints.Get("/", getWalletsHandler(walletService))
 	walletEndpoints.Post("/:id/deposit", depositHandler(walletService))
 	walletEndpoints.Post("/:id/withdraw", withdrawHandler(walletService))
 	walletEndpoints.Post("/:id/transfer", transferHandler(walletService))
+	walletEndpoints.Post("/:id/quotes", createQuoteHandler(walletService))
+	walletEndpoints.Post("/:id/holds", createHoldHandler(walletService))
+	walletEndpoints.Post("/:id/holds/:holdId/capture", captureHoldHandler(walletService))
+	walletEndpoints.Post("/:id/holds/:holdId/void", voidHoldHandler(walletService))
 	walletEndpoints.Get("/:id/balance", getBalanceHandler(walletService))
 	walletEndpoints.Get("/:id/transactions", getTransactionsHandler(walletService))
+	walletEndpoints.Get("/:id/transactions/:txid", getTransactionHandler(walletService))
+	walletEndpoints.Post("/:id/transactions/:txid/reverse", reverseTransactionHandler(walletService))
+	walletEndpoints.Get("/:id/operations/:idempotencyToken", getOperationHandler(walletService))
+	walletEndpoints.Get("/:id/statements", getStatementHandler(walletService))
+	walletEndpoints.Get("/:id/export", exportTransactionsHandler(walletService))
 
 	// This is a helper API to create users and wallets if needed. Please do not evaluate these endpoints.
 	userManagementEndpoints := app.Group("/user-management/v1")
@@ -60,6 +81,10 @@ func withdrawHandler(walletService services.WalletService) fiber.Handler {
 			return badRequest(c, err.Error())
 		}
 
+		if req.QuoteID != "" {
+			resp := walletService.ExecuteQuote(ctx, req.IdempotencyToken, walletID, req.QuoteID, commons.TransactionTypeWithdraw, userID)
+			return response(c, resp)
+		}
 		resp := walletService.Withdraw(ctx, req.IdempotencyToken, walletID, req.Amount, userID)
 		return response(c, resp)
 	}
@@ -82,11 +107,116 @@ func transferHandler(walletService services.WalletService) fiber.Handler {
 			return badRequest(c, err.Error())
 		}
 
+		if req.QuoteID != "" {
+			resp := walletService.ExecuteQuote(ctx, req.IdempotencyToken, walletID, req.QuoteID, commons.TransactionTypeTransfer, userID)
+			return response(c, resp)
+		}
 		resp := walletService.Transfer(ctx, req.IdempotencyToken, walletID, req.ToAccount, req.Amount, userID)
 		return response(c, resp)
 	}
 }
 
+func createQuoteHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		var req requests.QuoteRequest
+		if err := payloadValidation(c, &req); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		resp := walletService.CreateQuote(ctx, walletID, req.ToAccount, commons.TransactionType(req.Type), req.Amount, userID)
+		return response(c, resp)
+	}
+}
+
+func createHoldHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		var req requests.HoldRequest
+		if err := payloadValidation(c, &req); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		ttl := commons.DefaultHoldTTL
+		if req.ExpiresIn > 0 {
+			ttl = time.Duration(req.ExpiresIn) * time.Second
+		}
+		resp := walletService.CreateHold(ctx, req.IdempotencyToken, walletID, req.ToAccount, req.Amount, ttl, userID)
+		return response(c, resp)
+	}
+}
+
+func captureHoldHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		holdID := c.Params("holdId")
+		if err := validateHoldID(holdID); err != nil {
+			return badRequest(c, err.Error())
+		}
+		var req requests.CaptureHoldRequest
+		if err := payloadValidation(c, &req); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		resp := walletService.CaptureHold(ctx, req.IdempotencyToken, walletID, holdID, req.Amount, userID)
+		return response(c, resp)
+	}
+}
+
+func voidHoldHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		holdID := c.Params("holdId")
+		if err := validateHoldID(holdID); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		var req requests.VoidHoldRequest
+		if err := payloadValidation(c, &req); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		resp := walletService.VoidHold(ctx, req.IdempotencyToken, walletID, holdID, userID)
+		return response(c, resp)
+	}
+}
+
 func getBalanceHandler(walletService services.WalletService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx := c.Context()
@@ -99,6 +229,14 @@ func getBalanceHandler(walletService services.WalletService) fiber.Handler {
 		if err := validateUUID(walletID); err != nil {
 			return badRequest(c, "invalid wallet ID: must be a valid UUID")
 		}
+		asOf, err := parseQueryAsOf(c.Query("as_of"))
+		if err != nil {
+			return badRequest(c, "invalid as_of: must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		if asOf != nil {
+			resp := walletService.GetBalanceAsOf(ctx, walletID, userID, *asOf)
+			return response(c, resp)
+		}
 		resp := walletService.GetBalance(ctx, walletID, userID)
 		return response(c, resp)
 	}
@@ -116,11 +254,195 @@ func getTransactionsHandler(walletService services.WalletService) fiber.Handler
 		if err := validateUUID(walletID); err != nil {
 			return badRequest(c, "invalid wallet ID: must be a valid UUID")
 		}
-		// Use query params for pagination
+		filter, err := parseTransactionFilter(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+		// Use query params for pagination, the offset is ignored when a cursor is given
 		limit := parseQueryInt(c.Query("limit"), 10)
+		if limit > commons.MaxHistoryLimit {
+			return badRequest(c, fmt.Sprintf("limit must not exceed %d", commons.MaxHistoryLimit))
+		}
 		offset := parseQueryInt(c.Query("offset"), 0)
 
-		resp := walletService.GetTransactionHistory(ctx, walletID, userID, limit, offset)
+		resp := walletService.GetTransactionHistory(ctx, walletID, userID, filter, limit, offset)
+		return response(c, resp)
+	}
+}
+
+func getTransactionHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		txnID := c.Params("txid")
+		if err := validateTransactionID(txnID); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		resp := walletService.GetTransaction(ctx, walletID, txnID, userID)
+		return response(c, resp)
+	}
+}
+
+func getOperationHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		idempotencyToken := c.Params("idempotencyToken")
+		if err := validateIdempotencyToken(idempotencyToken); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		resp := walletService.GetOperation(ctx, walletID, idempotencyToken, userID)
+		return response(c, resp)
+	}
+}
+
+func getStatementHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		periodStart, periodEnd, err := parsePeriod(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		resp := walletService.GetStatement(ctx, walletID, userID, periodStart, periodEnd)
+		return response(c, resp)
+	}
+}
+
+func exportTransactionsHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		periodStart, periodEnd, err := parsePeriod(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+		format := commons.ExportFormat(c.Query("format", string(commons.ExportFormatCSV)))
+
+		resp := walletService.ExportTransactions(ctx, walletID, userID, format, periodStart, periodEnd)
+		export, ok := resp.Data.(responses.ExportResponse)
+		if resp.Status != http.StatusOK || !ok {
+			return response(c, resp)
+		}
+
+		// The body is streamed once the handler returns, after the request context is released
+		c.Set(fiber.HeaderContentType, export.ContentType)
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, export.FileName))
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			streamCtx, cancel := context.WithTimeout(context.Background(), commons.ExportTimeout)
+			defer cancel()
+			if err := export.Write(streamCtx, w); err != nil {
+				log.Errorf("transaction export of wallet %s interrupted: %v", walletID, err)
+			}
+			if err := w.Flush(); err != nil {
+				log.Errorf("transaction export of wallet %s could not be flushed: %v", walletID, err)
+			}
+		})
+		return nil
+	}
+}
+
+func reverseTransactionHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		walletID := c.Params("id")
+		if err := validateUUID(walletID); err != nil {
+			return badRequest(c, "invalid wallet ID: must be a valid UUID")
+		}
+		txnID := c.Params("txid")
+		if err := validateTransactionID(txnID); err != nil {
+			return badRequest(c, err.Error())
+		}
+		var req requests.ReversalRequest
+		if err := payloadValidation(c, &req); err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		resp := walletService.ReverseTransaction(ctx, req.IdempotencyToken, walletID, txnID, req.Amount, userID)
+		return response(c, resp)
+	}
+}
+
+func createWalletHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+		if err := validateUserID(userID); err != nil {
+			return badRequest(c, err.Error())
+		}
+		// The payload is optional. Wallets are created in the default currency unless specified.
+		var req requests.CreateWalletRequest
+		if len(c.Body()) > 0 {
+			if err := payloadValidation(c, &req); err != nil {
+				return badRequest(c, err.Error())
+			}
+		}
+		if req.Currency == "" {
+			req.Currency = commons.DefaultCurrency
+		}
+		currency, err := commons.NormalizeCurrency(req.Currency)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+		resp := walletService.CreateWallet(ctx, userID, strings.TrimSpace(req.Label), currency)
+		return response(c, resp)
+	}
+}
+
+func getWalletsHandler(walletService services.WalletService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, err := getUserIDHeaderValue(c)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+		if err := validateUserID(userID); err != nil {
+			return badRequest(c, err.Error())
+		}
+		resp := walletService.GetWallets(ctx, userID)
 		return response(c, resp)
 	}
 }
@@ -136,7 +458,21 @@ func getWalletUsersHandler(userService services.UserService) fiber.Handler {
 func createUserAndWalletHandler(userService services.UserService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx := c.Context()
-		resp := userService.CreateUser(ctx)
+		// The payload is optional. Wallets are created in the default currency unless specified.
+		var req requests.CreateUserRequest
+		if len(c.Body()) > 0 {
+			if err := payloadValidation(c, &req); err != nil {
+				return badRequest(c, err.Error())
+			}
+		}
+		if req.Currency == "" {
+			req.Currency = commons.DefaultCurrency
+		}
+		currency, err := commons.NormalizeCurrency(req.Currency)
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+		resp := userService.CreateUser(ctx, currency)
 		return response(c, resp)
 	}
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -14,6 +15,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 
+	"WalletApp/commons"
+	"WalletApp/models"
 	"WalletApp/models/responses"
 	"WalletApp/services/mocks"
 )
@@ -48,6 +51,36 @@ func TestDepositHandler(t *testing.T) {
 		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 	})
 
+	t.Run("should return bad request if the amount has more decimal places than any currency allows", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/deposit",
+			strings.NewReader(`{"amount": "10.0005", "idempotency_token": "abcd-efgh-ijkl"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should pass string amounts to the service without losing precision", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+
+		mockWalletService.EXPECT().Deposit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Cond(func(amount commons.Money) bool {
+			return amount.Equal(commons.MustMoney("0.3"))
+		}), gomock.Any()).Return(responses.Response{Status: http.StatusOK})
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/deposit",
+			strings.NewReader(`{"amount": "0.30", "idempotency_token": "abcd-efgh-ijkl"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
 	t.Run("should return success response if the service provides success response", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -400,6 +433,475 @@ func TestTransferHandler(t *testing.T) {
 	})
 }
 
+func TestQuoteHandler(t *testing.T) {
+	t.Run("should return bad request if the quote type is not supported", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/quotes",
+			strings.NewReader(`{
+				  "type": "deposit",
+				  "amount": "100"
+				}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return bad request if a transfer quote has no recipient", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/quotes",
+			strings.NewReader(`{
+				  "type": "transfer",
+				  "amount": "100"
+				}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return created response if the service creates the quote", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().CreateQuote(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f",
+			"2cbcd158-56d2-4d45-8113-d51adf9ef57a", commons.TransactionTypeTransfer, commons.MustMoney("100"), "user-123").
+			Return(responses.Response{Status: http.StatusCreated, Message: "quote created successfully"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/quotes",
+			strings.NewReader(`{
+				  "type": "transfer",
+				  "amount": "100",
+				  "recipient_wallet_id": "2cbcd158-56d2-4d45-8113-d51adf9ef57a"
+				}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("should return bad request if an amount is sent with a quote id", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transfer",
+			strings.NewReader(`{
+				  "amount": "100",
+				  "quote_id": "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11",
+				  "idempotency_token": "gggg"
+				}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should execute the quote if a quote id is provided", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().ExecuteQuote(gomock.Any(), "unique-token-1", "2ad7eec6-51f3-409f-9e82-582a68417f6f",
+			"8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", commons.TransactionTypeTransfer, "user-123").
+			Return(responses.Response{Status: http.StatusOK, Message: "transfer successful"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transfer",
+			strings.NewReader(`{
+				  "quote_id": "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11",
+				  "idempotency_token": "unique-token-1"
+				}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestHoldHandlers(t *testing.T) {
+	t.Run("should return bad request if the hold expiry exceeds the maximum", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/holds",
+			strings.NewReader(`{
+				  "amount": "100",
+				  "idempotency_token": "unique-token-1",
+				  "expires_in_seconds": 2592001
+				}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should create the hold with the default expiry", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().CreateHold(gomock.Any(), "unique-token-1", "2ad7eec6-51f3-409f-9e82-582a68417f6f",
+			"", commons.MustMoney("100"), commons.DefaultHoldTTL, "user-123").
+			Return(responses.Response{Status: http.StatusCreated, Message: "hold created successfully"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/holds",
+			strings.NewReader(`{
+				  "amount": "100",
+				  "idempotency_token": "unique-token-1"
+				}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("should return bad request if the hold ID is not a UUID", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/holds/abc/capture",
+			strings.NewReader(`{"idempotency_token": "unique-token-1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should capture the full hold if no amount is provided", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().CaptureHold(gomock.Any(), "unique-token-1", "2ad7eec6-51f3-409f-9e82-582a68417f6f",
+			"8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", commons.ZeroMoney, "user-123").
+			Return(responses.Response{Status: http.StatusOK, Message: "hold captured successfully"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/holds/8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11/capture",
+			strings.NewReader(`{"idempotency_token": "unique-token-1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should void the hold", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().VoidHold(gomock.Any(), "unique-token-1", "2ad7eec6-51f3-409f-9e82-582a68417f6f",
+			"8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", "user-123").
+			Return(responses.Response{Status: http.StatusOK, Message: "hold voided successfully"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/holds/8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11/void",
+			strings.NewReader(`{"idempotency_token": "unique-token-1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should return bad request without calling the service if the void has no idempotency token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/holds/8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11/void",
+			strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestGetTransactionsFilters(t *testing.T) {
+	for name, query := range map[string]string{
+		"unknown type":         "type=deposit,refund",
+		"invalid date":         "from=2026-13-01",
+		"from after to":        "from=2026-09-30&to=2026-09-01",
+		"negative amount":      "min_amount=-1",
+		"min amount above max": "min_amount=10&max_amount=5",
+		"invalid counterparty": "counterparty_wallet_id=abc",
+		"unknown direction":    "direction=in",
+		"unknown sort":         "sort=newest",
+		"malformed cursor":     "cursor=not-a-cursor",
+		"negative after_seq":   "after_seq=-1",
+	} {
+		t.Run("should return bad request for "+name, func(t *testing.T) {
+			app := fiber.New()
+			setupAPIGroups(app, nil, nil)
+			req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions?"+query, nil)
+			req.Header.Set("X-User-ID", "user-123")
+			resp, _ := app.Test(req, -1)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+
+	t.Run("should pass the parsed filters to the service", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().GetTransactionHistory(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f", "user-123",
+			gomock.Cond(func(filter *models.TransactionFilter) bool {
+				return assert.ObjectsAreEqual([]string{"deposit", "transfer"}, filter.Types) &&
+					filter.From.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) &&
+					filter.To.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) &&
+					filter.MinAmount.Equal(commons.MustMoney("10.5")) && filter.MaxAmount == nil &&
+					filter.CounterpartyWalletID == "2cbcd158-56d2-4d45-8113-d51adf9ef57a" &&
+					filter.Direction == "credit" && filter.Ascending
+			}), int32(20), int32(0)).
+			Return(responses.Response{Status: http.StatusOK})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions?type=deposit,transfer"+
+			"&from=2026-09-01&to=2026-09-30&min_amount=10.5&counterparty_wallet_id=2cbcd158-56d2-4d45-8113-d51adf9ef57a"+
+			"&direction=credit&sort=asc&limit=20", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should pass the cursor and its sort order to the service", func(t *testing.T) {
+		cursor := &models.TransactionCursor{
+			CreatedAt: time.Date(2026, 9, 14, 10, 30, 0, 123456000, time.UTC),
+			ID:        "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11",
+			Ascending: true,
+			Backward:  true,
+		}
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().GetTransactionHistory(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f", "user-123",
+			gomock.Cond(func(filter *models.TransactionFilter) bool {
+				return filter.Ascending && filter.Cursor != nil && filter.Cursor.Backward &&
+					filter.Cursor.ID == cursor.ID && filter.Cursor.CreatedAt.Equal(cursor.CreatedAt)
+			}), int32(10), gomock.Any()).
+			Return(responses.Response{Status: http.StatusOK})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions?cursor="+cursor.Encode(), nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestGetStatementHandler(t *testing.T) {
+	for name, query := range map[string]string{
+		"missing period":        "",
+		"invalid month":         "period=2026-13",
+		"missing to":            "from=2026-09-01",
+		"from after to":         "from=2026-09-30&to=2026-09-01",
+		"more than a year long": "from=2025-01-01&to=2026-09-30",
+	} {
+		t.Run("should return bad request for "+name, func(t *testing.T) {
+			app := fiber.New()
+			setupAPIGroups(app, nil, nil)
+			req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/statements?"+query, nil)
+			req.Header.Set("X-User-ID", "user-123")
+			resp, _ := app.Test(req, -1)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+
+	for name, query := range map[string]string{
+		"a calendar month": "period=2026-09",
+		"a date range":     "from=2026-09-01&to=2026-09-30",
+	} {
+		t.Run("should request the statement of "+name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockWalletService := mocks.NewMockWalletService(ctrl)
+			mockWalletService.EXPECT().GetStatement(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f", "user-123",
+				time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)).
+				Return(responses.Response{Status: http.StatusOK, Message: "statement retrieval successful"})
+
+			app := fiber.New()
+			setupAPIGroups(app, mockWalletService, nil)
+			req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/statements?"+query, nil)
+			req.Header.Set("X-User-ID", "user-123")
+			resp, _ := app.Test(req, -1)
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+func TestExportTransactionsHandler(t *testing.T) {
+	t.Run("should return bad request for a missing period", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/export?format=ofx", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should pass the error response of the service through", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().ExportTransactions(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f", "user-123", commons.ExportFormat("xlsx"),
+			gomock.Any(), gomock.Any()).Return(responses.Response{Status: http.StatusBadRequest, Message: "format must be csv, ofx or camt053"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/export?format=xlsx&period=2026-09", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should stream the export as an attachment in CSV by default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().ExportTransactions(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f", "user-123", commons.ExportFormatCSV,
+			time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)).
+			Return(responses.Response{Status: http.StatusOK, Message: "transaction export ready", Data: responses.ExportResponse{
+				ContentType: "text/csv; charset=utf-8",
+				FileName:    "export.csv",
+				Write: func(_ context.Context, w io.Writer) error {
+					_, err := io.WriteString(w, "transaction_id\n")
+					return err
+				},
+			}})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/export?period=2026-09", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+		assert.Equal(t, `attachment; filename="export.csv"`, resp.Header.Get(fiber.HeaderContentDisposition))
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "transaction_id\n", string(body))
+	})
+}
+
+func TestGetTransactionHandler(t *testing.T) {
+	t.Run("should return bad request if the transaction ID is not a UUID", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions/abc", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return the transaction details", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().GetTransaction(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f",
+			"8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", "user-123").
+			Return(responses.Response{Status: http.StatusOK, Message: "transaction retrieval successful", Data: responses.TransactionDetailResponse{
+				TransactionHistory: &responses.TransactionHistory{ID: "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", Type: "deposit", Direction: "credit"},
+			}})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions/8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body, _ := io.ReadAll(resp.Body)
+		var payload struct {
+			Data map[string]any `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", payload.Data["id"])
+		assert.Equal(t, "credit", payload.Data["direction"])
+	})
+}
+
+func TestGetOperationHandler(t *testing.T) {
+	t.Run("should return bad request if the idempotency token is too short", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/operations/abc", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return the status of the operation", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().GetOperation(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f", "unique-token", "user-123").
+			Return(responses.Response{Status: http.StatusOK, Message: "operation retrieval successful", Data: responses.OperationResponse{
+				WalletID: "2ad7eec6-51f3-409f-9e82-582a68417f6f", IdempotencyToken: "unique-token", Status: "pending",
+			}})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/operations/unique-token", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body, _ := io.ReadAll(resp.Body)
+		var payload struct {
+			Data map[string]any `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, "pending", payload.Data["status"])
+	})
+}
+
+func TestReverseTransactionHandler(t *testing.T) {
+	t.Run("should return bad request if the transaction ID is not a UUID", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions/abc/reverse",
+			strings.NewReader(`{"idempotency_token": "unique-token-1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return bad request if the amount is negative", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions/8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11/reverse",
+			strings.NewReader(`{"amount": "-10", "idempotency_token": "unique-token-1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should reverse the transaction", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().ReverseTransaction(gomock.Any(), "unique-token-1", "2ad7eec6-51f3-409f-9e82-582a68417f6f",
+			"8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", commons.MustMoney("10"), "user-123").
+			Return(responses.Response{Status: http.StatusOK, Message: "reversal successful"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions/8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11/reverse",
+			strings.NewReader(`{"amount": "10", "idempotency_token": "unique-token-1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
 func TestGetTransactionsHandler(t *testing.T) {
 	t.Run("should return bad request if the user-id header is not present", func(t *testing.T) {
 		app := fiber.New()
@@ -410,13 +912,30 @@ func TestGetTransactionsHandler(t *testing.T) {
 		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 	})
 
+	t.Run("should return bad request without calling the service if the limit is too large", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		app := fiber.New()
+		setupAPIGroups(app, mocks.NewMockWalletService(ctrl), nil)
+
+		for _, limit := range []string{"101", "2147483647"} {
+			req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/transactions?limit="+limit, nil)
+			req.Header.Set("X-User-ID", "user-123")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal("error while initiating test api call", err)
+			}
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, limit)
+		}
+	})
+
 	t.Run("should return success response if the service provides success response", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockWalletService := mocks.NewMockWalletService(ctrl)
 		message := "success"
 
-		mockWalletService.EXPECT().GetTransactionHistory(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(responses.Response{
+		mockWalletService.EXPECT().GetTransactionHistory(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(responses.Response{
 			Status:  http.StatusOK,
 			Message: message,
 		})
@@ -452,7 +971,7 @@ func TestGetTransactionsHandler(t *testing.T) {
 		message := "invalid request"
 		expectedError := errors.New("test error")
 
-		mockWalletService.EXPECT().GetTransactionHistory(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(responses.Response{
+		mockWalletService.EXPECT().GetTransactionHistory(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(responses.Response{
 			Status:  http.StatusBadRequest,
 			Message: message,
 			Error:   expectedError,
@@ -564,4 +1083,131 @@ func TestWalletBalance(t *testing.T) {
 		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 		assert.Equal(t, message, bodyMap["message"])
 	})
+
+	t.Run("should return bad request if as_of is not a timestamp or a date", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/balance?as_of=yesterday", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return the balance at the end of the as_of day", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().GetBalanceAsOf(gomock.Any(), "2ad7eec6-51f3-409f-9e82-582a68417f6f", "user-123",
+			time.Date(2026, 9, 30, 23, 59, 59, 999999000, time.UTC)).
+			Return(responses.Response{Status: http.StatusOK, Message: "success"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/balance?as_of=2026-09-30", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should convert an as_of timestamp to UTC", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().GetBalanceAsOf(gomock.Any(), gomock.Any(), gomock.Any(),
+			time.Date(2026, 9, 30, 22, 0, 0, 0, time.UTC)).
+			Return(responses.Response{Status: http.StatusOK, Message: "success"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/2ad7eec6-51f3-409f-9e82-582a68417f6f/balance?as_of=2026-10-01T00:00:00%2B02:00", nil)
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestWalletsHandler(t *testing.T) {
+	t.Run("should return bad request if the user-id header is not present", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/", strings.NewReader(""))
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return bad request if the user id is not a valid UUID", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/", strings.NewReader(""))
+		req.Header.Set("X-User-ID", "user-123")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return bad request if the currency is not supported", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/", strings.NewReader(`{"currency": "XYZ"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "0a644be3-cdf9-4491-b4ba-1cd8974c0278")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return bad request if the label is too long", func(t *testing.T) {
+		app := fiber.New()
+		setupAPIGroups(app, nil, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/",
+			strings.NewReader(`{"label": "`+strings.Repeat("a", commons.MaxWalletLabelLength+1)+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "0a644be3-cdf9-4491-b4ba-1cd8974c0278")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should create a wallet in the default currency if no payload is provided", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().CreateWallet(gomock.Any(), "0a644be3-cdf9-4491-b4ba-1cd8974c0278", "", commons.DefaultCurrency).
+			Return(responses.Response{Status: http.StatusCreated, Message: "wallet created successfully"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/", strings.NewReader(""))
+		req.Header.Set("X-User-ID", "0a644be3-cdf9-4491-b4ba-1cd8974c0278")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("should pass the label and normalized currency to the service", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().CreateWallet(gomock.Any(), "0a644be3-cdf9-4491-b4ba-1cd8974c0278", "Savings", "EUR").
+			Return(responses.Response{Status: http.StatusCreated, Message: "wallet created successfully"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("POST", "/wallet/v1/", strings.NewReader(`{"label": " Savings ", "currency": "eur"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "0a644be3-cdf9-4491-b4ba-1cd8974c0278")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("should return the wallets of the user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockWalletService := mocks.NewMockWalletService(ctrl)
+		mockWalletService.EXPECT().GetWallets(gomock.Any(), "0a644be3-cdf9-4491-b4ba-1cd8974c0278").
+			Return(responses.Response{Status: http.StatusOK, Message: "wallet retrieval successful"})
+
+		app := fiber.New()
+		setupAPIGroups(app, mockWalletService, nil)
+		req := httptest.NewRequest("GET", "/wallet/v1/", strings.NewReader(""))
+		req.Header.Set("X-User-ID", "0a644be3-cdf9-4491-b4ba-1cd8974c0278")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
 }
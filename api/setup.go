@@ -1,21 +1,112 @@
 package api
 
 import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
 
+	"WalletApp/commons"
 	"WalletApp/config"
 	"WalletApp/db"
+	"WalletApp/models"
 	"WalletApp/services"
 )
 
 func Setup(app *fiber.App) {
-	pgClient := config.InitDB()
-	redisClient := config.InitRedis()
+	database, rateProvider, pgClient := newDatabase()
+	// The cache is kept in Redis unless CACHE_STORE=memory, which runs a single instance without Redis. The idempotency
+	// keys are then kept apart from the cache, so that they are never evicted to make room for wallets
+	var cache db.Cache
+	var idempotencyStore db.IdempotencyStore
+	if os.Getenv("CACHE_STORE") == "memory" {
+		cache = db.NewInMemoryCache(commons.InMemoryCacheMaxEntries)
+		idempotencyStore = db.NewInMemoryIdempotencyStore(commons.InMemoryIdempotencyMaxEntries)
+	} else {
+		cache = db.NewRedisCache(config.InitRedis())
+		idempotencyStore = db.NewCacheIdempotencyStore(cache)
+	}
+	if os.Getenv("RATE_PROVIDER") == "file" {
+		fileRateProvider, err := db.NewFileRateProvider(os.Getenv("RATE_FILE"))
+		if err != nil {
+			log.Fatalf("error loading exchange rates: %v", err)
+		}
+		rateProvider = fileRateProvider
+	}
+	// Idempotency keys are kept in Redis unless IDEMPOTENCY_STORE=postgres. Either way, the ledger writes commit their
+	// keys in Postgres for the retention, e.g. IDEMPOTENCY_RETENTION=720h
+	if os.Getenv("IDEMPOTENCY_STORE") == "postgres" {
+		if pgClient != nil {
+			idempotencyStore = db.NewPostgreSQLIdempotencyStore(pgClient)
+		} else {
+			log.Warnf("IDEMPOTENCY_STORE=postgres requires DB_DRIVER=postgres. idempotency keys are kept in the cache")
+		}
+	}
+	idempotencyRetention := commons.IdempotencyResponseTTL
+	if retention := os.Getenv("IDEMPOTENCY_RETENTION"); retention != "" {
+		duration, err := time.ParseDuration(retention)
+		if err != nil || duration <= 0 {
+			log.Warnf("cannot parse %s of IDEMPOTENCY_RETENTION. idempotency keys are kept for %s", retention, idempotencyRetention)
+		} else {
+			idempotencyRetention = duration
+		}
+	}
+	// Wallets, their balances and owners are served from Redis and written through by the ledger writes. The Bloom filter
+	// of the existing wallets rejects unknown recipients without a lookup when WALLET_FILTER=true
+	var walletFilter *db.WalletFilter
+	if enabled, _ := strconv.ParseBool(os.Getenv("WALLET_FILTER")); enabled {
+		walletFilter = db.NewWalletFilter(cache, commons.WalletFilterCapacity, commons.WalletFilterFalsePositiveRate)
+		go walletFilter.Run(context.Background(), database, commons.WalletFilterSeedTTL/2)
+	}
+	cachingDatabase := db.NewCachingDatabase(database, cache, walletFilter)
+	walletService := services.NewWalletServiceV1(cachingDatabase, idempotencyStore, idempotencyRetention, rateProvider)
+	userService := services.NewUserService(cachingDatabase)
+
+	// Reconcile the wallet balances periodically when an interval is configured, e.g. RECONCILIATION_INTERVAL=1h
+	if interval := os.Getenv("RECONCILIATION_INTERVAL"); interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil || duration <= 0 {
+			log.Warnf("cannot parse %s of RECONCILIATION_INTERVAL. scheduled reconciliation is disabled", interval)
+		} else {
+			reconciliationService := services.NewReconciliationService(database, ReconciliationReportsEnabled())
+			go services.RunReconciliationJob(context.Background(), reconciliationService, duration)
+		}
+	}
 
-	database := db.NewPostgreSQLDB(pgClient)
-	cache := db.NewRedisCache(redisClient)
-	walletService := services.NewWalletServiceV1(database, cache)
-	userService := services.NewUserService(database)
+	go services.RunHoldExpiryJob(context.Background(), database, commons.HoldExpiryInterval)
 
 	setupAPIGroups(app, walletService, userService)
 }
+
+// Reconcile runs the balance reconciliation once against the configured database. It is used by the
+// reconcile subcommand.
+func Reconcile(ctx context.Context, saveReport bool) (*models.ReconciliationReport, error) {
+	database, _, _ := newDatabase()
+	return services.NewReconciliationService(database, saveReport).Reconcile(ctx)
+}
+
+// newDatabase returns the database selected by DB_DRIVER and the exchange rates kept with it. The ledger is kept in
+// PostgreSQL unless DB_DRIVER=sqlite, which keeps it in the SQLITE_PATH file for a single node, or DB_DRIVER=memory,
+// which keeps it in the process with the sample data for a single instance and loses it on restart. The PostgreSQL
+// client is nil unless the ledger is kept in PostgreSQL.
+func newDatabase() (db.Database, db.RateProvider, *sqlx.DB) {
+	switch os.Getenv("DB_DRIVER") {
+	case "memory":
+		return db.NewInMemoryDB(), db.NewInMemoryRateProvider(db.SampleExchangeRates()), nil
+	case "sqlite":
+		sqliteClient := config.InitSQLite()
+		return db.NewSQLiteDB(sqliteClient), db.NewSQLiteRateProvider(sqliteClient), nil
+	}
+	pgClient := config.InitDB()
+	return db.NewPostgreSQLDB(pgClient), db.NewPostgreSQLRateProvider(pgClient), pgClient
+}
+
+// ReconciliationReportsEnabled reports whether RECONCILIATION_REPORTS asks for reports to be saved.
+func ReconciliationReportsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("RECONCILIATION_REPORTS"))
+	return enabled
+}
@@ -2,11 +2,17 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"WalletApp/commons"
+	"WalletApp/models"
 	"WalletApp/models/requests"
 	"WalletApp/models/responses"
 )
@@ -34,6 +40,9 @@ func payloadValidation[T any](c *fiber.Ctx, req *T) error {
 		}
 
 	case *requests.WithdrawRequest:
+		if r.QuoteID != "" {
+			return validateQuoteExecution(r.QuoteID, r.IdempotencyToken, r.Amount, "")
+		}
 		if err := validateAmount(r.Amount); err != nil {
 			return err
 		}
@@ -42,6 +51,9 @@ func payloadValidation[T any](c *fiber.Ctx, req *T) error {
 		}
 
 	case *requests.TransferRequest:
+		if r.QuoteID != "" {
+			return validateQuoteExecution(r.QuoteID, r.IdempotencyToken, r.Amount, r.ToAccount)
+		}
 		if err := validateAmount(r.Amount); err != nil {
 			return err
 		}
@@ -51,20 +63,96 @@ func payloadValidation[T any](c *fiber.Ctx, req *T) error {
 		if err := validateReceiverAccountInfo(r.ToAccount); err != nil {
 			return err
 		}
+
+	case *requests.CreateWalletRequest:
+		if utf8.RuneCountInString(strings.TrimSpace(r.Label)) > commons.MaxWalletLabelLength {
+			return fmt.Errorf("label cannot be longer than %d characters", commons.MaxWalletLabelLength)
+		}
+
+	case *requests.HoldRequest:
+		if err := validateAmount(r.Amount); err != nil {
+			return err
+		}
+		if err := validateIdempotencyToken(r.IdempotencyToken); err != nil {
+			return err
+		}
+		if r.ToAccount != "" {
+			if err := validateReceiverAccountInfo(r.ToAccount); err != nil {
+				return err
+			}
+		}
+		if maxSeconds := int64(commons.MaxHoldTTL / time.Second); r.ExpiresIn < 0 || r.ExpiresIn > maxSeconds {
+			return fmt.Errorf("expires_in_seconds must be between 1 and %d", maxSeconds)
+		}
+
+	case *requests.CaptureHoldRequest:
+		// A capture without amount captures the full held amount
+		if !r.Amount.IsZero() {
+			if err := validateAmount(r.Amount); err != nil {
+				return err
+			}
+		}
+		if err := validateIdempotencyToken(r.IdempotencyToken); err != nil {
+			return err
+		}
+
+	case *requests.VoidHoldRequest:
+		if err := validateIdempotencyToken(r.IdempotencyToken); err != nil {
+			return err
+		}
+
+	case *requests.ReversalRequest:
+		// A reversal without amount reverses the amount left to reverse
+		if !r.Amount.IsZero() {
+			if err := validateAmount(r.Amount); err != nil {
+				return err
+			}
+		}
+		if err := validateIdempotencyToken(r.IdempotencyToken); err != nil {
+			return err
+		}
+
+	case *requests.QuoteRequest:
+		if r.Type != string(commons.TransactionTypeTransfer) && r.Type != string(commons.TransactionTypeWithdraw) {
+			return errors.New("quote type must be either transfer or withdrawal")
+		}
+		if err := validateAmount(r.Amount); err != nil {
+			return err
+		}
+		if r.Type == string(commons.TransactionTypeTransfer) {
+			if err := validateReceiverAccountInfo(r.ToAccount); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
-func validateAmount(amount float64) error {
-	if amount <= 0 {
+func validateAmount(amount commons.Money) error {
+	if !amount.IsPositive() {
 		return errors.New("invalid amount")
 	}
-	if amount > 50000 {
+	if amount.GreaterThan(commons.MaxTransactionAmount) {
 		return errors.New("max amount to transfer is 50,000 in a single request")
 	}
+	if amount.DecimalPlaces() > commons.MaxAmountDecimalPlaces {
+		return fmt.Errorf("amount cannot have more than %d decimal places", commons.MaxAmountDecimalPlaces)
+	}
 	return nil
 }
 
+// validateQuoteExecution validates a withdrawal or transfer executed by quote. The amount and recipient are
+// taken from the quote, so they must not be provided in the payload.
+func validateQuoteExecution(quoteID string, token string, amount commons.Money, toAccount string) error {
+	if _, err := uuid.Parse(quoteID); err != nil {
+		return errors.New("invalid quote ID: must be a valid UUID")
+	}
+	if !amount.IsZero() || toAccount != "" {
+		return errors.New("amount and recipient_wallet_id must not be provided with quote_id")
+	}
+	return validateIdempotencyToken(token)
+}
+
 func validateReceiverAccountInfo(walletID string) error {
 	if walletID == "" {
 		return errors.New("receiver account info is empty")
@@ -85,6 +173,27 @@ func validateIdempotencyToken(token string) error {
 	return nil
 }
 
+func validateHoldID(holdID string) error {
+	if _, err := uuid.Parse(holdID); err != nil {
+		return errors.New("invalid hold ID: must be a valid UUID")
+	}
+	return nil
+}
+
+func validateTransactionID(txnID string) error {
+	if _, err := uuid.Parse(txnID); err != nil {
+		return errors.New("invalid transaction ID: must be a valid UUID")
+	}
+	return nil
+}
+
+func validateUserID(userID string) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return errors.New("invalid user ID: must be a valid UUID")
+	}
+	return nil
+}
+
 func validateUUID(walletID string) error {
 	if _, err := uuid.Parse(walletID); err != nil {
 		return errors.New("invalid wallet ID: must be a valid UUID")
@@ -92,6 +201,157 @@ func validateUUID(walletID string) error {
 	return nil
 }
 
+// parseTransactionFilter reads the transaction history filters from the query parameters. Dates are either
+// RFC 3339 timestamps or YYYY-MM-DD days, in which case the whole day of "to" is included.
+func parseTransactionFilter(c *fiber.Ctx) (*models.TransactionFilter, error) {
+	filter := &models.TransactionFilter{}
+	if types := c.Query("type"); types != "" {
+		for _, trsType := range strings.Split(types, ",") {
+			switch commons.TransactionType(trsType) {
+			case commons.TransactionTypeDeposit, commons.TransactionTypeWithdraw, commons.TransactionTypeTransfer:
+				filter.Types = append(filter.Types, trsType)
+			default:
+				return nil, errors.New("type must be deposit, withdrawal or transfer")
+			}
+		}
+	}
+
+	var err error
+	if filter.From, err = parseQueryTime(c.Query("from"), false); err != nil {
+		return nil, errors.New("invalid from: must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if filter.To, err = parseQueryTime(c.Query("to"), true); err != nil {
+		return nil, errors.New("invalid to: must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, errors.New("from must be before to")
+	}
+
+	if filter.MinAmount, err = parseQueryMoney(c.Query("min_amount")); err != nil {
+		return nil, errors.New("invalid min_amount")
+	}
+	if filter.MaxAmount, err = parseQueryMoney(c.Query("max_amount")); err != nil {
+		return nil, errors.New("invalid max_amount")
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && filter.MinAmount.GreaterThan(*filter.MaxAmount) {
+		return nil, errors.New("min_amount cannot be greater than max_amount")
+	}
+
+	if counterparty := c.Query("counterparty_wallet_id"); counterparty != "" {
+		if err := validateUUID(counterparty); err != nil {
+			return nil, errors.New("invalid counterparty_wallet_id: must be a valid UUID")
+		}
+		filter.CounterpartyWalletID = counterparty
+	}
+
+	switch direction := commons.TransactionDirection(c.Query("direction")); direction {
+	case "", commons.TransactionDirectionCredit, commons.TransactionDirectionDebit:
+		filter.Direction = string(direction)
+	default:
+		return nil, errors.New("direction must be credit or debit")
+	}
+
+	switch c.Query("sort", "desc") {
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+	default:
+		return nil, errors.New("sort must be asc or desc")
+	}
+
+	// A cursor keeps the sort order of the page it was taken from
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := models.DecodeTransactionCursor(value)
+		if err != nil || validateUUID(cursor.ID) != nil {
+			return nil, commons.InvalidCursorError
+		}
+		filter.Cursor = cursor
+		filter.Ascending = cursor.Ascending
+	}
+
+	// Incremental syncs return everything after a wallet sequence number, oldest first
+	if value := c.Query("after_seq"); value != "" {
+		seq, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seq < 0 {
+			return nil, errors.New("invalid after_seq: must be a non-negative integer")
+		}
+		if filter.Cursor != nil {
+			return nil, errors.New("after_seq cannot be combined with cursor")
+		}
+		filter.AfterSeq = &seq
+	}
+	return filter, nil
+}
+
+func parseQueryTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	// Timestamps are stored in UTC without time zone
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parsePeriod reads the period of a statement or export, either a calendar month (period=2026-09) or a from/to
+// range as accepted by the transaction history. Periods are in UTC and their end is exclusive.
+func parsePeriod(c *fiber.Ctx) (time.Time, time.Time, error) {
+	if period := c.Query("period"); period != "" {
+		start, err := time.Parse("2006-01", period)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid period: must be a YYYY-MM month")
+		}
+		return start, start.AddDate(0, 1, 0), nil
+	}
+
+	start, err := parseQueryTime(c.Query("from"), false)
+	if err != nil || start == nil {
+		return time.Time{}, time.Time{}, errors.New("period or from and to are required: from must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	end, err := parseQueryTime(c.Query("to"), true)
+	if err != nil || end == nil {
+		return time.Time{}, time.Time{}, errors.New("period or from and to are required: to must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if !start.Before(*end) {
+		return time.Time{}, time.Time{}, errors.New("from must be before to")
+	}
+	if end.Sub(*start) > commons.MaxReportingPeriod {
+		return time.Time{}, time.Time{}, errors.New("a period cannot cover more than a year")
+	}
+	return *start, *end, nil
+}
+
+// parseQueryAsOf reads an inclusive point in time. A YYYY-MM-DD date stands for the end of that day, i.e. the last
+// microsecond stored by the database before midnight.
+func parseQueryAsOf(value string) (*time.Time, error) {
+	t, err := parseQueryTime(value, false)
+	if err != nil || t == nil || value != t.Format(time.DateOnly) {
+		return t, err
+	}
+	endOfDay := t.AddDate(0, 0, 1).Add(-time.Microsecond)
+	return &endOfDay, nil
+}
+
+func parseQueryMoney(value string) (*commons.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := commons.NewMoneyFromString(value)
+	if err != nil || amount.IsNegative() {
+		return nil, errors.New("invalid amount")
+	}
+	return &amount, nil
+}
+
 func parseQueryInt(value string, defaultVal int32) int32 {
 	if i, err := strconv.Atoi(value); err == nil && i >= 0 {
 		return int32(i)
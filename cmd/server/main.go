@@ -11,7 +11,6 @@ import (
 )
 
 func main() {
-	app := fiber.New()
 	env := os.Getenv("GO_ENV")
 	envFile := ".env.local" // default
 	if env == "docker" {
@@ -21,6 +20,13 @@ func main() {
 	if err != nil {
 		log.Warnf("no env file loaded from %s err: %v", envFile, err)
 	}
+
+	// `wallet-app reconcile [-report]` checks the wallet balances once and exits
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(reconcile(os.Args[2:]))
+	}
+
+	app := fiber.New()
 	api.Setup(app)
 	err = app.Listen(":8080")
 	if err != nil {
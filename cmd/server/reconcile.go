@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"WalletApp/api"
+)
+
+// Exit codes of the reconcile subcommand so that it can be used in scripts and cron jobs
+const (
+	reconcileOK         = 0
+	reconcileMismatches = 1
+	reconcileFailed     = 2
+)
+
+// reconcile runs the balance reconciliation once and prints the report as JSON.
+func reconcile(args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	saveReport := flags.Bool("report", api.ReconciliationReportsEnabled(), "write the report to the reconciliation_reports table")
+	if err := flags.Parse(args); err != nil {
+		return reconcileFailed
+	}
+
+	report, err := api.Reconcile(context.Background(), *saveReport)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconciliation failed: %v\n", err)
+		return reconcileFailed
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print the reconciliation report: %v\n", err)
+		return reconcileFailed
+	}
+	if len(report.Mismatches) > 0 {
+		return reconcileMismatches
+	}
+	return reconcileOK
+}
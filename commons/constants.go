@@ -3,4 +3,79 @@ package commons
 import "time"
 
 const IdempotencyCacheTTL = 30 * time.Second
+
+// IdempotencyResponseTTL is how long the response of a request is replayed to retries with the same idempotency key.
+const IdempotencyResponseTTL = 24 * time.Hour
 const DBOperationTimeout = 5 * time.Second
+
+// WalletCacheTTL bounds how long a cached wallet is served without being written through again. Ledger writes update
+// the cached wallet.
+const WalletCacheTTL = 5 * time.Minute
+
+// HeldAmountCacheTTL bounds how long the amount reserved by the holds of a wallet is cached. Creating, voiding and
+// capturing holds clear it, and it is never cached past the expiry of the earliest active hold. The bound covers a
+// hold changed while the amount was read, which may be cached after it was cleared.
+const HeldAmountCacheTTL = 10 * time.Second
+
+// WalletOwnerCacheTTL is how long the owner of a wallet is cached. Wallets never change owner.
+const WalletOwnerCacheTTL = 24 * time.Hour
+
+// MissingWalletCacheTTL is how long a wallet ID is remembered not to exist. Creating the wallet clears it sooner.
+const MissingWalletCacheTTL = time.Minute
+
+// WalletFilterCapacity and WalletFilterFalsePositiveRate size the Bloom filter of the existing wallets. Past the
+// capacity the filter lets more missing wallets through to the database.
+const WalletFilterCapacity = 1_000_000
+const WalletFilterFalsePositiveRate = 0.01
+
+// WalletFilterSeedTTL is how long a seeded wallet filter is trusted. It is seeded again every half of it, so a wallet
+// that could not be added to the filter is reported missing for at most that long, and a filter no instance seeds
+// anymore is turned off.
+const WalletFilterSeedTTL = time.Hour
+
+// InMemoryCacheMaxEntries bounds the number of keys of the in-memory cache.
+const InMemoryCacheMaxEntries = 100_000
+
+// InMemoryIdempotencyMaxEntries bounds the number of idempotency keys kept in memory. They are not evicted, so it
+// bounds the requests within the idempotency retention.
+const InMemoryIdempotencyMaxEntries = 1_000_000
+
+// QuoteTTL is how long a transfer or withdrawal quote can be executed after it is created.
+const QuoteTTL = 60 * time.Second
+
+// DefaultHoldTTL is how long a hold reserves funds when no expiry is requested. Holds cannot exceed MaxHoldTTL.
+const DefaultHoldTTL = 7 * 24 * time.Hour
+const MaxHoldTTL = 30 * 24 * time.Hour
+
+// HoldExpiryInterval is how often expired holds are marked as expired. Expired holds stop reducing the
+// available balance as soon as they expire, regardless of this interval.
+const HoldExpiryInterval = time.Minute
+
+// MaxHistoryLimit is the largest page of the transaction history a request can ask for.
+const MaxHistoryLimit = 100
+
+// StatementPageSize is the number of transactions read at once while building a statement.
+const StatementPageSize = 500
+
+// StatementSettlementDelay is how long after the end of its period a statement is considered closed and persisted.
+// It covers transactions started before the end of the period that commit shortly after it.
+const StatementSettlementDelay = time.Minute
+
+// MaxReportingPeriod is the longest period a single statement or export can cover.
+const MaxReportingPeriod = 366 * 24 * time.Hour
+
+// ExportTimeout bounds the time spent streaming a transaction export.
+const ExportTimeout = 10 * time.Minute
+
+// MaxAmountDecimalPlaces is the maximum number of fractional digits accepted for an amount in any currency.
+// Currency specific precision is validated in the service layer once the wallet currency is known.
+const MaxAmountDecimalPlaces = 3
+
+// MaxWalletLabelLength is the maximum number of characters of a wallet label.
+const MaxWalletLabelLength = 64
+
+// MaxTransactionAmount is the maximum amount accepted in a single request.
+var MaxTransactionAmount = NewMoneyFromInt(50000)
+
+// FXFeeRate is the fee charged on cross-currency transfers as a fraction of the source amount.
+var FXFeeRate = MustRate("0.005")
@@ -0,0 +1,61 @@
+package commons
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const DefaultCurrency = "USD"
+
+// currencyDecimalPlaces maps the supported ISO 4217 currency codes to the number of
+// decimal places (minor units) allowed for amounts in that currency.
+var currencyDecimalPlaces = map[string]int32{
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"LKR": 2,
+	"OMR": 3,
+	"SGD": 2,
+	"USD": 2,
+}
+
+var UnsupportedCurrencyError = errors.New("unsupported currency")
+var CurrencyMismatchError = errors.New("cross-currency transfers are not supported")
+
+// NormalizeCurrency upper-cases a currency code and verifies that it is supported.
+func NormalizeCurrency(code string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := currencyDecimalPlaces[normalized]; !ok {
+		return "", fmt.Errorf("%w: %s", UnsupportedCurrencyError, code)
+	}
+	return normalized, nil
+}
+
+// CurrencyDecimalPlaces returns the number of decimal places allowed for the currency.
+func CurrencyDecimalPlaces(currency string) (int32, error) {
+	places, ok := currencyDecimalPlaces[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", UnsupportedCurrencyError, currency)
+	}
+	return places, nil
+}
+
+// ValidateAmountPrecision checks that the amount does not have more decimal places than the currency allows.
+func ValidateAmountPrecision(amount Money, currency string) error {
+	places, err := CurrencyDecimalPlaces(currency)
+	if err != nil {
+		return err
+	}
+	if amount.DecimalPlaces() > places {
+		return fmt.Errorf("amount cannot have more than %d decimal places for %s", places, currency)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package commons
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrency(t *testing.T) {
+	t.Run("should normalize supported currency codes", func(t *testing.T) {
+		currency, err := NormalizeCurrency(" eur ")
+		assert.Nil(t, err)
+		assert.Equal(t, "EUR", currency)
+	})
+
+	t.Run("should reject unsupported currency codes", func(t *testing.T) {
+		_, err := NormalizeCurrency("XYZ")
+		assert.ErrorIs(t, err, UnsupportedCurrencyError)
+	})
+
+	t.Run("should validate amount precision per currency", func(t *testing.T) {
+		assert.Nil(t, ValidateAmountPrecision(MustMoney("100"), "JPY"))
+		assert.NotNil(t, ValidateAmountPrecision(MustMoney("100.5"), "JPY"))
+		assert.Nil(t, ValidateAmountPrecision(MustMoney("10.25"), "USD"))
+		assert.NotNil(t, ValidateAmountPrecision(MustMoney("10.255"), "USD"))
+		assert.Nil(t, ValidateAmountPrecision(MustMoney("1.255"), "KWD"))
+		assert.NotNil(t, ValidateAmountPrecision(MustMoney("1.2555"), "KWD"))
+	})
+
+	t.Run("should reject amounts with a huge negative exponent quickly", func(t *testing.T) {
+		start := time.Now()
+		assert.NotNil(t, ValidateAmountPrecision(MustMoney("1e-100000000"), "USD"))
+		assert.Nil(t, ValidateAmountPrecision(MustMoney("1000e-3"), "JPY"))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,149 @@
+package commons
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Money is an exact decimal amount used for balances and transaction amounts.
+// It never goes through float64, scans losslessly from PostgreSQL NUMERIC columns
+// and is serialized to JSON as a string (e.g. "120.50").
+type Money struct {
+	d decimal.Decimal
+}
+
+var ZeroMoney = Money{}
+
+var InvalidMoneyError = errors.New("invalid money amount")
+
+// NewMoneyFromString parses a decimal string such as "120.50" into Money.
+func NewMoneyFromString(value string) (Money, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %s", InvalidMoneyError, value)
+	}
+	return Money{d: d}, nil
+}
+
+// MustMoney parses a decimal string and panics on failure. Intended for constants and tests.
+func MustMoney(value string) Money {
+	m, err := NewMoneyFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// NewMoneyFromInt creates Money from a whole number of major units.
+func NewMoneyFromInt(value int64) Money {
+	return Money{d: decimal.NewFromInt(value)}
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{d: m.d.Add(other.d)}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{d: m.d.Sub(other.d)}
+}
+
+func (m Money) Neg() Money {
+	return Money{d: m.d.Neg()}
+}
+
+func (m Money) Cmp(other Money) int {
+	return m.d.Cmp(other.d)
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.d.Equal(other.d)
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.d.LessThan(other.d)
+}
+
+func (m Money) GreaterThan(other Money) bool {
+	return m.d.GreaterThan(other.d)
+}
+
+func (m Money) IsPositive() bool {
+	return m.d.IsPositive()
+}
+
+func (m Money) IsNegative() bool {
+	return m.d.IsNegative()
+}
+
+func (m Money) IsZero() bool {
+	return m.d.IsZero()
+}
+
+// Prorate returns the part/whole share of the amount rounded half away from zero to the given number of
+// decimal places. Used to split a converted amount across partial operations.
+func (m Money) Prorate(part, whole Money, places int32) Money {
+	return Money{d: m.d.Mul(part.d).Div(whole.d).Round(places)}
+}
+
+// DecimalPlaces returns the number of significant fractional digits, ignoring trailing zeros.
+// For example "10.50" has one decimal place and "10.00" has none. It is derived from the exponent,
+// so that amounts such as "1e-20000" are measured in time linear in their length.
+func (m Money) DecimalPlaces() int32 {
+	exponent := int64(m.d.Exponent())
+	if exponent >= 0 || m.d.IsZero() {
+		return 0
+	}
+	coefficient := m.d.Coefficient().String()
+	trailingZeros := len(coefficient) - len(strings.TrimRight(coefficient, "0"))
+	places := -exponent - int64(trailingZeros)
+	if places <= 0 {
+		return 0
+	}
+	if places > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(places)
+}
+
+// String returns the canonical decimal representation without trailing zeros.
+func (m Money) String() string {
+	return m.d.String()
+}
+
+// StringFixed returns the amount rounded to the given number of decimal places.
+func (m Money) StringFixed(places int32) string {
+	return m.d.StringFixed(places)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + m.d.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts both quoted decimal strings ("12.50") and bare JSON numbers (12.50).
+// Bare numbers are parsed from their literal text so no float rounding happens.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var d decimal.Decimal
+	if err := d.UnmarshalJSON(data); err != nil {
+		return fmt.Errorf("%w: %s", InvalidMoneyError, string(data))
+	}
+	m.d = d
+	return nil
+}
+
+// Scan implements sql.Scanner so NUMERIC columns are read without going through float64.
+func (m *Money) Scan(value any) error {
+	return m.d.Scan(value)
+}
+
+// Value implements driver.Valuer and sends the amount as an exact decimal string.
+func (m Money) Value() (driver.Value, error) {
+	return m.d.String(), nil
+}
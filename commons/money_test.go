@@ -0,0 +1,69 @@
+package commons
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("should add decimal amounts without floating point drift", func(t *testing.T) {
+		sum := MustMoney("0.1").Add(MustMoney("0.2"))
+		assert.True(t, sum.Equal(MustMoney("0.3")))
+		assert.Equal(t, "0.3", sum.String())
+	})
+
+	t.Run("should count significant decimal places ignoring trailing zeros", func(t *testing.T) {
+		assert.Equal(t, int32(0), MustMoney("10").DecimalPlaces())
+		assert.Equal(t, int32(0), MustMoney("10.00").DecimalPlaces())
+		assert.Equal(t, int32(1), MustMoney("10.50").DecimalPlaces())
+		assert.Equal(t, int32(3), MustMoney("0.005").DecimalPlaces())
+		assert.Equal(t, int32(0), MustMoney("1.5e3").DecimalPlaces())
+		assert.Equal(t, int32(1), MustMoney("150e-2").DecimalPlaces())
+	})
+
+	t.Run("should count decimal places of huge negative exponents without iterating over them", func(t *testing.T) {
+		start := time.Now()
+		assert.Equal(t, int32(20000), MustMoney("1e-20000").DecimalPlaces())
+		assert.Equal(t, int32(100000000), MustMoney("1e-100000000").DecimalPlaces())
+		assert.Equal(t, int32(0), MustMoney("10000e-4").DecimalPlaces())
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should prorate an amount and round to the given places", func(t *testing.T) {
+		assert.True(t, MustMoney("92.15").Prorate(MustMoney("1"), MustMoney("3"), 2).Equal(MustMoney("30.72")))
+		assert.True(t, MustMoney("92.15").Prorate(MustMoney("3"), MustMoney("3"), 2).Equal(MustMoney("92.15")))
+	})
+
+	t.Run("should unmarshal both quoted strings and bare numbers", func(t *testing.T) {
+		var payload struct {
+			Quoted Money `json:"quoted"`
+			Bare   Money `json:"bare"`
+		}
+		err := json.Unmarshal([]byte(`{"quoted": "12.34", "bare": 0.1}`), &payload)
+		assert.Nil(t, err)
+		assert.True(t, payload.Quoted.Equal(MustMoney("12.34")))
+		assert.True(t, payload.Bare.Equal(MustMoney("0.1")))
+	})
+
+	t.Run("should reject invalid amounts", func(t *testing.T) {
+		var m Money
+		assert.NotNil(t, json.Unmarshal([]byte(`"abc"`), &m))
+		_, err := NewMoneyFromString("1,00")
+		assert.ErrorIs(t, err, InvalidMoneyError)
+	})
+
+	t.Run("should marshal amounts as JSON strings", func(t *testing.T) {
+		data, err := json.Marshal(MustMoney("250.50"))
+		assert.Nil(t, err)
+		assert.Equal(t, `"250.5"`, string(data))
+	})
+
+	t.Run("should scan numeric database values losslessly", func(t *testing.T) {
+		var m Money
+		assert.Nil(t, m.Scan([]byte("12345678901234567.89")))
+		assert.Equal(t, "12345678901234567.89", m.String())
+	})
+}
@@ -0,0 +1,68 @@
+package commons
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Rate is an exact decimal exchange rate. One unit of the base currency equals Rate units of the quote currency.
+type Rate struct {
+	d decimal.Decimal
+}
+
+// NewRateFromString parses a decimal string such as "0.9215" into a Rate.
+func NewRateFromString(value string) (Rate, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid exchange rate: %s", value)
+	}
+	return Rate{d: d}, nil
+}
+
+// MustRate parses a decimal string and panics on failure. Intended for constants and tests.
+func MustRate(value string) Rate {
+	r, err := NewRateFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r Rate) IsPositive() bool {
+	return r.d.IsPositive()
+}
+
+func (r Rate) Equal(other Rate) bool {
+	return r.d.Equal(other.d)
+}
+
+func (r Rate) String() string {
+	return r.d.String()
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + r.d.String() + `"`), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	return r.d.UnmarshalJSON(data)
+}
+
+func (r *Rate) Scan(value any) error {
+	return r.d.Scan(value)
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.d.String(), nil
+}
+
+// MulRate multiplies the amount by the given rate and rounds the result half away from zero
+// to the given number of decimal places. Used for currency conversion and percentage fees.
+func (m Money) MulRate(rate Rate, places int32) Money {
+	return Money{d: m.d.Mul(rate.d).Round(places)}
+}
@@ -10,4 +10,74 @@ const (
 	TransactionTypeTransfer TransactionType = "transfer"
 )
 
+// OperationAction is an idempotent operation on holds and transactions other than recording a transaction of a given
+// type. Transaction types and actions name the operation in the fingerprint of idempotent requests.
+type OperationAction string
+
+const (
+	OperationActionHold     OperationAction = "hold"
+	OperationActionCapture  OperationAction = "capture"
+	OperationActionVoid     OperationAction = "void"
+	OperationActionReversal OperationAction = "reversal"
+)
+
+// TransactionDirection tells whether a transaction credits or debits a given wallet.
+type TransactionDirection string
+
+const (
+	TransactionDirectionCredit TransactionDirection = "credit"
+	TransactionDirectionDebit  TransactionDirection = "debit"
+)
+
+// OperationStatus is the outcome of the request made with an idempotency token.
+type OperationStatus string
+
+const (
+	OperationStatusPending   OperationStatus = "pending"
+	OperationStatusCompleted OperationStatus = "completed"
+	OperationStatusFailed    OperationStatus = "failed" // rejected, or never applied
+)
+
+// ExportFormat is a file format of transaction exports.
+type ExportFormat string
+
+const (
+	ExportFormatCSV     ExportFormat = "csv"
+	ExportFormatOFX     ExportFormat = "ofx"     // OFX 2.2 bank statement
+	ExportFormatCAMT053 ExportFormat = "camt053" // ISO 20022 camt.053.001.08 bank to customer statement
+)
+
+// LedgerAccountType identifies the owner of a ledger account. Every wallet has a wallet account, while system
+// accounts record money entering or leaving the platform, collected fees and currency conversions per currency.
+type LedgerAccountType string
+
+const (
+	LedgerAccountWallet  LedgerAccountType = "wallet"
+	LedgerAccountCashIn  LedgerAccountType = "cash_in"
+	LedgerAccountCashOut LedgerAccountType = "cash_out"
+	LedgerAccountFees    LedgerAccountType = "fees"
+	LedgerAccountFX      LedgerAccountType = "fx"
+)
+
+// HoldStatus is the state of a fund hold. Active holds reduce the available balance of the wallet until they
+// are captured, voided or expire.
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusVoided   HoldStatus = "voided"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
 var InsufficientBalanceError = errors.New("insufficient balance error")
+var QuoteExpiredError = errors.New("quote expired")
+var QuoteAlreadyExecutedError = errors.New("quote already executed")
+var HoldNotActiveError = errors.New("hold is not active")
+var HoldExpiredError = errors.New("hold expired")
+var TransactionAlreadyReversedError = errors.New("transaction is already fully reversed")
+var ReversalAmountExceededError = errors.New("reversal amount exceeds the amount left to reverse")
+var HoldAmountExceededError = errors.New("capture amount exceeds the held amount")
+var InvalidCursorError = errors.New("invalid cursor")
+var DuplicateRequestError = errors.New("idempotency key already used by a committed request")
+var CacheFullError = errors.New("cache is full")
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jmoiron/sqlx"
+
+	"WalletApp/db"
+	"WalletApp/migration"
+)
+
+func InitSQLite() *sqlx.DB {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		log.Warnf("SQLITE_PATH is not set. using default wallet.db")
+		path = "wallet.db"
+	}
+
+	DB, err := sqlx.Connect(db.SQLiteDriverName, db.SQLiteDataSourceName(path))
+	if err != nil {
+		log.Fatalf("error opening sqlite db: %v", err)
+	}
+
+	if _, err = DB.Exec(migration.SQLiteSchema); err != nil {
+		log.Fatalf("error migrating sqlite db: %v", err)
+	}
+
+	fmt.Println("connected to SQLite")
+	return DB
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"WalletApp/commons"
+)
+
+// walletFilterSeedBatch is the number of wallets whose bits are set at once while seeding the filter.
+const walletFilterSeedBatch = 1000
+
+// WalletFilter is a Bloom filter of the existing wallet IDs kept as a bitmap in the cache, so that it is shared by
+// all instances. It never reports an existing wallet as missing once seeded, and reports a missing wallet as
+// existing with the false positive rate it was sized for. Until it is seeded, e.g. after the cache was flushed, every
+// wallet might exist.
+type WalletFilter struct {
+	cache  Cache
+	key    string
+	bits   uint64
+	hashes int
+}
+
+// NewWalletFilter returns a Bloom filter sized for the capacity and false positive rate. The filter has to be seeded
+// before it rejects any wallet.
+func NewWalletFilter(cache Cache, capacity uint64, falsePositiveRate float64) *WalletFilter {
+	bits := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Max(1, math.Round(float64(bits)/float64(capacity)*math.Ln2)))
+	return &WalletFilter{
+		cache: cache,
+		// the size is part of the key so that a resized filter is seeded again
+		key:    fmt.Sprintf("wallet-filter-%d-%d", bits, hashes),
+		bits:   bits,
+		hashes: hashes,
+	}
+}
+
+// Seed adds all existing wallets to the filter and marks it as seeded for commons.WalletFilterSeedTTL. Wallets created
+// meanwhile add themselves.
+func (f *WalletFilter) Seed(ctx context.Context, database Database) error {
+	wallets, err := database.GetWalletUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the wallets: %w", err)
+	}
+	offsets := make([]uint64, 0, walletFilterSeedBatch*f.hashes)
+	for i, wallet := range wallets {
+		offsets = append(offsets, f.offsets(wallet.ID)...)
+		if (i+1)%walletFilterSeedBatch == 0 || i == len(wallets)-1 {
+			if err := f.cache.SetBits(ctx, f.key, offsets); err != nil {
+				return fmt.Errorf("failed to add the wallets to the filter: %w", err)
+			}
+			offsets = offsets[:0]
+		}
+	}
+	return f.cache.SetWithExpiration(ctx, f.readyKey(), "1", commons.WalletFilterSeedTTL)
+}
+
+// Run seeds the filter at once and then every interval until the context is done, so that wallets that could not be
+// added are added back and the filter is trusted again after being turned off.
+func (f *WalletFilter) Run(ctx context.Context, database Database, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := f.Seed(ctx, database); err != nil {
+			log.Errorf("error seeding the wallet filter. unknown wallets are looked up in the database: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Add adds the wallet to the filter.
+func (f *WalletFilter) Add(ctx context.Context, walletID string) error {
+	return f.cache.SetBits(ctx, f.key, f.offsets(walletID))
+}
+
+// Invalidate marks the filter as not seeded, so that every wallet might exist until it is seeded again. It is used
+// when a wallet could not be added, since the filter must never report an existing wallet as missing.
+func (f *WalletFilter) Invalidate(ctx context.Context) error {
+	return f.cache.Delete(ctx, f.readyKey())
+}
+
+// MightExist returns false only if the wallet does not exist.
+func (f *WalletFilter) MightExist(ctx context.Context, walletID string) (bool, error) {
+	_, seeded, err := f.cache.Get(ctx, f.readyKey())
+	if err != nil || !seeded {
+		return true, err
+	}
+	return f.cache.AreBitsSet(ctx, f.key, f.offsets(walletID))
+}
+
+func (f *WalletFilter) readyKey() string {
+	return f.key + ":ready"
+}
+
+// offsets returns the bits of the wallet, derived from two hashes of its ID by double hashing.
+func (f *WalletFilter) offsets(walletID string) []uint64 {
+	h1 := fnv.New64a()
+	_, _ = h1.Write([]byte(walletID))
+	h2 := fnv.New64()
+	_, _ = h2.Write([]byte(walletID))
+	sum1, sum2 := h1.Sum64(), h2.Sum64()|1
+
+	offsets := make([]uint64, f.hashes)
+	for i := range offsets {
+		offsets[i] = (sum1 + uint64(i)*sum2) % f.bits
+	}
+	return offsets
+}
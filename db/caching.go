@@ -0,0 +1,305 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"WalletApp/commons"
+	"WalletApp/models"
+)
+
+// cachingDatabase serves wallets, and so their balances, from the cache. Ledger writes write the wallets they
+// change through to the cache once committed, versioned by the wallet sequence number so that an out-of-order
+// write never replaces a newer balance. Holds do not change the sequence number, so the amount they reserve is cached
+// apart from the wallet: creating, voiding or capturing a hold clears it, and it expires with the earliest active hold.
+// The available balance is computed from both on read.
+//
+// The owners of the wallets are cached as well, with an empty owner for wallets that do not exist. Wallets never
+// change owner, and creating a wallet clears what was cached about its ID. The optional filter rejects most missing
+// wallets without a lookup. Cache errors are logged and the database is used instead.
+type cachingDatabase struct {
+	Database
+	cache  Cache
+	filter *WalletFilter
+}
+
+// NewCachingDatabase returns a Database serving wallets and their owners from the cache and the rest from the
+// database. filter can be nil.
+func NewCachingDatabase(database Database, cache Cache, filter *WalletFilter) Database {
+	return &cachingDatabase{Database: database, cache: cache, filter: filter}
+}
+
+func walletCacheKey(walletID string) string {
+	return fmt.Sprintf("wallet-%s", walletID)
+}
+
+func walletOwnerCacheKey(walletID string) string {
+	return fmt.Sprintf("wallet-owner-%s", walletID)
+}
+
+func heldAmountCacheKey(walletID string) string {
+	return fmt.Sprintf("wallet-held-%s", walletID)
+}
+
+func (c *cachingDatabase) CheckWalletOwner(ctx context.Context, walletID string, userID string) (bool, error) {
+	owner, found, err := c.cache.Get(ctx, walletOwnerCacheKey(walletID))
+	if err != nil {
+		log.Errorf("failed to read the owner of wallet %s from the cache: %v", walletID, err)
+	}
+	if found {
+		return owner != "" && owner == userID, nil
+	}
+
+	wallet, _, err := c.lookupWallet(ctx, walletID)
+	if err != nil || wallet == nil {
+		return false, err
+	}
+	return wallet.UserID == userID, nil
+}
+
+func (c *cachingDatabase) GetWallet(ctx context.Context, walletID string) (*models.Wallet, error) {
+	wallet, cached, err := c.lookupWallet(ctx, walletID)
+	if err != nil || wallet == nil || !cached {
+		return wallet, err
+	}
+	held, err := c.heldAmount(ctx, walletID)
+	if err != nil {
+		log.Errorf("failed to read the holds of cached wallet %s: %v", walletID, err)
+		return c.Database.GetWallet(ctx, walletID)
+	}
+	wallet.AvailableBalance = wallet.Balance.Sub(held)
+	return wallet, nil
+}
+
+// heldAmount returns the amount reserved by the active holds of the wallet from the cache, or from the database. It
+// is cached until the earliest active hold expires, and for at most commons.HeldAmountCacheTTL since a hold changed
+// concurrently with the read may be cached after the change cleared it.
+func (c *cachingDatabase) heldAmount(ctx context.Context, walletID string) (commons.Money, error) {
+	value, found, err := c.cache.Get(ctx, heldAmountCacheKey(walletID))
+	if err != nil {
+		log.Errorf("failed to read the held amount of wallet %s from the cache: %v", walletID, err)
+	}
+	if found {
+		held, err := commons.NewMoneyFromString(value)
+		if err == nil {
+			return held, nil
+		}
+		log.Errorf("failed to decode the cached held amount of wallet %s: %v", walletID, err)
+	}
+
+	held, expiresAt, err := c.Database.GetHeldAmount(ctx, walletID)
+	if err != nil {
+		return commons.ZeroMoney, err
+	}
+	ttl := commons.HeldAmountCacheTTL
+	if expiresAt != nil && time.Until(*expiresAt) < ttl {
+		ttl = time.Until(*expiresAt)
+	}
+	if ttl > 0 {
+		if err := c.cache.SetWithExpiration(ctx, heldAmountCacheKey(walletID), held.String(), ttl); err != nil {
+			log.Errorf("failed to cache the held amount of wallet %s: %v", walletID, err)
+		}
+	}
+	return held, nil
+}
+
+func (c *cachingDatabase) CreateHold(ctx context.Context, hold *models.Hold, ttl time.Duration) (*models.Hold, error) {
+	created, err := c.Database.CreateHold(ctx, hold, ttl)
+	// the commit may have succeeded regardless of the error
+	c.invalidateHeldAmount(ctx, hold.WalletID)
+	return created, err
+}
+
+func (c *cachingDatabase) VoidHold(ctx context.Context, holdID string, key *models.IdempotencyKey) (*models.Hold, error) {
+	voided, err := c.Database.VoidHold(ctx, holdID, key)
+	// without the hold its wallet is unknown, a void hidden by an error is picked up once the held amount expires
+	if voided != nil {
+		c.invalidateHeldAmount(ctx, voided.WalletID)
+	}
+	return voided, err
+}
+
+// lookupWallet returns the wallet from the cache, without its available balance, or from the database. cached
+// reports whether it was served from the cache.
+func (c *cachingDatabase) lookupWallet(ctx context.Context, walletID string) (wallet *models.Wallet, cached bool, err error) {
+	value, found, err := c.cache.Get(ctx, walletCacheKey(walletID))
+	if err != nil {
+		log.Errorf("failed to read wallet %s from the cache: %v", walletID, err)
+	}
+	if found {
+		var cachedWallet models.Wallet
+		if err := json.Unmarshal([]byte(value), &cachedWallet); err == nil {
+			return &cachedWallet, true, nil
+		}
+		log.Errorf("failed to decode cached wallet %s: %v", walletID, err)
+	}
+	if c.isMissingWallet(ctx, walletID) {
+		return nil, false, nil
+	}
+
+	wallet, err = c.Database.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, false, err
+	}
+	if wallet == nil {
+		c.cacheOwner(ctx, walletID, "", commons.MissingWalletCacheTTL)
+		return nil, false, nil
+	}
+	c.cacheWallet(ctx, wallet)
+	c.cacheOwner(ctx, walletID, wallet.UserID, commons.WalletOwnerCacheTTL)
+	return wallet, false, nil
+}
+
+func (c *cachingDatabase) CreateWallet(ctx context.Context, userID string, label string, currency string) (*models.Wallet, error) {
+	wallet, err := c.Database.CreateWallet(ctx, userID, label, currency)
+	if err != nil || wallet == nil {
+		return wallet, err
+	}
+	c.addWallet(ctx, wallet)
+	return wallet, nil
+}
+
+func (c *cachingDatabase) CreateUserWallet(ctx context.Context, currency string) (*models.Wallet, error) {
+	wallet, err := c.Database.CreateUserWallet(ctx, currency)
+	if err != nil || wallet == nil {
+		return wallet, err
+	}
+	c.addWallet(ctx, wallet)
+	return wallet, nil
+}
+
+func (c *cachingDatabase) GetWalletBalance(ctx context.Context, walletID string) (commons.Money, error) {
+	wallet, _, err := c.lookupWallet(ctx, walletID)
+	if err != nil || wallet == nil {
+		return c.Database.GetWalletBalance(ctx, walletID)
+	}
+	return wallet.Balance, nil
+}
+
+func (c *cachingDatabase) InsertTxnAndGetWalletBalance(ctx context.Context, txn *models.Transaction) (commons.Money, error) {
+	balance, err := c.Database.InsertTxnAndGetWalletBalance(ctx, txn)
+	walletIDs := []string{txn.WalletID}
+	if txn.ToWalletID.Valid {
+		walletIDs = append(walletIDs, txn.ToWalletID.String)
+	}
+	if txn.HoldID.Valid {
+		// capturing releases the hold, whether or not the error hides a commit
+		c.invalidateHeldAmount(ctx, txn.WalletID)
+	}
+	if err != nil {
+		// the commit may have succeeded regardless, so the wallets are read again on the next request
+		for _, walletID := range walletIDs {
+			c.invalidateWallet(ctx, walletID)
+		}
+		return balance, err
+	}
+	c.writeThroughBalance(ctx, txn)
+	if txn.ToWalletID.Valid {
+		// only the balance of the source wallet is returned by the write
+		c.refreshWallet(ctx, txn.ToWalletID.String)
+	}
+	return balance, nil
+}
+
+// writeThroughBalance writes the balance and sequence number left on the source wallet of a committed transaction
+// through to the cached wallet. The wallet is read again when it is not cached, so that the cache keeps the new
+// sequence number and rejects older snapshots read concurrently.
+func (c *cachingDatabase) writeThroughBalance(ctx context.Context, txn *models.Transaction) {
+	walletID := txn.WalletID
+	value, found, err := c.cache.Get(ctx, walletCacheKey(walletID))
+	if err != nil {
+		log.Errorf("failed to read wallet %s from the cache: %v", walletID, err)
+	}
+	var wallet models.Wallet
+	if !found || !txn.Seq.Valid || txn.BalanceAfter == nil || json.Unmarshal([]byte(value), &wallet) != nil {
+		c.refreshWallet(ctx, walletID)
+		return
+	}
+	wallet.Balance, wallet.Seq, wallet.UpdatedAt = *txn.BalanceAfter, txn.Seq.Int64, txn.CreatedAt
+	c.cacheWallet(ctx, &wallet)
+}
+
+// refreshWallet writes the committed state of the wallet through to the cache.
+func (c *cachingDatabase) refreshWallet(ctx context.Context, walletID string) {
+	wallet, err := c.Database.GetWallet(ctx, walletID)
+	if err != nil || wallet == nil {
+		log.Errorf("failed to read wallet %s to refresh the cache: %v", walletID, err)
+		c.invalidateWallet(ctx, walletID)
+		return
+	}
+	c.cacheWallet(ctx, wallet)
+}
+
+func (c *cachingDatabase) cacheWallet(ctx context.Context, wallet *models.Wallet) {
+	cached := *wallet
+	cached.AvailableBalance = commons.ZeroMoney // depends on the holds, computed on read
+	value, err := json.Marshal(cached)
+	if err != nil {
+		log.Errorf("failed to encode wallet %s for the cache: %v", wallet.ID, err)
+		return
+	}
+	// a rejected value is older than the cached one, e.g. read before a concurrent write was committed
+	_, err = c.cache.SetWithExpirationIfVersionIsNotOlder(ctx, walletCacheKey(wallet.ID), string(value), wallet.Seq, commons.WalletCacheTTL)
+	if err != nil {
+		log.Errorf("failed to cache wallet %s: %v", wallet.ID, err)
+	}
+}
+
+// isMissingWallet reports whether the wallet is known not to exist, from the cache or the filter.
+func (c *cachingDatabase) isMissingWallet(ctx context.Context, walletID string) bool {
+	owner, found, err := c.cache.Get(ctx, walletOwnerCacheKey(walletID))
+	if err != nil {
+		log.Errorf("failed to read the owner of wallet %s from the cache: %v", walletID, err)
+	}
+	if found {
+		return owner == ""
+	}
+	if c.filter == nil {
+		return false
+	}
+	mightExist, err := c.filter.MightExist(ctx, walletID)
+	if err != nil {
+		log.Errorf("failed to look up wallet %s in the filter: %v", walletID, err)
+		return false
+	}
+	return !mightExist
+}
+
+func (c *cachingDatabase) cacheOwner(ctx context.Context, walletID string, owner string, duration time.Duration) {
+	if err := c.cache.SetWithExpiration(ctx, walletOwnerCacheKey(walletID), owner, duration); err != nil {
+		log.Errorf("failed to cache the owner of wallet %s: %v", walletID, err)
+	}
+}
+
+// addWallet makes a created wallet visible to the existence checks before it is returned to the client. A wallet
+// that cannot be added to the filter turns the filter off until it is seeded again, otherwise the wallet would be
+// reported missing once its owner expires from the cache. The wallet is committed either way, so if the filter cannot
+// be turned off either, the wallet may be reported missing until the filter is seeded again, at most
+// commons.WalletFilterSeedTTL later.
+func (c *cachingDatabase) addWallet(ctx context.Context, wallet *models.Wallet) {
+	if c.filter != nil {
+		if err := c.filter.Add(ctx, wallet.ID); err != nil {
+			log.Errorf("failed to add wallet %s to the filter. unknown wallets are looked up in the database: %v", wallet.ID, err)
+			if err := c.filter.Invalidate(ctx); err != nil {
+				log.Errorf("failed to turn the filter off. wallet %s may be reported missing until the filter is seeded again: %v", wallet.ID, err)
+			}
+		}
+	}
+	c.cacheOwner(ctx, wallet.ID, wallet.UserID, commons.WalletOwnerCacheTTL)
+}
+
+func (c *cachingDatabase) invalidateWallet(ctx context.Context, walletID string) {
+	if err := c.cache.Delete(ctx, walletCacheKey(walletID)); err != nil {
+		log.Errorf("failed to invalidate cached wallet %s: %v", walletID, err)
+	}
+}
+
+func (c *cachingDatabase) invalidateHeldAmount(ctx context.Context, walletID string) {
+	if err := c.cache.Delete(ctx, heldAmountCacheKey(walletID)); err != nil {
+		log.Errorf("failed to invalidate the cached held amount of wallet %s: %v", walletID, err)
+	}
+}
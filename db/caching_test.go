@@ -0,0 +1,379 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"WalletApp/commons"
+	"WalletApp/db"
+	"WalletApp/db/mocks"
+	"WalletApp/models"
+)
+
+func TestCachingDatabase(t *testing.T) {
+	walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+	toWalletID := "2cbcd158-56d2-4d45-8113-d51adf9ef57a"
+	cacheKey := "wallet-" + walletID
+	ownerCacheKey := "wallet-owner-" + walletID
+	heldCacheKey := "wallet-held-" + walletID
+	wallet := &models.Wallet{ID: walletID, Currency: "EUR", Balance: commons.MustMoney("100"), AvailableBalance: commons.MustMoney("80"), Seq: 7}
+	// the available balance depends on the holds and is not cached
+	cachedWallet := *wallet
+	cachedWallet.AvailableBalance = commons.ZeroMoney
+	cached, _ := json.Marshal(cachedWallet)
+
+	t.Run("should serve the wallet balance from the cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return(string(cached), true, nil).Times(2)
+		cacheMock.EXPECT().Get(gomock.Any(), heldCacheKey).Return("20", true, nil)
+
+		database := db.NewCachingDatabase(databaseMock, cacheMock, nil)
+		got, err := database.GetWallet(context.Background(), walletID)
+		assert.Nil(t, err)
+		assert.Equal(t, wallet.Seq, got.Seq)
+		assert.True(t, wallet.AvailableBalance.Equal(got.AvailableBalance))
+		balance, err := database.GetWalletBalance(context.Background(), walletID)
+		assert.Nil(t, err)
+		assert.True(t, wallet.Balance.Equal(balance))
+	})
+
+	t.Run("should fall back to the database and cache the wallet with its sequence number", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return("", false, errors.New("connection refused"))
+		cacheMock.EXPECT().Get(gomock.Any(), ownerCacheKey).Return("", false, errors.New("connection refused"))
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(wallet, nil)
+		cacheMock.EXPECT().SetWithExpirationIfVersionIsNotOlder(gomock.Any(), cacheKey, string(cached), int64(7), commons.WalletCacheTTL).Return(true, nil)
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), ownerCacheKey, wallet.UserID, commons.WalletOwnerCacheTTL).Return(nil)
+
+		got, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).GetWallet(context.Background(), walletID)
+		assert.Nil(t, err)
+		assert.Equal(t, wallet, got)
+	})
+
+	t.Run("should remember missing wallets", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		gomock.InOrder(
+			cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return("", false, nil),
+			cacheMock.EXPECT().Get(gomock.Any(), ownerCacheKey).Return("", false, nil),
+			databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(nil, nil),
+			cacheMock.EXPECT().SetWithExpiration(gomock.Any(), ownerCacheKey, "", commons.MissingWalletCacheTTL).Return(nil),
+			cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return("", false, nil),
+			cacheMock.EXPECT().Get(gomock.Any(), ownerCacheKey).Return("", true, nil),
+		)
+
+		database := db.NewCachingDatabase(databaseMock, cacheMock, nil)
+		for range 2 {
+			got, err := database.GetWallet(context.Background(), walletID)
+			assert.Nil(t, err)
+			assert.Nil(t, got)
+		}
+	})
+
+	t.Run("should check the wallet owner from the cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cacheMock := mocks.NewMockCache(ctrl)
+		cacheMock.EXPECT().Get(gomock.Any(), ownerCacheKey).Return("0a644be3-cdf9-4491-b4ba-1cd8974c0278", true, nil).Times(2)
+		cacheMock.EXPECT().Get(gomock.Any(), "wallet-owner-"+toWalletID).Return("", true, nil)
+
+		database := db.NewCachingDatabase(mocks.NewMockDatabase(ctrl), cacheMock, nil)
+		isOwner, err := database.CheckWalletOwner(context.Background(), walletID, "0a644be3-cdf9-4491-b4ba-1cd8974c0278")
+		assert.Nil(t, err)
+		assert.True(t, isOwner)
+		isOwner, err = database.CheckWalletOwner(context.Background(), walletID, "abe1f04a-8ab5-4f4c-b5d4-0e6a7f1fc3a2")
+		assert.Nil(t, err)
+		assert.False(t, isOwner)
+		// the wallet does not exist
+		isOwner, err = database.CheckWalletOwner(context.Background(), toWalletID, "0a644be3-cdf9-4491-b4ba-1cd8974c0278")
+		assert.Nil(t, err)
+		assert.False(t, isOwner)
+	})
+
+	t.Run("should look up the wallet owner on a cache miss", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		owned := &models.Wallet{ID: walletID, UserID: "0a644be3-cdf9-4491-b4ba-1cd8974c0278", Currency: "EUR", Seq: 7}
+		cacheMock.EXPECT().Get(gomock.Any(), ownerCacheKey).Return("", false, nil).Times(2)
+		cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return("", false, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(owned, nil)
+		cacheMock.EXPECT().SetWithExpirationIfVersionIsNotOlder(gomock.Any(), cacheKey, gomock.Any(), int64(7), commons.WalletCacheTTL).Return(true, nil)
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), ownerCacheKey, owned.UserID, commons.WalletOwnerCacheTTL).Return(nil)
+
+		isOwner, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).CheckWalletOwner(context.Background(), walletID, owned.UserID)
+		assert.Nil(t, err)
+		assert.True(t, isOwner)
+	})
+
+	t.Run("should reject wallets missing from the filter without a lookup", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cacheMock := mocks.NewMockCache(ctrl)
+		filter := db.NewWalletFilter(cacheMock, 1000, 0.01)
+		cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return("", false, nil)
+		cacheMock.EXPECT().Get(gomock.Any(), ownerCacheKey).Return("", false, nil)
+		cacheMock.EXPECT().Get(gomock.Any(), gomock.Cond(func(key string) bool {
+			return strings.HasPrefix(key, "wallet-filter-") && strings.HasSuffix(key, ":ready")
+		})).Return("1", true, nil)
+		cacheMock.EXPECT().AreBitsSet(gomock.Any(), gomock.Any(), gomock.Len(7)).Return(false, nil)
+
+		got, err := db.NewCachingDatabase(mocks.NewMockDatabase(ctrl), cacheMock, filter).GetWallet(context.Background(), walletID)
+		assert.Nil(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should make created wallets visible to the existence checks", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		filter := db.NewWalletFilter(cacheMock, 1000, 0.01)
+		created := &models.Wallet{ID: walletID, UserID: "0a644be3-cdf9-4491-b4ba-1cd8974c0278", Currency: "EUR"}
+		databaseMock.EXPECT().CreateUserWallet(gomock.Any(), "EUR").Return(created, nil)
+		cacheMock.EXPECT().SetBits(gomock.Any(), gomock.Any(), gomock.Len(7)).Return(nil)
+		// overwrites a missing wallet remembered for the ID
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), ownerCacheKey, created.UserID, commons.WalletOwnerCacheTTL).Return(nil)
+
+		got, err := db.NewCachingDatabase(databaseMock, cacheMock, filter).CreateUserWallet(context.Background(), "EUR")
+		assert.Nil(t, err)
+		assert.Equal(t, created, got)
+	})
+
+	t.Run("should turn the filter off if a created wallet cannot be added to it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		filter := db.NewWalletFilter(cacheMock, 1000, 0.01)
+		created := &models.Wallet{ID: walletID, UserID: "0a644be3-cdf9-4491-b4ba-1cd8974c0278", Currency: "EUR"}
+		databaseMock.EXPECT().CreateWallet(gomock.Any(), created.UserID, "savings", "EUR").Return(created, nil)
+		cacheMock.EXPECT().SetBits(gomock.Any(), gomock.Any(), gomock.Len(7)).Return(errors.New("connection refused"))
+		cacheMock.EXPECT().Delete(gomock.Any(), gomock.Cond(func(key string) bool {
+			return strings.HasPrefix(key, "wallet-filter-") && strings.HasSuffix(key, ":ready")
+		})).Return(nil)
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), ownerCacheKey, created.UserID, commons.WalletOwnerCacheTTL).Return(nil)
+
+		got, err := db.NewCachingDatabase(databaseMock, cacheMock, filter).CreateWallet(context.Background(), created.UserID, "savings", "EUR")
+		assert.Nil(t, err)
+		assert.Equal(t, created, got)
+	})
+
+	t.Run("should return the created wallet even if the filter can be neither updated nor turned off", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		filter := db.NewWalletFilter(cacheMock, 1000, 0.01)
+		created := &models.Wallet{ID: walletID, UserID: "0a644be3-cdf9-4491-b4ba-1cd8974c0278", Currency: "EUR"}
+		databaseMock.EXPECT().CreateUserWallet(gomock.Any(), "EUR").Return(created, nil)
+		cacheMock.EXPECT().SetBits(gomock.Any(), gomock.Any(), gomock.Len(7)).Return(errors.New("connection refused"))
+		cacheMock.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(errors.New("connection refused"))
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), ownerCacheKey, created.UserID, commons.WalletOwnerCacheTTL).Return(errors.New("connection refused"))
+
+		got, err := db.NewCachingDatabase(databaseMock, cacheMock, filter).CreateUserWallet(context.Background(), "EUR")
+		assert.Nil(t, err)
+		assert.Equal(t, created, got)
+	})
+
+	t.Run("should trust the seeded filter only until it is seeded again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		filter := db.NewWalletFilter(cacheMock, 1000, 0.01)
+		databaseMock.EXPECT().GetWalletUsers(gomock.Any()).Return([]*models.Wallet{{ID: walletID}}, nil)
+		cacheMock.EXPECT().SetBits(gomock.Any(), gomock.Any(), gomock.Len(7)).Return(nil)
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Cond(func(key string) bool {
+			return strings.HasSuffix(key, ":ready")
+		}), "1", commons.WalletFilterSeedTTL).Return(nil)
+
+		assert.Nil(t, filter.Seed(context.Background(), databaseMock))
+	})
+
+	t.Run("should keep the wallets created while the filter is seeded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cache := db.NewInMemoryCache(1000)
+		filter := db.NewWalletFilter(cache, 1000, 0.01)
+		database := db.NewCachingDatabase(databaseMock, cache, filter)
+		existing := &models.Wallet{ID: toWalletID, UserID: "0a644be3-cdf9-4491-b4ba-1cd8974c0278", Currency: "EUR"}
+		created := &models.Wallet{ID: walletID, UserID: "0a644be3-cdf9-4491-b4ba-1cd8974c0278", Currency: "EUR"}
+		databaseMock.EXPECT().CreateUserWallet(gomock.Any(), "EUR").Return(created, nil)
+		// the wallet is created after the wallets were listed and before the filter is marked as seeded
+		databaseMock.EXPECT().GetWalletUsers(gomock.Any()).DoAndReturn(func(ctx context.Context) ([]*models.Wallet, error) {
+			_, err := database.CreateUserWallet(ctx, "EUR")
+			assert.Nil(t, err)
+			return []*models.Wallet{existing}, nil
+		})
+
+		assert.Nil(t, filter.Seed(context.Background(), databaseMock))
+		for _, id := range []string{existing.ID, created.ID} {
+			exists, err := filter.MightExist(context.Background(), id)
+			assert.Nil(t, err)
+			assert.True(t, exists)
+		}
+	})
+
+	t.Run("should write both wallets of a transfer through once it is committed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		txn := &models.Transaction{WalletID: walletID, ToWalletID: sql.NullString{String: toWalletID, Valid: true}, Type: "transfer", Amount: commons.MustMoney("10")}
+		recipient := &models.Wallet{ID: toWalletID, Currency: "EUR", Balance: commons.MustMoney("10"), Seq: 3}
+		gomock.InOrder(
+			databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), txn).DoAndReturn(func(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+				balance := commons.MustMoney("90")
+				txn.Seq, txn.BalanceAfter = sql.NullInt64{Int64: 8, Valid: true}, &balance
+				return balance, nil
+			}),
+			// the balance and sequence number left by the write are written through without reading the wallet
+			cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return(string(cached), true, nil),
+			cacheMock.EXPECT().SetWithExpirationIfVersionIsNotOlder(gomock.Any(), cacheKey, gomock.Cond(func(value string) bool {
+				var written models.Wallet
+				return json.Unmarshal([]byte(value), &written) == nil && written.Balance.Equal(commons.MustMoney("90")) && written.Seq == 8 &&
+					written.Currency == "EUR"
+			}), int64(8), commons.WalletCacheTTL).Return(true, nil),
+			databaseMock.EXPECT().GetWallet(gomock.Any(), toWalletID).Return(recipient, nil),
+			// a concurrent transfer has already written a newer balance
+			cacheMock.EXPECT().SetWithExpirationIfVersionIsNotOlder(gomock.Any(), "wallet-"+toWalletID, gomock.Any(), int64(3), commons.WalletCacheTTL).Return(false, nil),
+		)
+
+		balance, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).InsertTxnAndGetWalletBalance(context.Background(), txn)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("90").Equal(balance))
+	})
+
+	t.Run("should read the wallet written through if it is not cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		txn := &models.Transaction{WalletID: walletID, Type: "deposit", Amount: commons.MustMoney("10")}
+		gomock.InOrder(
+			databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), txn).DoAndReturn(func(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+				balance := commons.MustMoney("100")
+				txn.Seq, txn.BalanceAfter = sql.NullInt64{Int64: 7, Valid: true}, &balance
+				return balance, nil
+			}),
+			cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return("", false, nil),
+			databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(wallet, nil),
+			cacheMock.EXPECT().SetWithExpirationIfVersionIsNotOlder(gomock.Any(), cacheKey, string(cached), int64(7), commons.WalletCacheTTL).Return(true, nil),
+		)
+
+		_, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).InsertTxnAndGetWalletBalance(context.Background(), txn)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should invalidate the wallet if the write fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		txn := &models.Transaction{WalletID: walletID, Type: "withdraw", Amount: commons.MustMoney("1000")}
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), txn).Return(commons.Money{}, commons.InsufficientBalanceError)
+		cacheMock.EXPECT().Delete(gomock.Any(), cacheKey).Return(nil)
+
+		_, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).InsertTxnAndGetWalletBalance(context.Background(), txn)
+		assert.ErrorIs(t, err, commons.InsufficientBalanceError)
+	})
+
+	t.Run("should cache the held amount until the earliest active hold expires", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		expiresAt := time.Now().Add(2 * time.Second)
+		cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return(string(cached), true, nil)
+		cacheMock.EXPECT().Get(gomock.Any(), heldCacheKey).Return("", false, nil)
+		databaseMock.EXPECT().GetHeldAmount(gomock.Any(), walletID).Return(commons.MustMoney("20"), &expiresAt, nil)
+		var ttl time.Duration
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), heldCacheKey, "20", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, _ string, duration time.Duration) error {
+				ttl = duration
+				return nil
+			})
+
+		got, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).GetWallet(context.Background(), walletID)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("80").Equal(got.AvailableBalance))
+		assert.True(t, ttl > 0 && ttl <= 2*time.Second)
+	})
+
+	t.Run("should cache the held amount of a wallet without holds for a bounded time", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return(string(cached), true, nil)
+		cacheMock.EXPECT().Get(gomock.Any(), heldCacheKey).Return("", false, nil)
+		databaseMock.EXPECT().GetHeldAmount(gomock.Any(), walletID).Return(commons.ZeroMoney, nil, nil)
+		cacheMock.EXPECT().SetWithExpiration(gomock.Any(), heldCacheKey, "0", commons.HeldAmountCacheTTL).Return(nil)
+
+		got, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).GetWallet(context.Background(), walletID)
+		assert.Nil(t, err)
+		assert.True(t, wallet.Balance.Equal(got.AvailableBalance))
+	})
+
+	t.Run("should clear the held amount when a hold is created, voided or captured", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		hold := &models.Hold{ID: "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", WalletID: walletID, Amount: commons.MustMoney("20")}
+		txn := &models.Transaction{WalletID: walletID, Type: "withdraw", Amount: commons.MustMoney("20"),
+			HoldID: sql.NullString{String: hold.ID, Valid: true}}
+		gomock.InOrder(
+			databaseMock.EXPECT().CreateHold(gomock.Any(), hold, time.Minute).Return(hold, nil),
+			cacheMock.EXPECT().Delete(gomock.Any(), heldCacheKey).Return(nil),
+			databaseMock.EXPECT().VoidHold(gomock.Any(), hold.ID, nil).Return(hold, nil),
+			cacheMock.EXPECT().Delete(gomock.Any(), heldCacheKey).Return(nil),
+			cacheMock.EXPECT().Delete(gomock.Any(), heldCacheKey).Return(nil),
+		)
+		// the capture is rejected, it may have been committed regardless
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), txn).Return(commons.Money{}, errors.New("connection reset"))
+		cacheMock.EXPECT().Delete(gomock.Any(), cacheKey).Return(nil)
+
+		database := db.NewCachingDatabase(databaseMock, cacheMock, nil)
+		_, err := database.CreateHold(context.Background(), hold, time.Minute)
+		assert.Nil(t, err)
+		_, err = database.VoidHold(context.Background(), hold.ID, nil)
+		assert.Nil(t, err)
+		_, err = database.InsertTxnAndGetWalletBalance(context.Background(), txn)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("should read the wallet from the database if the holds cannot be read", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks.NewMockDatabase(ctrl)
+		cacheMock := mocks.NewMockCache(ctrl)
+		cacheMock.EXPECT().Get(gomock.Any(), cacheKey).Return(string(cached), true, nil)
+		cacheMock.EXPECT().Get(gomock.Any(), heldCacheKey).Return("", false, nil)
+		databaseMock.EXPECT().GetHeldAmount(gomock.Any(), walletID).Return(commons.Money{}, nil, errors.New("connection refused"))
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(wallet, nil)
+
+		got, err := db.NewCachingDatabase(databaseMock, cacheMock, nil).GetWallet(context.Background(), walletID)
+		assert.Nil(t, err)
+		assert.Equal(t, wallet, got)
+	})
+}
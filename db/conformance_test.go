@@ -0,0 +1,555 @@
+package db_test
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"WalletApp/commons"
+	"WalletApp/db"
+	"WalletApp/models"
+)
+
+// testDatabaseConformance runs the behaviour every Database implementation must share against a database holding
+// the sample data of migration/init.sql. The subtests build on each other and only go through the interface, checks
+// bound to a storage engine stay with its own test.
+func testDatabaseConformance(t *testing.T, ctx context.Context, database db.Database) {
+	var eurWalletID string
+
+	t.Run("successful deposit", func(t *testing.T) {
+		balance, err := database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{
+			WalletID: "7dbacf5d-3099-4a66-ad3d-2fee93970017",
+			Type:     string(commons.TransactionTypeDeposit),
+			Amount:   commons.MustMoney("50"),
+		})
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("50").Equal(balance))
+
+		// Verify that the transaction has been recorded and the wallet balance updated.
+		history, err := database.GetTransactions(ctx, "7dbacf5d-3099-4a66-ad3d-2fee93970017", &models.TransactionFilter{Types: []string{"deposit"}}, 10, 0)
+		assert.Nil(t, err)
+		assert.Len(t, history, 1, "Expected one deposit transaction to be recorded.")
+
+		walletBalance, err := database.GetWalletBalance(ctx, "7dbacf5d-3099-4a66-ad3d-2fee93970017")
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("50").Equal(walletBalance), "Wallet balance should reflect the deposit.")
+	})
+
+	t.Run("withdraw more than balance should rollback", func(t *testing.T) {
+		setWalletBalance(t, ctx, database, "7dbacf5d-3099-4a66-ad3d-2fee93970017", commons.MustMoney("150"))
+		_, err := database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{
+			WalletID: "7dbacf5d-3099-4a66-ad3d-2fee93970017",
+			Type:     string(commons.TransactionTypeWithdraw),
+			Amount:   commons.MustMoney("1000"),
+		})
+		assert.ErrorIs(t, err, commons.InsufficientBalanceError)
+
+		balance, err := database.GetWalletBalance(ctx, "7dbacf5d-3099-4a66-ad3d-2fee93970017")
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("150").Equal(balance)) // unchanged due to rollback
+	})
+
+	t.Run("transfer to non-existent wallet should rollback", func(t *testing.T) {
+		setWalletBalance(t, ctx, database, "7dbacf5d-3099-4a66-ad3d-2fee93970017", commons.MustMoney("150"))
+		_, err := database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{
+			WalletID:   "7dbacf5d-3099-4a66-ad3d-2fee93970017",
+			ToWalletID: sql.NullString{String: "710acea9-142e-4f72-8416-53f032134f08", Valid: true},
+			Type:       string(commons.TransactionTypeTransfer),
+			Amount:     commons.MustMoney("20"),
+		})
+		assert.NotNil(t, err)
+
+		balance, err := database.GetWalletBalance(ctx, "7dbacf5d-3099-4a66-ad3d-2fee93970017")
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("150").Equal(balance))
+	})
+
+	t.Run("cross-currency transfer should credit the converted amount and record the rate", func(t *testing.T) {
+		setWalletBalance(t, ctx, database, "7dbacf5d-3099-4a66-ad3d-2fee93970017", commons.MustMoney("150"))
+		wallet, err := database.CreateWallet(ctx, "abe1f04a-68df-4e13-bd0d-5365ca9fdb0e", "", "EUR")
+		assert.Nil(t, err)
+		eurWalletID = wallet.ID
+
+		destAmount := commons.MustMoney("92")
+		rate := commons.MustRate("0.92")
+		transfer := &models.Transaction{
+			WalletID:     "7dbacf5d-3099-4a66-ad3d-2fee93970017",
+			ToWalletID:   sql.NullString{String: eurWalletID, Valid: true},
+			Type:         string(commons.TransactionTypeTransfer),
+			Amount:       commons.MustMoney("100"),
+			DestAmount:   &destAmount,
+			DestCurrency: sql.NullString{String: "EUR", Valid: true},
+			FXRate:       &rate,
+			FXRateAt:     sql.NullTime{Time: time.Now(), Valid: true},
+		}
+		balance, err := database.InsertTxnAndGetWalletBalance(ctx, transfer)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("50").Equal(balance))
+
+		receiverBalance, err := database.GetWalletBalance(ctx, eurWalletID)
+		assert.Nil(t, err)
+		assert.True(t, destAmount.Equal(receiverBalance))
+
+		recorded, err := database.GetTransaction(ctx, transfer.ID)
+		assert.Nil(t, err)
+		assert.True(t, rate.Equal(*recorded.FXRate))
+		assert.Equal(t, "EUR", recorded.DestCurrency.String)
+	})
+
+	t.Run("quote can be executed only once", func(t *testing.T) {
+		setWalletBalance(t, ctx, database, "7dbacf5d-3099-4a66-ad3d-2fee93970017", commons.MustMoney("150"))
+
+		quote, err := database.CreateQuote(ctx, &models.Quote{
+			WalletID: "7dbacf5d-3099-4a66-ad3d-2fee93970017",
+			Type:     string(commons.TransactionTypeWithdraw),
+			Amount:   commons.MustMoney("10"),
+			Currency: "USD",
+		})
+		assert.Nil(t, err)
+		assert.True(t, quote.ExpiresAt.After(time.Now()))
+
+		balance, err := database.InsertTxnAndGetWalletBalance(ctx, quote.Transaction())
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("140").Equal(balance))
+
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, quote.Transaction())
+		assert.ErrorIs(t, err, commons.QuoteAlreadyExecutedError)
+	})
+
+	t.Run("reconciliation should find no drift between balances and the history", func(t *testing.T) {
+		wallets, err := database.GetWalletUsers(ctx)
+		assert.Nil(t, err)
+		walletsChecked, mismatches, err := database.FindBalanceMismatches(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, len(wallets), walletsChecked)
+		assert.Empty(t, mismatches)
+
+		report, err := database.SaveReconciliationReport(ctx, &models.ReconciliationReport{
+			StartedAt:      time.Now(),
+			FinishedAt:     time.Now(),
+			WalletsChecked: walletsChecked,
+		})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, report.ID)
+	})
+
+	t.Run("hold should reserve funds until it is captured or voided", func(t *testing.T) {
+		walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		setWalletBalance(t, ctx, database, walletID, commons.MustMoney("100"))
+
+		hold, err := database.CreateHold(ctx, &models.Hold{WalletID: walletID, Amount: commons.MustMoney("60"), Currency: "USD"}, time.Hour)
+		assert.Nil(t, err)
+		assert.Equal(t, string(commons.HoldStatusActive), hold.Status)
+
+		wallet, err := database.GetWallet(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("100").Equal(wallet.Balance))
+		assert.True(t, commons.MustMoney("40").Equal(wallet.AvailableBalance))
+		held, expiresAt, err := database.GetHeldAmount(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("60").Equal(held))
+		if assert.NotNil(t, expiresAt) {
+			assert.WithinDuration(t, hold.ExpiresAt, *expiresAt, time.Millisecond)
+		}
+
+		_, err = database.CreateHold(ctx, &models.Hold{WalletID: walletID, Amount: commons.MustMoney("50"), Currency: "USD"}, time.Hour)
+		assert.ErrorIs(t, err, commons.InsufficientBalanceError)
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeWithdraw), Amount: commons.MustMoney("50")})
+		assert.ErrorIs(t, err, commons.InsufficientBalanceError)
+
+		// A partial capture debits the captured amount and releases the remainder
+		balance, err := database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeWithdraw),
+			Amount: commons.MustMoney("25"), Currency: "USD", HoldID: sql.NullString{String: hold.ID, Valid: true}})
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("75").Equal(balance))
+		captured, err := database.GetHold(ctx, hold.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, string(commons.HoldStatusCaptured), captured.Status)
+		assert.True(t, commons.MustMoney("25").Equal(captured.CapturedAmount))
+		wallet, err = database.GetWallet(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("75").Equal(wallet.AvailableBalance))
+
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeWithdraw),
+			Amount: commons.MustMoney("10"), Currency: "USD", HoldID: sql.NullString{String: hold.ID, Valid: true}})
+		assert.ErrorIs(t, err, commons.HoldNotActiveError)
+
+		// A voided hold releases its funds
+		hold, err = database.CreateHold(ctx, &models.Hold{WalletID: walletID, Amount: commons.MustMoney("30"), Currency: "USD"}, time.Hour)
+		assert.Nil(t, err)
+		voided, err := database.VoidHold(ctx, hold.ID, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, string(commons.HoldStatusVoided), voided.Status)
+		_, err = database.VoidHold(ctx, hold.ID, nil)
+		assert.ErrorIs(t, err, commons.HoldNotActiveError)
+		wallet, err = database.GetWallet(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("75").Equal(wallet.AvailableBalance))
+		held, expiresAt, err = database.GetHeldAmount(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, held.IsZero())
+		assert.Nil(t, expiresAt)
+	})
+
+	t.Run("expired holds should not reserve funds", func(t *testing.T) {
+		walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		hold, err := database.CreateHold(ctx, &models.Hold{WalletID: walletID, Amount: commons.MustMoney("30"), Currency: "USD"}, 100*time.Millisecond)
+		assert.Nil(t, err)
+		time.Sleep(200 * time.Millisecond)
+
+		wallet, err := database.GetWallet(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, wallet.Balance.Equal(wallet.AvailableBalance))
+		_, err = database.VoidHold(ctx, hold.ID, nil)
+		assert.ErrorIs(t, err, commons.HoldExpiredError)
+
+		expired, err := database.ExpireHolds(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), expired)
+		expiredHold, err := database.GetHold(ctx, hold.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, string(commons.HoldStatusExpired), expiredHold.Status)
+	})
+
+	t.Run("transfer can be reversed partially but not beyond its amount", func(t *testing.T) {
+		senderID, recipientID := "7dbacf5d-3099-4a66-ad3d-2fee93970017", "2cbcd158-56d2-4d45-8113-d51adf9ef57a"
+		setWalletBalance(t, ctx, database, senderID, commons.MustMoney("100"))
+		setWalletBalance(t, ctx, database, recipientID, commons.ZeroMoney)
+		transfer := &models.Transaction{WalletID: senderID, ToWalletID: sql.NullString{String: recipientID, Valid: true},
+			Type: string(commons.TransactionTypeTransfer), Amount: commons.MustMoney("50")}
+		_, err := database.InsertTxnAndGetWalletBalance(ctx, transfer)
+		assert.Nil(t, err)
+
+		reversal := func(amount string) *models.Transaction {
+			return &models.Transaction{WalletID: recipientID, ToWalletID: sql.NullString{String: senderID, Valid: true},
+				Type: string(commons.TransactionTypeTransfer), Amount: commons.MustMoney(amount), ReversalOf: sql.NullString{String: transfer.ID, Valid: true}}
+		}
+		balance, err := database.InsertTxnAndGetWalletBalance(ctx, reversal("20"))
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("30").Equal(balance))
+
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, reversal("40"))
+		assert.ErrorIs(t, err, commons.ReversalAmountExceededError)
+
+		// The recipient cannot give back funds it has already spent
+		setWalletBalance(t, ctx, database, recipientID, commons.MustMoney("10"))
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, reversal("30"))
+		assert.ErrorIs(t, err, commons.InsufficientBalanceError)
+
+		setWalletBalance(t, ctx, database, recipientID, commons.MustMoney("30"))
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, reversal("30"))
+		assert.Nil(t, err)
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, reversal("1"))
+		assert.ErrorIs(t, err, commons.TransactionAlreadyReversedError)
+
+		original, err := database.GetTransaction(ctx, transfer.ID)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("50").Equal(original.ReversedAmount))
+		history, err := database.GetTransactions(ctx, senderID, nil, 1, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, transfer.ID, history[0].ReversalOf.String)
+	})
+
+	t.Run("wallet postings should carry the sequence number and balance of the wallet", func(t *testing.T) {
+		walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		setWalletBalance(t, ctx, database, walletID, commons.MustMoney("10"))
+		first := &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit), Amount: commons.MustMoney("5")}
+		_, err := database.InsertTxnAndGetWalletBalance(ctx, first)
+		assert.Nil(t, err)
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit), Amount: commons.MustMoney("7")})
+		assert.Nil(t, err)
+
+		posting, err := database.GetWalletPosting(ctx, walletID, first.ID)
+		assert.Nil(t, err)
+		assert.True(t, commons.MustMoney("15").Equal(*posting.BalanceAfter))
+		// the write reports the posting of the source wallet
+		assert.Equal(t, posting.Seq, first.Seq)
+		assert.True(t, commons.MustMoney("15").Equal(*first.BalanceAfter))
+
+		// Every posting of the wallet takes the next sequence number, the latest one is kept on the wallet
+		wallet, err := database.GetWallet(ctx, walletID)
+		assert.Nil(t, err)
+		assert.Equal(t, posting.Seq.Int64+1, wallet.Seq)
+
+		synced, err := database.GetTransactions(ctx, walletID, &models.TransactionFilter{AfterSeq: &posting.Seq.Int64}, 10, 0)
+		assert.Nil(t, err)
+		assert.Len(t, synced, 1)
+		assert.Equal(t, wallet.Seq, synced[0].Seq.Int64)
+		assert.True(t, commons.MustMoney("22").Equal(*synced[0].BalanceAfter))
+	})
+
+	t.Run("statement of a period should be saved once", func(t *testing.T) {
+		walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		periodStart := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+		periodEnd := periodStart.AddDate(0, 1, 0)
+		missing, err := database.GetStatement(ctx, walletID, periodStart, periodEnd)
+		assert.Nil(t, err)
+		assert.Nil(t, missing)
+
+		first, err := database.SaveStatement(ctx, &models.Statement{WalletID: walletID, PeriodStart: periodStart, PeriodEnd: periodEnd, Content: []byte(`{"closing_balance": "10"}`)})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, first.ID)
+		second, err := database.SaveStatement(ctx, &models.Statement{WalletID: walletID, PeriodStart: periodStart, PeriodEnd: periodEnd, Content: []byte(`{"closing_balance": "20"}`)})
+		assert.Nil(t, err)
+		assert.Equal(t, first.ID, second.ID)
+		assert.JSONEq(t, `{"closing_balance": "10"}`, string(second.Content))
+	})
+
+	t.Run("point-in-time balance should be the balance left by the latest posting at that time", func(t *testing.T) {
+		walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		before, err := database.GetLatestWalletPosting(ctx, walletID, time.Now().UTC().Add(-time.Hour))
+		assert.Nil(t, err)
+		assert.Nil(t, before)
+
+		deposit := &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit), Amount: commons.MustMoney("3")}
+		balance, err := database.InsertTxnAndGetWalletBalance(ctx, deposit)
+		assert.Nil(t, err)
+
+		latest, err := database.GetLatestWalletPosting(ctx, walletID, time.Now().UTC())
+		assert.Nil(t, err)
+		assert.Equal(t, deposit.ID, latest.TransactionID)
+		assert.True(t, balance.Equal(*latest.BalanceAfter))
+	})
+
+	t.Run("idempotency key committed by a ledger write cannot be used by another request", func(t *testing.T) {
+		userID, walletID := "0a644be3-cdf9-4491-b4ba-1cd8974c0278", "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		key := &models.IdempotencyKey{UserID: userID, Token: "ledger-key", Value: "in-flight", Retention: time.Hour}
+		deposit := &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit), Amount: commons.MustMoney("1"), IdempotencyKey: key}
+		balance, err := database.InsertTxnAndGetWalletBalance(ctx, deposit)
+		assert.Nil(t, err)
+		recorded, err := database.GetTransactionByIdempotencyToken(ctx, walletID, "ledger-key")
+		assert.Nil(t, err)
+		assert.Equal(t, deposit.ID, recorded.ID)
+		assert.Equal(t, "ledger-key", recorded.IdempotencyToken.String)
+
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit),
+			Amount: commons.MustMoney("1"), IdempotencyKey: key})
+		assert.ErrorIs(t, err, commons.DuplicateRequestError)
+		unchanged, err := database.GetWalletBalance(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, balance.Equal(unchanged))
+
+		hold := &models.Hold{WalletID: walletID, Amount: commons.MustMoney("1"),
+			IdempotencyKey: &models.IdempotencyKey{UserID: userID, Token: "hold-key", Value: "in-flight", Retention: time.Hour}}
+		_, err = database.CreateHold(ctx, hold, time.Hour)
+		assert.Nil(t, err)
+		_, err = database.CreateHold(ctx, hold, time.Hour)
+		assert.ErrorIs(t, err, commons.DuplicateRequestError)
+
+		// the void is rolled back with the key
+		created, err := database.CreateHold(ctx, &models.Hold{WalletID: walletID, Amount: commons.MustMoney("1")}, time.Hour)
+		assert.Nil(t, err)
+		_, err = database.VoidHold(ctx, created.ID, hold.IdempotencyKey)
+		assert.ErrorIs(t, err, commons.DuplicateRequestError)
+		active, err := database.GetHold(ctx, created.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, string(commons.HoldStatusActive), active.Status)
+		_, err = database.VoidHold(ctx, created.ID, &models.IdempotencyKey{UserID: userID, Token: "void-key", Value: "in-flight", Retention: time.Hour})
+		assert.Nil(t, err)
+	})
+
+	t.Run("transactions should be streamed in the order of the history", func(t *testing.T) {
+		walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		filter := &models.TransactionFilter{Ascending: true}
+		history, err := database.GetTransactions(ctx, walletID, filter, 1000, 0)
+		assert.Nil(t, err)
+
+		var streamed []string
+		err = database.StreamTransactions(ctx, walletID, filter, func(tx *models.Transaction) error {
+			streamed = append(streamed, tx.ID)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Len(t, streamed, len(history))
+		for i, tx := range history {
+			assert.Equal(t, tx.ID, streamed[i])
+		}
+
+		stop := errors.New("stop")
+		err = database.StreamTransactions(ctx, walletID, filter, func(*models.Transaction) error { return stop })
+		assert.ErrorIs(t, err, stop)
+	})
+
+	t.Run("transaction history should be filtered and sorted from the perspective of the wallet", func(t *testing.T) {
+		walletID, counterpartyID := "2cbcd158-56d2-4d45-8113-d51adf9ef57a", "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		since := time.Now().UTC() // created_at is stored in UTC without time zone
+		setWalletBalance(t, ctx, database, counterpartyID, commons.MustMoney("100"))
+		_, err := database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: counterpartyID, ToWalletID: sql.NullString{String: walletID, Valid: true},
+			Type: string(commons.TransactionTypeTransfer), Amount: commons.MustMoney("30")})
+		assert.Nil(t, err)
+		_, err = database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeWithdraw), Amount: commons.MustMoney("5")})
+		assert.Nil(t, err)
+
+		credits, err := database.GetTransactions(ctx, walletID, &models.TransactionFilter{From: &since, Direction: "credit", CounterpartyWalletID: counterpartyID}, 10, 0)
+		assert.Nil(t, err)
+		assert.Len(t, credits, 1)
+		assert.True(t, commons.MustMoney("30").Equal(credits[0].Amount))
+
+		minAmount := commons.MustMoney("10")
+		large, err := database.GetTransactions(ctx, walletID, &models.TransactionFilter{From: &since, MinAmount: &minAmount}, 10, 0)
+		assert.Nil(t, err)
+		assert.Len(t, large, 1)
+
+		oldestFirst, err := database.GetTransactions(ctx, walletID, &models.TransactionFilter{From: &since, Types: []string{"transfer", "withdrawal"}, Ascending: true}, 10, 0)
+		assert.Nil(t, err)
+		assert.Len(t, oldestFirst, 2)
+		assert.Equal(t, string(commons.TransactionTypeTransfer), oldestFirst[0].Type)
+		assert.Equal(t, string(commons.TransactionTypeWithdraw), oldestFirst[1].Type)
+	})
+
+	t.Run("transaction history should page forward and backward from a cursor", func(t *testing.T) {
+		since := time.Now().UTC()
+		for _, amount := range []string{"1", "2", "3"} {
+			_, err := database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: eurWalletID, Type: string(commons.TransactionTypeDeposit), Amount: commons.MustMoney(amount)})
+			assert.Nil(t, err)
+		}
+		all, err := database.GetTransactions(ctx, eurWalletID, &models.TransactionFilter{From: &since}, 10, 0)
+		assert.Nil(t, err)
+		assert.Len(t, all, 3)
+
+		// Newest first: the cursor on the newest deposit pages to the two older ones
+		newest := &models.TransactionCursor{CreatedAt: all[0].CreatedAt, ID: all[0].ID}
+		older, err := database.GetTransactions(ctx, eurWalletID, &models.TransactionFilter{From: &since, Cursor: newest}, 10, 5)
+		assert.Nil(t, err)
+		assert.Len(t, older, 2)
+		assert.Equal(t, all[1].ID, older[0].ID)
+		assert.Equal(t, all[2].ID, older[1].ID)
+
+		oldest := &models.TransactionCursor{CreatedAt: all[2].CreatedAt, ID: all[2].ID, Backward: true}
+		newer, err := database.GetTransactions(ctx, eurWalletID, &models.TransactionFilter{From: &since, Cursor: oldest}, 1, 0)
+		assert.Nil(t, err)
+		assert.Len(t, newer, 1)
+		assert.Equal(t, all[1].ID, newer[0].ID)
+	})
+
+	t.Run("user can open and list several wallets", func(t *testing.T) {
+		userID := "0a644be3-cdf9-4491-b4ba-1cd8974c0278"
+		wallet, err := database.CreateWallet(ctx, userID, "Savings", "EUR")
+		assert.Nil(t, err)
+		assert.NotNil(t, wallet)
+		assert.Equal(t, "Savings", wallet.Label.String)
+		assert.Equal(t, "EUR", wallet.Currency)
+		assert.True(t, wallet.Balance.IsZero())
+
+		wallets, err := database.GetUserWallets(ctx, userID)
+		assert.Nil(t, err)
+		assert.Len(t, wallets, 2)
+		assert.Equal(t, "7dbacf5d-3099-4a66-ad3d-2fee93970017", wallets[0].ID)
+		assert.Equal(t, wallet.ID, wallets[1].ID)
+
+		owner, err := database.CheckWalletOwner(ctx, wallet.ID, userID)
+		assert.Nil(t, err)
+		assert.True(t, owner)
+		owner, err = database.CheckWalletOwner(ctx, wallet.ID, "abe1f04a-68df-4e13-bd0d-5365ca9fdb0e")
+		assert.Nil(t, err)
+		assert.False(t, owner)
+	})
+
+	t.Run("opening a wallet for a non-existent user should return nil", func(t *testing.T) {
+		wallet, err := database.CreateWallet(ctx, "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", "", "USD")
+		assert.Nil(t, err)
+		assert.Nil(t, wallet)
+	})
+
+	t.Run("concurrent withdrawals should never overdraw the wallet", func(t *testing.T) {
+		walletID := "2cbcd158-56d2-4d45-8113-d51adf9ef57a"
+		setWalletBalance(t, ctx, database, walletID, commons.MustMoney("10"))
+
+		results := make(chan error, 20)
+		for range 20 {
+			go func() {
+				_, err := database.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeWithdraw), Amount: commons.MustMoney("1")})
+				results <- err
+			}()
+		}
+		succeeded := 0
+		for range 20 {
+			err := <-results
+			if err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, commons.InsufficientBalanceError)
+			}
+		}
+		assert.Equal(t, 10, succeeded)
+		balance, err := database.GetWalletBalance(ctx, walletID)
+		assert.Nil(t, err)
+		assert.True(t, balance.IsZero())
+	})
+
+	t.Run("concurrent postings should be timestamped in the order of their sequence numbers", func(t *testing.T) {
+		// The deposits start together and are serialized on the wallet, so they do not take their sequence numbers in
+		// the order they started. Timestamped at their start, a posting could be dated before the postings it follows
+		// and the balance as of a time would count postings made after it
+		walletID := "2cbcd158-56d2-4d45-8113-d51adf9ef57a"
+		type result struct {
+			txn *models.Transaction
+			err error
+		}
+		results := make(chan result, 20)
+		for range 20 {
+			go func() {
+				txn := &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit), Amount: commons.MustMoney("1")}
+				_, err := database.InsertTxnAndGetWalletBalance(ctx, txn)
+				results <- result{txn, err}
+			}()
+		}
+		var postings []*models.Posting
+		for range 20 {
+			result := <-results
+			if !assert.Nil(t, result.err) {
+				continue
+			}
+			posting, err := database.GetWalletPosting(ctx, walletID, result.txn.ID)
+			assert.Nil(t, err)
+			postings = append(postings, posting)
+		}
+		assert.Len(t, postings, 20)
+
+		slices.SortFunc(postings, func(a, b *models.Posting) int { return cmp.Compare(a.Seq.Int64, b.Seq.Int64) })
+		for i, posting := range postings {
+			if i > 0 {
+				assert.False(t, posting.CreatedAt.Before(postings[i-1].CreatedAt),
+					"posting %d is dated before posting %d", posting.Seq.Int64, postings[i-1].Seq.Int64)
+			}
+			// the balance as of the time of the posting is the one left by the last posting dated at that time
+			expected := posting
+			for _, later := range postings[i+1:] {
+				if !later.CreatedAt.After(posting.CreatedAt) {
+					expected = later
+				}
+			}
+			latest, err := database.GetLatestWalletPosting(ctx, walletID, posting.CreatedAt)
+			assert.Nil(t, err)
+			assert.Equal(t, expected.Seq, latest.Seq)
+			assert.True(t, expected.BalanceAfter.Equal(*latest.BalanceAfter))
+		}
+	})
+}
+
+// setWalletBalance brings a wallet to the given balance with a deposit or withdrawal so that the cached
+// balance stays consistent with the ledger.
+func setWalletBalance(t *testing.T, ctx context.Context, database db.Database, walletID string, balance commons.Money) {
+	t.Helper()
+	wallet, err := database.GetWallet(ctx, walletID)
+	if err != nil || wallet == nil {
+		t.Fatalf("error while fetching wallet %s: %v", walletID, err)
+	}
+	diff := balance.Sub(wallet.Balance)
+	if diff.IsZero() {
+		return
+	}
+	txn := &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit), Amount: diff}
+	if diff.IsNegative() {
+		txn.Type, txn.Amount = string(commons.TransactionTypeWithdraw), diff.Neg()
+	}
+	if _, err := database.InsertTxnAndGetWalletBalance(ctx, txn); err != nil {
+		t.Fatalf("error while setting the balance of wallet %s: %v", walletID, err)
+	}
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type cacheIdempotencyStore struct {
+	cache Cache
+}
+
+// NewCacheIdempotencyStore returns an IdempotencyStore keeping the keys in the cache.
+func NewCacheIdempotencyStore(cache Cache) IdempotencyStore {
+	return &cacheIdempotencyStore{cache: cache}
+}
+
+// NewInMemoryIdempotencyStore returns an IdempotencyStore keeping up to maxEntries keys in memory. The keys are never
+// evicted before they expire, so a request is refused with commons.CacheFullError rather than executed twice when the
+// store is full. Intended for a single instance running without Redis.
+func NewInMemoryIdempotencyStore(maxEntries int) IdempotencyStore {
+	return &cacheIdempotencyStore{cache: newInMemoryCache(maxEntries, false)}
+}
+
+func cacheIdempotencyKey(userID string, token string) string {
+	return fmt.Sprintf("idm-%s-%s", userID, token)
+}
+
+func (c *cacheIdempotencyStore) SetWithExpirationIfKeyIsNotSet(ctx context.Context, userID string, token string, value string, duration time.Duration) (bool, error) {
+	return c.cache.SetWithExpirationIfKeyIsNotSet(ctx, cacheIdempotencyKey(userID, token), value, duration)
+}
+
+func (c *cacheIdempotencyStore) SetWithExpiration(ctx context.Context, userID string, token string, value string, duration time.Duration) error {
+	return c.cache.SetWithExpiration(ctx, cacheIdempotencyKey(userID, token), value, duration)
+}
+
+func (c *cacheIdempotencyStore) Get(ctx context.Context, userID string, token string) (string, bool, error) {
+	return c.cache.Get(ctx, cacheIdempotencyKey(userID, token))
+}
+
+func (c *cacheIdempotencyStore) Delete(ctx context.Context, userID string, token string) error {
+	return c.cache.Delete(ctx, cacheIdempotencyKey(userID, token))
+}
+
+type postgresIdempotencyStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLIdempotencyStore returns an IdempotencyStore backed by the idempotency_keys table. The ledger writes
+// commit the keys of their requests in the same table, so they are kept even if the store is not used.
+func NewPostgreSQLIdempotencyStore(db *sqlx.DB) IdempotencyStore {
+	return &postgresIdempotencyStore{db: db}
+}
+
+// SetWithExpirationIfKeyIsNotSet inserts the key, or takes it over once it has expired.
+func (p *postgresIdempotencyStore) SetWithExpirationIfKeyIsNotSet(ctx context.Context, userID string, token string, value string, duration time.Duration) (bool, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, token, value, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		ON CONFLICT (user_id, token) DO UPDATE
+			SET value = EXCLUDED.value, committed = FALSE, expires_at = EXCLUDED.expires_at, created_at = NOW()
+			WHERE idempotency_keys.expires_at <= NOW();
+	`
+	result, err := p.db.ExecContext(dbCtx, query, userID, token, value, duration.Seconds())
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (p *postgresIdempotencyStore) SetWithExpiration(ctx context.Context, userID string, token string, value string, duration time.Duration) error {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, token, value, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		ON CONFLICT (user_id, token) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at;
+	`
+	_, err := p.db.ExecContext(dbCtx, query, userID, token, value, duration.Seconds())
+	return err
+}
+
+func (p *postgresIdempotencyStore) Get(ctx context.Context, userID string, token string) (string, bool, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var value string
+	query := `SELECT value FROM idempotency_keys WHERE user_id = $1 AND token = $2 AND expires_at > NOW();`
+	err := p.db.GetContext(dbCtx, &value, query, userID, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// Delete releases the key unless a ledger write has committed it.
+func (p *postgresIdempotencyStore) Delete(ctx context.Context, userID string, token string) error {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(dbCtx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND token = $2 AND NOT committed;`, userID, token)
+	return err
+}
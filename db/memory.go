@@ -0,0 +1,870 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"WalletApp/commons"
+	"WalletApp/models"
+)
+
+// negativeBalanceError mirrors the balance_check trigger of migration/init.sql.
+var negativeBalanceError = errors.New("balance cannot be negative")
+
+type inMemoryIdempotencyKey struct {
+	value     string
+	committed bool
+	expiresAt time.Time
+}
+
+// inMemoryDB is a Database kept in the process. It follows the schema of migration/init.sql: the ledger is recorded
+// with balanced journal entries whose wallet postings are numbered per wallet and update the cached wallet balance,
+// which can never go negative. Every write validates all its changes before applying any of them under a single
+// lock, so writes are atomic and serialized.
+type inMemoryDB struct {
+	mu               sync.RWMutex
+	users            map[string]time.Time
+	wallets          map[string]*models.Wallet
+	walletIDs        []string // in creation order
+	transactions     []*models.Transaction
+	transactionsByID map[string]*models.Transaction
+	reversedAmounts  map[string]commons.Money // amount already reversed by transaction
+	postings         []*models.Posting
+	walletPostings   map[string][]*models.Posting // postings of each wallet account, in sequence order
+	txnPostings      map[string]*models.Posting   // latest posting of a transaction on a wallet, by transaction and wallet
+	systemAccounts   map[string]string            // system ledger account by type and currency
+	quotes           map[string]*models.Quote
+	holds            map[string]*models.Hold
+	statements       map[string]*models.Statement
+	reports          []*models.ReconciliationReport
+	idempotencyKeys  map[string]*inMemoryIdempotencyKey
+}
+
+// NewInMemoryDB returns a Database kept in memory, holding the sample users and wallets of migration/init.sql.
+// Intended for tests and local development of a single instance.
+func NewInMemoryDB() Database {
+	m := &inMemoryDB{
+		users:            make(map[string]time.Time),
+		wallets:          make(map[string]*models.Wallet),
+		transactionsByID: make(map[string]*models.Transaction),
+		reversedAmounts:  make(map[string]commons.Money),
+		walletPostings:   make(map[string][]*models.Posting),
+		txnPostings:      make(map[string]*models.Posting),
+		systemAccounts:   make(map[string]string),
+		quotes:           make(map[string]*models.Quote),
+		holds:            make(map[string]*models.Hold),
+		statements:       make(map[string]*models.Statement),
+		idempotencyKeys:  make(map[string]*inMemoryIdempotencyKey),
+	}
+	now := timestampNow()
+	for userID, walletID := range map[string]string{
+		"0a644be3-cdf9-4491-b4ba-1cd8974c0278": "7dbacf5d-3099-4a66-ad3d-2fee93970017",
+		"abe1f04a-68df-4e13-bd0d-5365ca9fdb0e": "2cbcd158-56d2-4d45-8113-d51adf9ef57a",
+	} {
+		m.users[userID] = now
+		m.addWallet(&models.Wallet{ID: walletID, UserID: userID, Currency: "USD", CreatedAt: now, UpdatedAt: now})
+	}
+	return m
+}
+
+// timestampNow returns the current time as stored by a TIMESTAMP column.
+func timestampNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// copyOf returns a shallow copy of a stored row so that callers never change the stored one.
+func copyOf[T any](row *T) *T {
+	c := *row
+	return &c
+}
+
+func (m *inMemoryDB) GetWallet(_ context.Context, walletID string) (*models.Wallet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wallet, ok := m.wallets[walletID]
+	if !ok {
+		return nil, nil // wallet does not exist
+	}
+	return m.walletView(wallet, timestampNow()), nil
+}
+
+func (m *inMemoryDB) GetWalletBalance(_ context.Context, walletID string) (commons.Money, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wallet, ok := m.wallets[walletID]
+	if !ok {
+		return commons.ZeroMoney, sql.ErrNoRows
+	}
+	return wallet.Balance, nil
+}
+
+func (m *inMemoryDB) CheckWalletOwner(_ context.Context, walletID string, userID string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wallet, ok := m.wallets[walletID]
+	return ok && wallet.UserID == userID, nil
+}
+
+func (m *inMemoryDB) GetTransactions(_ context.Context, walletID string, filter *models.TransactionFilter, limit, offset int32) ([]*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if filter != nil && filter.Cursor != nil {
+		offset = 0
+	}
+	transactions := m.walletTransactions(walletID, filter)
+	start := min(int(offset), len(transactions))
+	end := min(start+int(limit), len(transactions))
+	page := transactions[start:end]
+	// A backward cursor scans towards the start of the history, the page is flipped back afterwards
+	if filter != nil && filter.Cursor != nil && filter.Cursor.Backward {
+		slices.Reverse(page)
+	}
+	return page, nil
+}
+
+// StreamTransactions calls fn with every transaction of the wallet matching the filter, in the order of the history.
+// The transactions are read at once, fn is called without holding the lock.
+func (m *inMemoryDB) StreamTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, fn func(*models.Transaction) error) error {
+	m.mu.RLock()
+	transactions := m.walletTransactions(walletID, filter)
+	m.mu.RUnlock()
+
+	for _, txn := range transactions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walletTransactions returns the history of the wallet matching the filter, sorted like transactionsQuery, with the
+// sequence number and balance of the wallet after each transaction.
+func (m *inMemoryDB) walletTransactions(walletID string, filter *models.TransactionFilter) []*models.Transaction {
+	var transactions []*models.Transaction
+	for _, txn := range m.transactions {
+		if !matchesTransactionFilter(txn, walletID, filter) {
+			continue
+		}
+		view := m.transactionView(txn)
+		if posting, ok := m.txnPostings[txnPostingKey(txn.ID, walletID)]; ok {
+			view.Seq, view.BalanceAfter = posting.Seq, posting.BalanceAfter
+		}
+		if filter != nil && filter.AfterSeq != nil && (!view.Seq.Valid || view.Seq.Int64 <= *filter.AfterSeq) {
+			continue
+		}
+		transactions = append(transactions, view)
+	}
+
+	if filter != nil && filter.AfterSeq != nil {
+		slices.SortFunc(transactions, func(a, b *models.Transaction) int {
+			return int(a.Seq.Int64 - b.Seq.Int64)
+		})
+		return transactions
+	}
+	backward := filter != nil && filter.Cursor != nil && filter.Cursor.Backward
+	ascending := filter != nil && filter.Ascending != backward
+	slices.SortFunc(transactions, func(a, b *models.Transaction) int {
+		order := compareTransactionPosition(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+		if !ascending {
+			return -order
+		}
+		return order
+	})
+	return transactions
+}
+
+// matchesTransactionFilter is transactionFilterClause applied to a single transaction, except for AfterSeq.
+func matchesTransactionFilter(txn *models.Transaction, walletID string, filter *models.TransactionFilter) bool {
+	incoming := txn.ToWalletID.Valid && txn.ToWalletID.String == walletID
+	if txn.WalletID != walletID && !incoming {
+		return false
+	}
+	if filter == nil {
+		return true
+	}
+
+	if len(filter.Types) > 0 && !slices.Contains(filter.Types, txn.Type) {
+		return false
+	}
+	if filter.From != nil && txn.CreatedAt.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && !txn.CreatedAt.Before(*filter.To) {
+		return false
+	}
+	// Incoming transfers are filtered on the amount credited to the wallet
+	walletAmount := txn.Amount
+	if incoming {
+		walletAmount = txn.CreditedAmount()
+	}
+	if filter.MinAmount != nil && walletAmount.LessThan(*filter.MinAmount) {
+		return false
+	}
+	if filter.MaxAmount != nil && walletAmount.GreaterThan(*filter.MaxAmount) {
+		return false
+	}
+	if counterparty := filter.CounterpartyWalletID; counterparty != "" {
+		outgoingTo := txn.WalletID == walletID && txn.ToWalletID.Valid && txn.ToWalletID.String == counterparty
+		incomingFrom := incoming && txn.WalletID == counterparty
+		if !outgoingTo && !incomingFrom {
+			return false
+		}
+	}
+	deposit := commons.TransactionType(txn.Type) == commons.TransactionTypeDeposit
+	switch commons.TransactionDirection(filter.Direction) {
+	case commons.TransactionDirectionCredit:
+		if !incoming && !deposit {
+			return false
+		}
+	case commons.TransactionDirectionDebit:
+		if txn.WalletID != walletID || deposit {
+			return false
+		}
+	}
+	if cursor := filter.Cursor; cursor != nil {
+		order := compareTransactionPosition(txn.CreatedAt, txn.ID, cursor.CreatedAt, cursor.ID)
+		if filter.Ascending != cursor.Backward {
+			return order > 0
+		}
+		return order < 0
+	}
+	return true
+}
+
+// compareTransactionPosition compares two positions of the history on (created_at, id).
+func compareTransactionPosition(createdAt time.Time, id string, otherCreatedAt time.Time, otherID string) int {
+	if order := createdAt.Compare(otherCreatedAt); order != 0 {
+		return order
+	}
+	return strings.Compare(id, otherID)
+}
+
+func (m *inMemoryDB) InsertTxnAndGetWalletBalance(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := timestampNow()
+	trsType := commons.TransactionType(txn.Type)
+
+	// Validate every change first, nothing is applied if one of them fails
+	if txn.IdempotencyKey != nil {
+		if err := m.checkIdempotencyKey(txn.IdempotencyKey, now); err != nil {
+			return commons.ZeroMoney, err
+		}
+		txn.IdempotencyToken = sql.NullString{String: txn.IdempotencyKey.Token, Valid: true}
+	}
+
+	var quote *models.Quote
+	if txn.QuoteID.Valid {
+		quote = m.quotes[txn.QuoteID.String]
+		if quote == nil {
+			return commons.ZeroMoney, fmt.Errorf("failed to lock quote: %w", sql.ErrNoRows)
+		}
+		if quote.ExecutedAt.Valid {
+			return commons.ZeroMoney, commons.QuoteAlreadyExecutedError
+		}
+		if !now.Before(quote.ExpiresAt) {
+			return commons.ZeroMoney, commons.QuoteExpiredError
+		}
+	}
+
+	var hold *models.Hold
+	if txn.HoldID.Valid {
+		hold = m.holds[txn.HoldID.String]
+		if hold == nil || hold.WalletID != txn.WalletID {
+			return commons.ZeroMoney, fmt.Errorf("failed to lock hold: %w", sql.ErrNoRows)
+		}
+		if hold.Status != string(commons.HoldStatusActive) {
+			return commons.ZeroMoney, commons.HoldNotActiveError
+		}
+		if !now.Before(hold.ExpiresAt) {
+			return commons.ZeroMoney, commons.HoldExpiredError
+		}
+		if txn.Amount.GreaterThan(hold.Amount) {
+			return commons.ZeroMoney, commons.HoldAmountExceededError
+		}
+	}
+
+	if txn.ReversalOf.Valid {
+		original := m.transactionsByID[txn.ReversalOf.String]
+		if original == nil {
+			return commons.ZeroMoney, fmt.Errorf("failed to lock reversed transaction: %w", sql.ErrNoRows)
+		}
+		remaining := original.Amount.Sub(m.reversedAmounts[original.ID])
+		if !remaining.IsPositive() {
+			return commons.ZeroMoney, commons.TransactionAlreadyReversedError
+		}
+		if txn.CreditedAmount().GreaterThan(remaining) {
+			return commons.ZeroMoney, commons.ReversalAmountExceededError
+		}
+	}
+
+	wallet := m.wallets[txn.WalletID]
+	// The captured hold releases its funds before the available balance is checked
+	if trsType == commons.TransactionTypeWithdraw || trsType == commons.TransactionTypeTransfer {
+		if wallet == nil {
+			return commons.ZeroMoney, sql.ErrNoRows
+		}
+		if m.availableBalance(wallet, now, hold).LessThan(txn.DebitedAmount()) {
+			return commons.ZeroMoney, commons.InsufficientBalanceError
+		}
+	}
+
+	stored, err := m.newTransaction(txn, now)
+	if err != nil {
+		return commons.ZeroMoney, fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	postings := stored.Postings()
+	if err := m.checkPostings(postings); err != nil {
+		return commons.ZeroMoney, fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	// Apply the changes
+	if txn.IdempotencyKey != nil {
+		m.commitIdempotencyKey(txn.IdempotencyKey, now)
+	}
+	if quote != nil {
+		quote.ExecutedAt = sql.NullTime{Time: now, Valid: true}
+	}
+	if hold != nil {
+		hold.Status, hold.CapturedAmount, hold.UpdatedAt = string(commons.HoldStatusCaptured), txn.Amount, now
+	}
+	if stored.ReversalOf.Valid {
+		m.reversedAmounts[stored.ReversalOf.String] = m.reversedAmounts[stored.ReversalOf.String].Add(stored.CreditedAmount())
+	}
+	m.transactions = append(m.transactions, stored)
+	m.transactionsByID[stored.ID] = stored
+	m.addJournalEntry(stored, postings, now)
+
+	txn.ID, txn.Currency, txn.DestCurrency, txn.CreatedAt = stored.ID, stored.Currency, stored.DestCurrency, stored.CreatedAt
+	balance := wallet.Balance
+	txn.Seq, txn.BalanceAfter = sql.NullInt64{Int64: wallet.Seq, Valid: true}, &balance
+	return balance, nil
+}
+
+// newTransaction returns the row recorded for the transaction, checked like the transactions table does. The
+// currencies are taken from the wallets so that the ledger postings match the wallet accounts.
+func (m *inMemoryDB) newTransaction(txn *models.Transaction, now time.Time) (*models.Transaction, error) {
+	wallet := m.wallets[txn.WalletID]
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet %s does not exist", txn.WalletID)
+	}
+	if !txn.Amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
+	}
+	if txn.Fee.IsNegative() {
+		return nil, errors.New("fee cannot be negative")
+	}
+
+	stored := &models.Transaction{
+		ID:               uuid.NewString(),
+		WalletID:         txn.WalletID,
+		Type:             txn.Type,
+		Amount:           txn.Amount,
+		Currency:         wallet.Currency,
+		Fee:              txn.Fee,
+		QuoteID:          txn.QuoteID,
+		HoldID:           txn.HoldID,
+		ReversalOf:       txn.ReversalOf,
+		IdempotencyToken: txn.IdempotencyToken,
+		CreatedAt:        now,
+	}
+	switch commons.TransactionType(txn.Type) {
+	case commons.TransactionTypeDeposit, commons.TransactionTypeWithdraw:
+	case commons.TransactionTypeTransfer:
+		// Transfers record the credited amount and, for cross-currency transfers, the applied rate
+		recipient := m.wallets[txn.ToWalletID.String]
+		if recipient == nil {
+			return nil, fmt.Errorf("wallet %s does not exist", txn.ToWalletID.String)
+		}
+		credited := txn.CreditedAmount()
+		if !credited.IsPositive() {
+			return nil, errors.New("destination amount must be positive")
+		}
+		stored.ToWalletID = txn.ToWalletID
+		stored.DestAmount = &credited
+		stored.DestCurrency = sql.NullString{String: recipient.Currency, Valid: true}
+		stored.FXRate, stored.FXRateAt = txn.FXRate, txn.FXRateAt
+	default:
+		return nil, fmt.Errorf("unknown transaction type %s", txn.Type)
+	}
+	return stored, nil
+}
+
+// checkPostings verifies that the journal entry balances per currency, that wallet postings match the currency of
+// their wallet and that no wallet balance goes negative.
+func (m *inMemoryDB) checkPostings(postings []*models.Posting) error {
+	if len(postings) < 2 {
+		return errors.New("journal entry must have at least two postings")
+	}
+	sums := make(map[string]commons.Money)
+	balances := make(map[string]commons.Money)
+	for _, posting := range postings {
+		sums[posting.Currency] = sums[posting.Currency].Add(posting.Amount)
+		if posting.AccountType != string(commons.LedgerAccountWallet) {
+			continue
+		}
+		wallet := m.wallets[posting.AccountID]
+		if wallet == nil || wallet.Currency != posting.Currency {
+			return fmt.Errorf("no %s ledger account for wallet %s", posting.Currency, posting.AccountID)
+		}
+		balance, ok := balances[wallet.ID]
+		if !ok {
+			balance = wallet.Balance
+		}
+		balances[wallet.ID] = balance.Add(posting.Amount)
+		if balances[wallet.ID].IsNegative() {
+			return negativeBalanceError
+		}
+	}
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("journal entry does not sum to zero in %s", currency)
+		}
+	}
+	return nil
+}
+
+// addJournalEntry records the checked postings of the transaction. Every wallet posting takes the next sequence
+// number of the wallet and records the balance it leaves.
+func (m *inMemoryDB) addJournalEntry(txn *models.Transaction, postings []*models.Posting, now time.Time) {
+	entryID := uuid.NewString()
+	for _, posting := range postings {
+		posting.ID = int64(len(m.postings) + 1)
+		posting.EntryID, posting.TransactionID, posting.CreatedAt = entryID, txn.ID, now
+		if posting.AccountType != string(commons.LedgerAccountWallet) {
+			posting.AccountID = m.systemAccountID(posting.AccountType, posting.Currency)
+		} else {
+			wallet := m.wallets[posting.AccountID]
+			wallet.Balance, wallet.Seq, wallet.UpdatedAt = wallet.Balance.Add(posting.Amount), wallet.Seq+1, now
+			balance := wallet.Balance
+			posting.Seq, posting.BalanceAfter = sql.NullInt64{Int64: wallet.Seq, Valid: true}, &balance
+			m.walletPostings[wallet.ID] = append(m.walletPostings[wallet.ID], posting)
+			m.txnPostings[txnPostingKey(txn.ID, wallet.ID)] = posting
+		}
+		m.postings = append(m.postings, posting)
+	}
+}
+
+func (m *inMemoryDB) systemAccountID(accountType string, currency string) string {
+	key := accountType + "-" + currency
+	if _, ok := m.systemAccounts[key]; !ok {
+		m.systemAccounts[key] = uuid.NewString()
+	}
+	return m.systemAccounts[key]
+}
+
+func txnPostingKey(txnID string, walletID string) string {
+	return txnID + "-" + walletID
+}
+
+func idempotencyKeyID(key *models.IdempotencyKey) string {
+	return key.UserID + "-" + key.Token
+}
+
+// checkIdempotencyKey returns commons.DuplicateRequestError if another request has committed the key and it has not
+// expired.
+func (m *inMemoryDB) checkIdempotencyKey(key *models.IdempotencyKey, now time.Time) error {
+	if _, ok := m.users[key.UserID]; !ok {
+		return fmt.Errorf("failed to commit idempotency key: user %s does not exist", key.UserID)
+	}
+	existing := m.idempotencyKeys[idempotencyKeyID(key)]
+	if existing != nil && existing.committed && now.Before(existing.expiresAt) {
+		return commons.DuplicateRequestError
+	}
+	return nil
+}
+
+func (m *inMemoryDB) commitIdempotencyKey(key *models.IdempotencyKey, now time.Time) {
+	m.idempotencyKeys[idempotencyKeyID(key)] = &inMemoryIdempotencyKey{value: key.Value, committed: true, expiresAt: now.Add(key.Retention)}
+}
+
+// availableBalance returns the balance of the wallet minus its active holds, except the hold being captured.
+func (m *inMemoryDB) availableBalance(wallet *models.Wallet, now time.Time, captured *models.Hold) commons.Money {
+	available := wallet.Balance
+	for _, hold := range m.holds {
+		if hold.WalletID == wallet.ID && hold != captured && hold.IsActive(now) {
+			available = available.Sub(hold.Amount)
+		}
+	}
+	return available
+}
+
+func (m *inMemoryDB) walletView(wallet *models.Wallet, now time.Time) *models.Wallet {
+	view := copyOf(wallet)
+	view.AvailableBalance = m.availableBalance(wallet, now, nil)
+	return view
+}
+
+func (m *inMemoryDB) transactionView(txn *models.Transaction) *models.Transaction {
+	view := copyOf(txn)
+	view.ReversedAmount = m.reversedAmounts[txn.ID]
+	return view
+}
+
+func (m *inMemoryDB) addWallet(wallet *models.Wallet) {
+	m.wallets[wallet.ID] = wallet
+	m.walletIDs = append(m.walletIDs, wallet.ID)
+}
+
+// CreateWallet opens an additional wallet for an existing user. It returns nil when the user does not exist.
+func (m *inMemoryDB) CreateWallet(_ context.Context, userID string, label string, currency string) (*models.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, nil // user does not exist
+	}
+	now := timestampNow()
+	wallet := &models.Wallet{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Label:     sql.NullString{String: label, Valid: label != ""},
+		Currency:  currency,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.addWallet(wallet)
+	return copyOf(wallet), nil
+}
+
+func (m *inMemoryDB) GetUserWallets(_ context.Context, userID string) ([]*models.Wallet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := timestampNow()
+	var wallets []*models.Wallet
+	for _, walletID := range m.walletIDs {
+		if wallet := m.wallets[walletID]; wallet.UserID == userID {
+			wallets = append(wallets, m.walletView(wallet, now))
+		}
+	}
+	slices.SortStableFunc(wallets, func(a, b *models.Wallet) int {
+		return compareTransactionPosition(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return wallets, nil
+}
+
+func (m *inMemoryDB) CreateUserWallet(_ context.Context, currency string) (*models.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := timestampNow()
+	userID := uuid.NewString()
+	m.users[userID] = now
+	wallet := &models.Wallet{ID: uuid.NewString(), UserID: userID, Currency: currency, CreatedAt: now, UpdatedAt: now}
+	m.addWallet(wallet)
+	return copyOf(wallet), nil
+}
+
+func (m *inMemoryDB) CreateQuote(_ context.Context, quote *models.Quote) (*models.Quote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.wallets[quote.WalletID]; !ok {
+		return nil, fmt.Errorf("failed to create quote: wallet %s does not exist", quote.WalletID)
+	}
+	if _, ok := m.wallets[quote.ToWalletID.String]; quote.ToWalletID.Valid && !ok {
+		return nil, fmt.Errorf("failed to create quote: wallet %s does not exist", quote.ToWalletID.String)
+	}
+	now := timestampNow()
+	created := copyOf(quote)
+	created.ID, created.ExpiresAt, created.ExecutedAt, created.CreatedAt = uuid.NewString(), now.Add(commons.QuoteTTL), sql.NullTime{}, now
+	m.quotes[created.ID] = created
+	return copyOf(created), nil
+}
+
+func (m *inMemoryDB) GetQuote(_ context.Context, quoteID string) (*models.Quote, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	quote, ok := m.quotes[quoteID]
+	if !ok {
+		return nil, nil // quote does not exist
+	}
+	return copyOf(quote), nil
+}
+
+// CreateHold reserves funds of the wallet. It returns commons.InsufficientBalanceError when the available
+// balance does not cover the hold.
+func (m *inMemoryDB) CreateHold(_ context.Context, hold *models.Hold, ttl time.Duration) (*models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := timestampNow()
+	if hold.IdempotencyKey != nil {
+		if err := m.checkIdempotencyKey(hold.IdempotencyKey, now); err != nil {
+			return nil, err
+		}
+	}
+	wallet := m.wallets[hold.WalletID]
+	if wallet == nil {
+		return nil, fmt.Errorf("failed to read available balance: %w", sql.ErrNoRows)
+	}
+	if m.availableBalance(wallet, now, nil).LessThan(hold.Amount) {
+		return nil, commons.InsufficientBalanceError
+	}
+	if !hold.Amount.IsPositive() {
+		return nil, errors.New("failed to create hold: amount must be positive")
+	}
+	if _, ok := m.wallets[hold.ToWalletID.String]; hold.ToWalletID.Valid && !ok {
+		return nil, fmt.Errorf("failed to create hold: wallet %s does not exist", hold.ToWalletID.String)
+	}
+
+	if hold.IdempotencyKey != nil {
+		m.commitIdempotencyKey(hold.IdempotencyKey, now)
+	}
+	created := &models.Hold{
+		ID:             uuid.NewString(),
+		WalletID:       hold.WalletID,
+		ToWalletID:     hold.ToWalletID,
+		Amount:         hold.Amount,
+		CapturedAmount: commons.ZeroMoney,
+		Currency:       wallet.Currency,
+		Status:         string(commons.HoldStatusActive),
+		ExpiresAt:      now.Add(ttl),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	m.holds[created.ID] = created
+	return copyOf(created), nil
+}
+
+// GetTransaction returns the transaction with the amount already reversed, or nil if it does not exist.
+func (m *inMemoryDB) GetTransaction(_ context.Context, txnID string) (*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	txn, ok := m.transactionsByID[txnID]
+	if !ok {
+		return nil, nil // transaction does not exist
+	}
+	return m.transactionView(txn), nil
+}
+
+// GetTransactionByIdempotencyToken returns the latest transaction of the wallet created by a request with the
+// idempotency token, or nil if there is none.
+func (m *inMemoryDB) GetTransactionByIdempotencyToken(_ context.Context, walletID string, token string) (*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, txn := range slices.Backward(m.transactions) {
+		if txn.WalletID == walletID && txn.IdempotencyToken.Valid && txn.IdempotencyToken.String == token {
+			return m.transactionView(txn), nil
+		}
+	}
+	return nil, nil // no transaction was created with the token
+}
+
+// GetWalletPosting returns the posting of the transaction on the wallet account, or nil when the transaction did not
+// touch the wallet.
+func (m *inMemoryDB) GetWalletPosting(_ context.Context, walletID string, txnID string) (*models.Posting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posting, ok := m.txnPostings[txnPostingKey(txnID, walletID)]
+	if !ok {
+		return nil, nil
+	}
+	return copyOf(posting), nil
+}
+
+// GetLatestWalletPosting returns the latest posting of the wallet account created at or before asOf, or nil when the
+// wallet had no posting yet. Walking the postings by seq is correct only because the writers are serialized by the
+// mutex and timestamp their postings while holding it, so created_at never decreases as seq increases.
+func (m *inMemoryDB) GetLatestWalletPosting(_ context.Context, walletID string, asOf time.Time) (*models.Posting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, posting := range slices.Backward(m.walletPostings[walletID]) {
+		if !posting.CreatedAt.After(asOf) {
+			return copyOf(posting), nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *inMemoryDB) GetHold(_ context.Context, holdID string) (*models.Hold, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hold, ok := m.holds[holdID]
+	if !ok {
+		return nil, nil // hold does not exist
+	}
+	return copyOf(hold), nil
+}
+
+func (m *inMemoryDB) GetHeldAmount(_ context.Context, walletID string) (commons.Money, *time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := timestampNow()
+	held := commons.ZeroMoney
+	var expiresAt *time.Time
+	for _, hold := range m.holds {
+		if hold.WalletID == walletID && hold.IsActive(now) {
+			held = held.Add(hold.Amount)
+			if expiresAt == nil || hold.ExpiresAt.Before(*expiresAt) {
+				expiry := hold.ExpiresAt
+				expiresAt = &expiry
+			}
+		}
+	}
+	return held, expiresAt, nil
+}
+
+// VoidHold releases an active hold. It returns commons.HoldNotActiveError or commons.HoldExpiredError when the
+// hold has already been captured, voided or has expired.
+func (m *inMemoryDB) VoidHold(_ context.Context, holdID string, key *models.IdempotencyKey) (*models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hold, ok := m.holds[holdID]
+	if !ok {
+		return nil, fmt.Errorf("failed to read hold: %w", sql.ErrNoRows)
+	}
+	now := timestampNow()
+	if hold.Status != string(commons.HoldStatusActive) {
+		return nil, commons.HoldNotActiveError
+	}
+	if !hold.IsActive(now) {
+		return nil, commons.HoldExpiredError
+	}
+	if key != nil {
+		if err := m.checkIdempotencyKey(key, now); err != nil {
+			return nil, err
+		}
+		m.commitIdempotencyKey(key, now)
+	}
+	hold.Status, hold.UpdatedAt = string(commons.HoldStatusVoided), now
+	return copyOf(hold), nil
+}
+
+// ExpireHolds marks the active holds past their expiry as expired and returns how many were expired.
+func (m *inMemoryDB) ExpireHolds(_ context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := timestampNow()
+	var expired int64
+	for _, hold := range m.holds {
+		if hold.Status == string(commons.HoldStatusActive) && !hold.IsActive(now) {
+			hold.Status, hold.UpdatedAt = string(commons.HoldStatusExpired), now
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func statementKey(walletID string, periodStart time.Time, periodEnd time.Time) string {
+	return fmt.Sprintf("%s-%d-%d", walletID, periodStart.UnixMicro(), periodEnd.UnixMicro())
+}
+
+func (m *inMemoryDB) GetStatement(_ context.Context, walletID string, periodStart time.Time, periodEnd time.Time) (*models.Statement, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statement, ok := m.statements[statementKey(walletID, periodStart, periodEnd)]
+	if !ok {
+		return nil, nil // statement not generated yet
+	}
+	return copyOf(statement), nil
+}
+
+// SaveStatement persists the statement of a closed period. When the statement was already saved, the stored one is
+// returned unchanged.
+func (m *inMemoryDB) SaveStatement(_ context.Context, statement *models.Statement) (*models.Statement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.wallets[statement.WalletID]; !ok {
+		return nil, fmt.Errorf("failed to save statement: wallet %s does not exist", statement.WalletID)
+	}
+	key := statementKey(statement.WalletID, statement.PeriodStart, statement.PeriodEnd)
+	if saved, ok := m.statements[key]; ok {
+		return copyOf(saved), nil
+	}
+	saved := copyOf(statement)
+	saved.ID, saved.Content, saved.CreatedAt = uuid.NewString(), slices.Clone(statement.Content), timestampNow()
+	m.statements[key] = saved
+	return copyOf(saved), nil
+}
+
+func (m *inMemoryDB) GetWalletUsers(_ context.Context) ([]*models.Wallet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wallets := make([]*models.Wallet, 0, len(m.walletIDs))
+	for _, walletID := range m.walletIDs {
+		wallet := m.wallets[walletID]
+		wallets = append(wallets, &models.Wallet{ID: wallet.ID, UserID: wallet.UserID, Currency: wallet.Currency, CreatedAt: wallet.CreatedAt})
+	}
+	return wallets, nil
+}
+
+// FindBalanceMismatches recomputes the balance of every wallet from its transaction history and returns the wallets
+// whose cached balance differs, ordered by wallet ID.
+func (m *inMemoryDB) FindBalanceMismatches(_ context.Context) (int, []*models.WalletMismatch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := make(map[string]commons.Money, len(m.wallets))
+	for _, txn := range m.transactions {
+		if commons.TransactionType(txn.Type) == commons.TransactionTypeDeposit {
+			expected[txn.WalletID] = expected[txn.WalletID].Add(txn.Amount)
+			continue
+		}
+		expected[txn.WalletID] = expected[txn.WalletID].Sub(txn.DebitedAmount())
+		if commons.TransactionType(txn.Type) == commons.TransactionTypeTransfer {
+			expected[txn.ToWalletID.String] = expected[txn.ToWalletID.String].Add(txn.CreditedAmount())
+		}
+	}
+
+	var mismatches []*models.WalletMismatch
+	for _, wallet := range m.wallets {
+		if !wallet.Balance.Equal(expected[wallet.ID]) {
+			mismatches = append(mismatches, &models.WalletMismatch{
+				WalletID:        wallet.ID,
+				Currency:        wallet.Currency,
+				Balance:         wallet.Balance,
+				ExpectedBalance: expected[wallet.ID],
+				Drift:           wallet.Balance.Sub(expected[wallet.ID]),
+			})
+		}
+	}
+	slices.SortFunc(mismatches, func(a, b *models.WalletMismatch) int {
+		return strings.Compare(a.WalletID, b.WalletID)
+	})
+	return len(m.wallets), mismatches, nil
+}
+
+func (m *inMemoryDB) SaveReconciliationReport(_ context.Context, report *models.ReconciliationReport) (*models.ReconciliationReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, mismatch := range report.Mismatches {
+		if _, ok := m.wallets[mismatch.WalletID]; !ok {
+			return nil, fmt.Errorf("failed to insert reconciliation mismatch: wallet %s does not exist", mismatch.WalletID)
+		}
+	}
+	saved := copyOf(report)
+	saved.ID = uuid.NewString()
+	m.reports = append(m.reports, saved)
+	return copyOf(saved), nil
+}
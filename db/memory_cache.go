@@ -0,0 +1,283 @@
+package db
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"WalletApp/commons"
+)
+
+type inMemoryCacheEntry struct {
+	key       string
+	value     string
+	bits      []byte
+	expiresAt time.Time // zero if the key does not expire
+	// version is the version of the value set by SetWithExpirationIfVersionIsNotOlder, kept in the same entry so that
+	// both are replaced at once. A deleted versioned key stays as a tombstone holding the version until it expires.
+	version   int64
+	versioned bool
+	deleted   bool
+	element   *list.Element // position in the LRU list, nil if the key does not expire
+	index     int           // position in the expiry heap, -1 if the key does not expire
+}
+
+// expiryHeap orders the expiring keys by their expiration, the earliest first.
+type expiryHeap []*inMemoryCacheEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	entry := x.(*inMemoryCacheEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// inMemoryCache is a Cache kept in the process. Like Redis with the volatile-lru policy, it evicts the least recently
+// used key having an expiration when it is full, and refuses new keys with commons.CacheFullError when only keys
+// without expiration are left. Without eviction, like Redis with the noeviction policy, it only removes the expired
+// keys and refuses new keys until one expires.
+//
+// Only the keys having an expiration are kept in the LRU list and the expiry heap, so that making room for a key
+// takes amortized constant time: the expired keys are popped from the heap, each once, or else the back of the list
+// is evicted.
+type inMemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	evict      bool
+	entries    map[string]*inMemoryCacheEntry
+	lru        *list.List // expiring keys, most recently used first
+	expiries   expiryHeap
+}
+
+// NewInMemoryCache returns a Cache holding up to maxEntries keys in memory, evicting the least recently used ones.
+// Intended for tests and local development of a single instance.
+func NewInMemoryCache(maxEntries int) Cache {
+	return newInMemoryCache(maxEntries, true)
+}
+
+func newInMemoryCache(maxEntries int, evict bool) *inMemoryCache {
+	return &inMemoryCache{
+		maxEntries: maxEntries,
+		evict:      evict,
+		entries:    make(map[string]*inMemoryCacheEntry),
+		lru:        list.New(),
+	}
+}
+
+func (m *inMemoryCache) SetWithExpirationIfKeyIsNotSet(_ context.Context, key string, value string, duration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.get(key) != nil {
+		return false, nil
+	}
+	_, err := m.set(key, value, duration)
+	return err == nil, err
+}
+
+func (m *inMemoryCache) SetWithExpiration(_ context.Context, key string, value string, duration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.set(key, value, duration)
+	return err
+}
+
+func (m *inMemoryCache) SetWithExpirationIfVersionIsNotOlder(_ context.Context, key string, value string, version int64, duration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current := m.lookup(key); current != nil && current.versioned && current.version > version {
+		return false, nil
+	}
+	entry, err := m.set(key, value, duration)
+	if err != nil {
+		return false, err
+	}
+	entry.version, entry.versioned = version, true
+	return true, nil
+}
+
+func (m *inMemoryCache) Get(_ context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.get(key)
+	if entry == nil {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (m *inMemoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.lookup(key)
+	if entry == nil {
+		return nil
+	}
+	if entry.versioned {
+		// the version outlives the value until it expires
+		entry.value, entry.bits, entry.deleted = "", nil, true
+		return nil
+	}
+	m.remove(entry)
+	return nil
+}
+
+func (m *inMemoryCache) SetBits(_ context.Context, key string, offsets []uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.get(key)
+	if entry == nil {
+		var err error
+		if entry, err = m.set(key, "", 0); err != nil {
+			return err
+		}
+	}
+	for _, offset := range offsets {
+		index := offset / 8
+		if index >= uint64(len(entry.bits)) {
+			bits := make([]byte, index+1)
+			copy(bits, entry.bits)
+			entry.bits = bits
+		}
+		entry.bits[index] |= 0x80 >> (offset % 8) // most significant bit first, as in Redis
+	}
+	return nil
+}
+
+func (m *inMemoryCache) AreBitsSet(_ context.Context, key string, offsets []uint64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.get(key)
+	if entry == nil {
+		return false, nil
+	}
+	for _, offset := range offsets {
+		index := offset / 8
+		if index >= uint64(len(entry.bits)) || entry.bits[index]&(0x80>>(offset%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lookup returns the entry of the key, deleted or not, unless it has expired.
+func (m *inMemoryCache) lookup(key string) *inMemoryCacheEntry {
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if m.isExpired(entry) {
+		m.remove(entry)
+		return nil
+	}
+	return entry
+}
+
+// get returns the entry of the key unless it has expired or was deleted, and marks it as recently used.
+func (m *inMemoryCache) get(key string) *inMemoryCacheEntry {
+	entry := m.lookup(key)
+	if entry == nil || entry.deleted {
+		return nil
+	}
+	if entry.element != nil {
+		m.lru.MoveToFront(entry.element)
+	}
+	return entry
+}
+
+// set replaces the value of the key, making room for it if the key is new, and returns its entry. A zero duration
+// keeps the key until it is deleted. The version of the key is kept.
+func (m *inMemoryCache) set(key string, value string, duration time.Duration) (*inMemoryCacheEntry, error) {
+	var expiresAt time.Time
+	if duration > 0 {
+		expiresAt = time.Now().Add(duration)
+	}
+	entry := m.lookup(key)
+	if entry == nil {
+		if len(m.entries) >= m.maxEntries && !m.makeRoom() {
+			return nil, commons.CacheFullError
+		}
+		entry = &inMemoryCacheEntry{key: key, index: -1}
+		m.entries[key] = entry
+	}
+	entry.value, entry.bits, entry.deleted = value, nil, false
+	m.setExpiration(entry, expiresAt)
+	return entry, nil
+}
+
+// setExpiration moves the entry in or out of the LRU list and the expiry heap as its expiration changes.
+func (m *inMemoryCache) setExpiration(entry *inMemoryCacheEntry, expiresAt time.Time) {
+	entry.expiresAt = expiresAt
+	switch {
+	case expiresAt.IsZero() && entry.index >= 0:
+		m.lru.Remove(entry.element)
+		entry.element = nil
+		heap.Remove(&m.expiries, entry.index)
+	case expiresAt.IsZero():
+	case entry.index >= 0:
+		m.lru.MoveToFront(entry.element)
+		heap.Fix(&m.expiries, entry.index)
+	default:
+		entry.element = m.lru.PushFront(entry)
+		heap.Push(&m.expiries, entry)
+	}
+}
+
+// makeRoom removes the expired keys, or else evicts the least recently used key having an expiration. It returns
+// false if nothing could be removed.
+func (m *inMemoryCache) makeRoom() bool {
+	removed := false
+	for len(m.expiries) > 0 && m.isExpired(m.expiries[0]) {
+		m.remove(m.expiries[0])
+		removed = true
+	}
+	if removed {
+		return true
+	}
+	if !m.evict {
+		return false
+	}
+	back := m.lru.Back()
+	if back == nil {
+		return false
+	}
+	m.remove(back.Value.(*inMemoryCacheEntry))
+	return true
+}
+
+func (m *inMemoryCache) isExpired(entry *inMemoryCacheEntry) bool {
+	return !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt)
+}
+
+func (m *inMemoryCache) remove(entry *inMemoryCacheEntry) {
+	if entry.index >= 0 {
+		m.lru.Remove(entry.element)
+		entry.element = nil
+		heap.Remove(&m.expiries, entry.index)
+	}
+	delete(m.entries, entry.key)
+}
@@ -0,0 +1,196 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"WalletApp/commons"
+	"WalletApp/db"
+)
+
+func TestInMemoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should expire keys after their duration", func(t *testing.T) {
+		cache := db.NewInMemoryCache(10)
+		assert.Nil(t, cache.SetWithExpiration(ctx, "idm-1", "in-flight", 20*time.Millisecond))
+		assert.Nil(t, cache.SetWithExpiration(ctx, "filter-ready", "1", 0))
+
+		value, found, err := cache.Get(ctx, "idm-1")
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "in-flight", value)
+
+		time.Sleep(30 * time.Millisecond)
+		_, found, err = cache.Get(ctx, "idm-1")
+		assert.Nil(t, err)
+		assert.False(t, found)
+		_, found, _ = cache.Get(ctx, "filter-ready")
+		assert.True(t, found)
+
+		ok, err := cache.SetWithExpirationIfKeyIsNotSet(ctx, "idm-1", "retry", time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("should set a key only once under concurrency", func(t *testing.T) {
+		cache := db.NewInMemoryCache(10)
+		var set atomic.Int32
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := cache.SetWithExpirationIfKeyIsNotSet(ctx, "idm-1", fmt.Sprint(i), time.Minute)
+				assert.Nil(t, err)
+				if ok {
+					set.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), set.Load())
+	})
+
+	t.Run("should reject values older than the cached version even after a delete", func(t *testing.T) {
+		cache := db.NewInMemoryCache(10)
+		ok, err := cache.SetWithExpirationIfVersionIsNotOlder(ctx, "wallet-1", "seq 5", 5, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Nil(t, cache.Delete(ctx, "wallet-1"))
+
+		ok, err = cache.SetWithExpirationIfVersionIsNotOlder(ctx, "wallet-1", "seq 4", 4, time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		_, found, _ := cache.Get(ctx, "wallet-1")
+		assert.False(t, found)
+
+		ok, err = cache.SetWithExpirationIfVersionIsNotOlder(ctx, "wallet-1", "seq 5", 5, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("should keep bitmaps", func(t *testing.T) {
+		cache := db.NewInMemoryCache(10)
+		assert.Nil(t, cache.SetBits(ctx, "filter", []uint64{3, 1000}))
+		set, err := cache.AreBitsSet(ctx, "filter", []uint64{3, 1000})
+		assert.Nil(t, err)
+		assert.True(t, set)
+		set, err = cache.AreBitsSet(ctx, "filter", []uint64{3, 4})
+		assert.Nil(t, err)
+		assert.False(t, set)
+		set, err = cache.AreBitsSet(ctx, "filter", []uint64{5000})
+		assert.Nil(t, err)
+		assert.False(t, set)
+	})
+
+	t.Run("should evict the least recently used expiring key when full", func(t *testing.T) {
+		cache := db.NewInMemoryCache(3)
+		assert.Nil(t, cache.SetWithExpiration(ctx, "filter-ready", "1", 0))
+		assert.Nil(t, cache.SetWithExpiration(ctx, "wallet-1", "1", time.Minute))
+		assert.Nil(t, cache.SetWithExpiration(ctx, "wallet-2", "2", time.Minute))
+		_, _, _ = cache.Get(ctx, "wallet-1")
+
+		assert.Nil(t, cache.SetWithExpiration(ctx, "wallet-3", "3", time.Minute))
+		_, found, _ := cache.Get(ctx, "wallet-2")
+		assert.False(t, found)
+		for _, key := range []string{"filter-ready", "wallet-1", "wallet-3"} {
+			_, found, _ = cache.Get(ctx, key)
+			assert.True(t, found, key)
+		}
+	})
+
+	t.Run("should refuse new keys when only keys without expiration are left", func(t *testing.T) {
+		cache := db.NewInMemoryCache(1)
+		assert.Nil(t, cache.SetWithExpiration(ctx, "filter-ready", "1", 0))
+		err := cache.SetWithExpiration(ctx, "wallet-1", "1", time.Minute)
+		assert.ErrorIs(t, err, commons.CacheFullError)
+		// existing keys can still be replaced
+		assert.Nil(t, cache.SetWithExpiration(ctx, "filter-ready", "2", 0))
+	})
+
+	t.Run("should remove the expired keys before evicting", func(t *testing.T) {
+		cache := db.NewInMemoryCache(2)
+		assert.Nil(t, cache.SetWithExpiration(ctx, "wallet-1", "1", time.Minute))
+		assert.Nil(t, cache.SetWithExpiration(ctx, "wallet-2", "2", 20*time.Millisecond))
+		_, _, _ = cache.Get(ctx, "wallet-2")
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Nil(t, cache.SetWithExpiration(ctx, "wallet-3", "3", time.Minute))
+		for _, key := range []string{"wallet-1", "wallet-3"} {
+			_, found, _ := cache.Get(ctx, key)
+			assert.True(t, found, key)
+		}
+	})
+
+	t.Run("should evict without walking the keys", func(t *testing.T) {
+		cache := db.NewInMemoryCache(50_000)
+		assert.Nil(t, cache.SetWithExpiration(ctx, "filter-ready", "1", 0))
+		start := time.Now()
+		for i := range 200_000 {
+			assert.Nil(t, cache.SetWithExpiration(ctx, fmt.Sprintf("wallet-%d", i), "1", time.Minute))
+		}
+		assert.Less(t, time.Since(start), 5*time.Second)
+		_, found, _ := cache.Get(ctx, "wallet-199999")
+		assert.True(t, found)
+		_, found, _ = cache.Get(ctx, "wallet-0")
+		assert.False(t, found)
+	})
+
+	t.Run("should keep the version in the entry of the value", func(t *testing.T) {
+		cache := db.NewInMemoryCache(1)
+		ok, err := cache.SetWithExpirationIfVersionIsNotOlder(ctx, "wallet-1", "seq 5", 5, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		value, found, _ := cache.Get(ctx, "wallet-1")
+		assert.True(t, found)
+		assert.Equal(t, "seq 5", value)
+
+		// a plain set keeps the version
+		assert.Nil(t, cache.SetWithExpiration(ctx, "wallet-1", "seq 6", time.Minute))
+		ok, err = cache.SetWithExpirationIfVersionIsNotOlder(ctx, "wallet-1", "seq 4", 4, time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		value, _, _ = cache.Get(ctx, "wallet-1")
+		assert.Equal(t, "seq 6", value)
+	})
+}
+
+func TestInMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should refuse new keys rather than evicting the kept ones", func(t *testing.T) {
+		store := db.NewInMemoryIdempotencyStore(1)
+		ok, err := store.SetWithExpirationIfKeyIsNotSet(ctx, "user-1", "token-1", "in-flight", time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		ok, err = store.SetWithExpirationIfKeyIsNotSet(ctx, "user-1", "token-2", "in-flight", time.Minute)
+		assert.ErrorIs(t, err, commons.CacheFullError)
+		assert.False(t, ok)
+		value, found, err := store.Get(ctx, "user-1", "token-1")
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "in-flight", value)
+		// the response of the kept key can still be stored
+		assert.Nil(t, store.SetWithExpiration(ctx, "user-1", "token-1", "done", time.Minute))
+	})
+
+	t.Run("should take new keys once the kept ones expire", func(t *testing.T) {
+		store := db.NewInMemoryIdempotencyStore(1)
+		ok, err := store.SetWithExpirationIfKeyIsNotSet(ctx, "user-1", "token-1", "in-flight", 20*time.Millisecond)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		time.Sleep(30 * time.Millisecond)
+		ok, err = store.SetWithExpirationIfKeyIsNotSet(ctx, "user-1", "token-2", "in-flight", time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,12 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"WalletApp/db"
+)
+
+func TestInMemoryDB(t *testing.T) {
+	testDatabaseConformance(t, context.Background(), db.NewInMemoryDB())
+}
@@ -58,34 +58,231 @@ func (mr *MockDatabaseMockRecorder) CheckWalletOwner(ctx, walletID, userID any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckWalletOwner", reflect.TypeOf((*MockDatabase)(nil).CheckWalletOwner), ctx, walletID, userID)
 }
 
+// CreateHold mocks base method.
+func (m *MockDatabase) CreateHold(ctx context.Context, hold *models.Hold, ttl time.Duration) (*models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, hold, ttl)
+	ret0, _ := ret[0].(*models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockDatabaseMockRecorder) CreateHold(ctx, hold, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockDatabase)(nil).CreateHold), ctx, hold, ttl)
+}
+
+// CreateQuote mocks base method.
+func (m *MockDatabase) CreateQuote(ctx context.Context, quote *models.Quote) (*models.Quote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateQuote", ctx, quote)
+	ret0, _ := ret[0].(*models.Quote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateQuote indicates an expected call of CreateQuote.
+func (mr *MockDatabaseMockRecorder) CreateQuote(ctx, quote any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateQuote", reflect.TypeOf((*MockDatabase)(nil).CreateQuote), ctx, quote)
+}
+
 // CreateUserWallet mocks base method.
-func (m *MockDatabase) CreateUserWallet(ctx context.Context) (*models.Wallet, error) {
+func (m *MockDatabase) CreateUserWallet(ctx context.Context, currency string) (*models.Wallet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUserWallet", ctx)
+	ret := m.ctrl.Call(m, "CreateUserWallet", ctx, currency)
 	ret0, _ := ret[0].(*models.Wallet)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUserWallet indicates an expected call of CreateUserWallet.
-func (mr *MockDatabaseMockRecorder) CreateUserWallet(ctx any) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) CreateUserWallet(ctx, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWallet", reflect.TypeOf((*MockDatabase)(nil).CreateUserWallet), ctx, currency)
+}
+
+// CreateWallet mocks base method.
+func (m *MockDatabase) CreateWallet(ctx context.Context, userID, label, currency string) (*models.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWallet", ctx, userID, label, currency)
+	ret0, _ := ret[0].(*models.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWallet indicates an expected call of CreateWallet.
+func (mr *MockDatabaseMockRecorder) CreateWallet(ctx, userID, label, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWallet", reflect.TypeOf((*MockDatabase)(nil).CreateWallet), ctx, userID, label, currency)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockDatabase) ExpireHolds(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockDatabaseMockRecorder) ExpireHolds(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockDatabase)(nil).ExpireHolds), ctx)
+}
+
+// FindBalanceMismatches mocks base method.
+func (m *MockDatabase) FindBalanceMismatches(ctx context.Context) (int, []*models.WalletMismatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindBalanceMismatches", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].([]*models.WalletMismatch)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindBalanceMismatches indicates an expected call of FindBalanceMismatches.
+func (mr *MockDatabaseMockRecorder) FindBalanceMismatches(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWallet", reflect.TypeOf((*MockDatabase)(nil).CreateUserWallet), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBalanceMismatches", reflect.TypeOf((*MockDatabase)(nil).FindBalanceMismatches), ctx)
+}
+
+// GetHeldAmount mocks base method.
+func (m *MockDatabase) GetHeldAmount(ctx context.Context, walletID string) (commons.Money, *time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeldAmount", ctx, walletID)
+	ret0, _ := ret[0].(commons.Money)
+	ret1, _ := ret[1].(*time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetHeldAmount indicates an expected call of GetHeldAmount.
+func (mr *MockDatabaseMockRecorder) GetHeldAmount(ctx, walletID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeldAmount", reflect.TypeOf((*MockDatabase)(nil).GetHeldAmount), ctx, walletID)
+}
+
+// GetHold mocks base method.
+func (m *MockDatabase) GetHold(ctx context.Context, holdID string) (*models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, holdID)
+	ret0, _ := ret[0].(*models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockDatabaseMockRecorder) GetHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockDatabase)(nil).GetHold), ctx, holdID)
+}
+
+// GetLatestWalletPosting mocks base method.
+func (m *MockDatabase) GetLatestWalletPosting(ctx context.Context, walletID string, asOf time.Time) (*models.Posting, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestWalletPosting", ctx, walletID, asOf)
+	ret0, _ := ret[0].(*models.Posting)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestWalletPosting indicates an expected call of GetLatestWalletPosting.
+func (mr *MockDatabaseMockRecorder) GetLatestWalletPosting(ctx, walletID, asOf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestWalletPosting", reflect.TypeOf((*MockDatabase)(nil).GetLatestWalletPosting), ctx, walletID, asOf)
+}
+
+// GetQuote mocks base method.
+func (m *MockDatabase) GetQuote(ctx context.Context, quoteID string) (*models.Quote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuote", ctx, quoteID)
+	ret0, _ := ret[0].(*models.Quote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuote indicates an expected call of GetQuote.
+func (mr *MockDatabaseMockRecorder) GetQuote(ctx, quoteID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuote", reflect.TypeOf((*MockDatabase)(nil).GetQuote), ctx, quoteID)
+}
+
+// GetStatement mocks base method.
+func (m *MockDatabase) GetStatement(ctx context.Context, walletID string, periodStart, periodEnd time.Time) (*models.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatement", ctx, walletID, periodStart, periodEnd)
+	ret0, _ := ret[0].(*models.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatement indicates an expected call of GetStatement.
+func (mr *MockDatabaseMockRecorder) GetStatement(ctx, walletID, periodStart, periodEnd any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatement", reflect.TypeOf((*MockDatabase)(nil).GetStatement), ctx, walletID, periodStart, periodEnd)
+}
+
+// GetTransaction mocks base method.
+func (m *MockDatabase) GetTransaction(ctx context.Context, txnID string) (*models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransaction", ctx, txnID)
+	ret0, _ := ret[0].(*models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransaction indicates an expected call of GetTransaction.
+func (mr *MockDatabaseMockRecorder) GetTransaction(ctx, txnID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockDatabase)(nil).GetTransaction), ctx, txnID)
+}
+
+// GetTransactionByIdempotencyToken mocks base method.
+func (m *MockDatabase) GetTransactionByIdempotencyToken(ctx context.Context, walletID, token string) (*models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionByIdempotencyToken", ctx, walletID, token)
+	ret0, _ := ret[0].(*models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionByIdempotencyToken indicates an expected call of GetTransactionByIdempotencyToken.
+func (mr *MockDatabaseMockRecorder) GetTransactionByIdempotencyToken(ctx, walletID, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionByIdempotencyToken", reflect.TypeOf((*MockDatabase)(nil).GetTransactionByIdempotencyToken), ctx, walletID, token)
 }
 
 // GetTransactions mocks base method.
-func (m *MockDatabase) GetTransactions(ctx context.Context, walletID string, limit, offset int32) ([]*models.Transaction, error) {
+func (m *MockDatabase) GetTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, limit, offset int32) ([]*models.Transaction, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransactions", ctx, walletID, limit, offset)
+	ret := m.ctrl.Call(m, "GetTransactions", ctx, walletID, filter, limit, offset)
 	ret0, _ := ret[0].([]*models.Transaction)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTransactions indicates an expected call of GetTransactions.
-func (mr *MockDatabaseMockRecorder) GetTransactions(ctx, walletID, limit, offset any) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetTransactions(ctx, walletID, filter, limit, offset any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockDatabase)(nil).GetTransactions), ctx, walletID, limit, offset)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockDatabase)(nil).GetTransactions), ctx, walletID, filter, limit, offset)
+}
+
+// GetUserWallets mocks base method.
+func (m *MockDatabase) GetUserWallets(ctx context.Context, userID string) ([]*models.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserWallets", ctx, userID)
+	ret0, _ := ret[0].([]*models.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserWallets indicates an expected call of GetUserWallets.
+func (mr *MockDatabaseMockRecorder) GetUserWallets(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserWallets", reflect.TypeOf((*MockDatabase)(nil).GetUserWallets), ctx, userID)
 }
 
 // GetWallet mocks base method.
@@ -104,10 +301,10 @@ func (mr *MockDatabaseMockRecorder) GetWallet(ctx, walletID any) *gomock.Call {
 }
 
 // GetWalletBalance mocks base method.
-func (m *MockDatabase) GetWalletBalance(ctx context.Context, walletID string) (float64, error) {
+func (m *MockDatabase) GetWalletBalance(ctx context.Context, walletID string) (commons.Money, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetWalletBalance", ctx, walletID)
-	ret0, _ := ret[0].(float64)
+	ret0, _ := ret[0].(commons.Money)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -118,6 +315,21 @@ func (mr *MockDatabaseMockRecorder) GetWalletBalance(ctx, walletID any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWalletBalance", reflect.TypeOf((*MockDatabase)(nil).GetWalletBalance), ctx, walletID)
 }
 
+// GetWalletPosting mocks base method.
+func (m *MockDatabase) GetWalletPosting(ctx context.Context, walletID, txnID string) (*models.Posting, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWalletPosting", ctx, walletID, txnID)
+	ret0, _ := ret[0].(*models.Posting)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWalletPosting indicates an expected call of GetWalletPosting.
+func (mr *MockDatabaseMockRecorder) GetWalletPosting(ctx, walletID, txnID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWalletPosting", reflect.TypeOf((*MockDatabase)(nil).GetWalletPosting), ctx, walletID, txnID)
+}
+
 // GetWalletUsers mocks base method.
 func (m *MockDatabase) GetWalletUsers(ctx context.Context) ([]*models.Wallet, error) {
 	m.ctrl.T.Helper()
@@ -134,18 +346,116 @@ func (mr *MockDatabaseMockRecorder) GetWalletUsers(ctx any) *gomock.Call {
 }
 
 // InsertTxnAndGetWalletBalance mocks base method.
-func (m *MockDatabase) InsertTxnAndGetWalletBalance(ctx context.Context, fromAccount, toAccount string, amount float64, trsType commons.TransactionType) (float64, error) {
+func (m *MockDatabase) InsertTxnAndGetWalletBalance(ctx context.Context, txn *models.Transaction) (commons.Money, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertTxnAndGetWalletBalance", ctx, fromAccount, toAccount, amount, trsType)
-	ret0, _ := ret[0].(float64)
+	ret := m.ctrl.Call(m, "InsertTxnAndGetWalletBalance", ctx, txn)
+	ret0, _ := ret[0].(commons.Money)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // InsertTxnAndGetWalletBalance indicates an expected call of InsertTxnAndGetWalletBalance.
-func (mr *MockDatabaseMockRecorder) InsertTxnAndGetWalletBalance(ctx, fromAccount, toAccount, amount, trsType any) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) InsertTxnAndGetWalletBalance(ctx, txn any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertTxnAndGetWalletBalance", reflect.TypeOf((*MockDatabase)(nil).InsertTxnAndGetWalletBalance), ctx, fromAccount, toAccount, amount, trsType)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertTxnAndGetWalletBalance", reflect.TypeOf((*MockDatabase)(nil).InsertTxnAndGetWalletBalance), ctx, txn)
+}
+
+// SaveReconciliationReport mocks base method.
+func (m *MockDatabase) SaveReconciliationReport(ctx context.Context, report *models.ReconciliationReport) (*models.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveReconciliationReport", ctx, report)
+	ret0, _ := ret[0].(*models.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveReconciliationReport indicates an expected call of SaveReconciliationReport.
+func (mr *MockDatabaseMockRecorder) SaveReconciliationReport(ctx, report any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveReconciliationReport", reflect.TypeOf((*MockDatabase)(nil).SaveReconciliationReport), ctx, report)
+}
+
+// SaveStatement mocks base method.
+func (m *MockDatabase) SaveStatement(ctx context.Context, statement *models.Statement) (*models.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveStatement", ctx, statement)
+	ret0, _ := ret[0].(*models.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveStatement indicates an expected call of SaveStatement.
+func (mr *MockDatabaseMockRecorder) SaveStatement(ctx, statement any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveStatement", reflect.TypeOf((*MockDatabase)(nil).SaveStatement), ctx, statement)
+}
+
+// StreamTransactions mocks base method.
+func (m *MockDatabase) StreamTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, fn func(*models.Transaction) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamTransactions", ctx, walletID, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamTransactions indicates an expected call of StreamTransactions.
+func (mr *MockDatabaseMockRecorder) StreamTransactions(ctx, walletID, filter, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamTransactions", reflect.TypeOf((*MockDatabase)(nil).StreamTransactions), ctx, walletID, filter, fn)
+}
+
+// VoidHold mocks base method.
+func (m *MockDatabase) VoidHold(ctx context.Context, holdID string, key *models.IdempotencyKey) (*models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VoidHold", ctx, holdID, key)
+	ret0, _ := ret[0].(*models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VoidHold indicates an expected call of VoidHold.
+func (mr *MockDatabaseMockRecorder) VoidHold(ctx, holdID, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoidHold", reflect.TypeOf((*MockDatabase)(nil).VoidHold), ctx, holdID, key)
+}
+
+// MockRateProvider is a mock of RateProvider interface.
+type MockRateProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateProviderMockRecorder
+	isgomock struct{}
+}
+
+// MockRateProviderMockRecorder is the mock recorder for MockRateProvider.
+type MockRateProviderMockRecorder struct {
+	mock *MockRateProvider
+}
+
+// NewMockRateProvider creates a new mock instance.
+func NewMockRateProvider(ctrl *gomock.Controller) *MockRateProvider {
+	mock := &MockRateProvider{ctrl: ctrl}
+	mock.recorder = &MockRateProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateProvider) EXPECT() *MockRateProviderMockRecorder {
+	return m.recorder
+}
+
+// GetRate mocks base method.
+func (m *MockRateProvider) GetRate(ctx context.Context, baseCurrency, quoteCurrency string) (*models.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRate", ctx, baseCurrency, quoteCurrency)
+	ret0, _ := ret[0].(*models.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRate indicates an expected call of GetRate.
+func (mr *MockRateProviderMockRecorder) GetRate(ctx, baseCurrency, quoteCurrency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRate", reflect.TypeOf((*MockRateProvider)(nil).GetRate), ctx, baseCurrency, quoteCurrency)
 }
 
 // MockCache is a mock of Cache interface.
@@ -172,6 +482,79 @@ func (m *MockCache) EXPECT() *MockCacheMockRecorder {
 	return m.recorder
 }
 
+// AreBitsSet mocks base method.
+func (m *MockCache) AreBitsSet(ctx context.Context, key string, offsets []uint64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AreBitsSet", ctx, key, offsets)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AreBitsSet indicates an expected call of AreBitsSet.
+func (mr *MockCacheMockRecorder) AreBitsSet(ctx, key, offsets any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AreBitsSet", reflect.TypeOf((*MockCache)(nil).AreBitsSet), ctx, key, offsets)
+}
+
+// Delete mocks base method.
+func (m *MockCache) Delete(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCacheMockRecorder) Delete(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCache)(nil).Delete), ctx, key)
+}
+
+// Get mocks base method.
+func (m *MockCache) Get(ctx context.Context, key string) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockCacheMockRecorder) Get(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache)(nil).Get), ctx, key)
+}
+
+// SetBits mocks base method.
+func (m *MockCache) SetBits(ctx context.Context, key string, offsets []uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBits", ctx, key, offsets)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBits indicates an expected call of SetBits.
+func (mr *MockCacheMockRecorder) SetBits(ctx, key, offsets any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBits", reflect.TypeOf((*MockCache)(nil).SetBits), ctx, key, offsets)
+}
+
+// SetWithExpiration mocks base method.
+func (m *MockCache) SetWithExpiration(ctx context.Context, key, value string, duration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpiration", ctx, key, value, duration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWithExpiration indicates an expected call of SetWithExpiration.
+func (mr *MockCacheMockRecorder) SetWithExpiration(ctx, key, value, duration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockCache)(nil).SetWithExpiration), ctx, key, value, duration)
+}
+
 // SetWithExpirationIfKeyIsNotSet mocks base method.
 func (m *MockCache) SetWithExpirationIfKeyIsNotSet(ctx context.Context, key, value string, duration time.Duration) (bool, error) {
 	m.ctrl.T.Helper()
@@ -186,3 +569,101 @@ func (mr *MockCacheMockRecorder) SetWithExpirationIfKeyIsNotSet(ctx, key, value,
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpirationIfKeyIsNotSet", reflect.TypeOf((*MockCache)(nil).SetWithExpirationIfKeyIsNotSet), ctx, key, value, duration)
 }
+
+// SetWithExpirationIfVersionIsNotOlder mocks base method.
+func (m *MockCache) SetWithExpirationIfVersionIsNotOlder(ctx context.Context, key, value string, version int64, duration time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpirationIfVersionIsNotOlder", ctx, key, value, version, duration)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetWithExpirationIfVersionIsNotOlder indicates an expected call of SetWithExpirationIfVersionIsNotOlder.
+func (mr *MockCacheMockRecorder) SetWithExpirationIfVersionIsNotOlder(ctx, key, value, version, duration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpirationIfVersionIsNotOlder", reflect.TypeOf((*MockCache)(nil).SetWithExpirationIfVersionIsNotOlder), ctx, key, value, version, duration)
+}
+
+// MockIdempotencyStore is a mock of IdempotencyStore interface.
+type MockIdempotencyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockIdempotencyStoreMockRecorder is the mock recorder for MockIdempotencyStore.
+type MockIdempotencyStoreMockRecorder struct {
+	mock *MockIdempotencyStore
+}
+
+// NewMockIdempotencyStore creates a new mock instance.
+func NewMockIdempotencyStore(ctrl *gomock.Controller) *MockIdempotencyStore {
+	mock := &MockIdempotencyStore{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyStore) EXPECT() *MockIdempotencyStoreMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockIdempotencyStore) Delete(ctx context.Context, userID, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, userID, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockIdempotencyStoreMockRecorder) Delete(ctx, userID, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockIdempotencyStore)(nil).Delete), ctx, userID, token)
+}
+
+// Get mocks base method.
+func (m *MockIdempotencyStore) Get(ctx context.Context, userID, token string) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, userID, token)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockIdempotencyStoreMockRecorder) Get(ctx, userID, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockIdempotencyStore)(nil).Get), ctx, userID, token)
+}
+
+// SetWithExpiration mocks base method.
+func (m *MockIdempotencyStore) SetWithExpiration(ctx context.Context, userID, token, value string, duration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpiration", ctx, userID, token, value, duration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWithExpiration indicates an expected call of SetWithExpiration.
+func (mr *MockIdempotencyStoreMockRecorder) SetWithExpiration(ctx, userID, token, value, duration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockIdempotencyStore)(nil).SetWithExpiration), ctx, userID, token, value, duration)
+}
+
+// SetWithExpirationIfKeyIsNotSet mocks base method.
+func (m *MockIdempotencyStore) SetWithExpirationIfKeyIsNotSet(ctx context.Context, userID, token, value string, duration time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpirationIfKeyIsNotSet", ctx, userID, token, value, duration)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetWithExpirationIfKeyIsNotSet indicates an expected call of SetWithExpirationIfKeyIsNotSet.
+func (mr *MockIdempotencyStoreMockRecorder) SetWithExpirationIfKeyIsNotSet(ctx, userID, token, value, duration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpirationIfKeyIsNotSet", reflect.TypeOf((*MockIdempotencyStore)(nil).SetWithExpirationIfKeyIsNotSet), ctx, userID, token, value, duration)
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2/log"
@@ -22,11 +24,19 @@ func NewPostgreSQLDB(db *sqlx.DB) Database {
 	return &postgresDB{db: db}
 }
 
+// activeHoldsQuery sums the funds reserved by the active holds of a wallet. Expired holds are released as soon
+// as they expire, even before the hold expiry job marks them as expired.
+const activeHoldsQuery = `SELECT COALESCE(SUM(amount), 0) FROM holds WHERE wallet_id = %s AND status = 'active' AND expires_at > NOW()`
+
+// reversedAmountQuery sums the amount already reversed of a transaction, in the currency of the transaction. The
+// reversal of a transfer credits the original sender with its dest_amount.
+const reversedAmountQuery = `SELECT COALESCE(SUM(COALESCE(r.dest_amount, r.amount)), 0) FROM transactions r WHERE r.reversal_of = %s`
+
 func (p *postgresDB) GetWallet(ctx context.Context, walletID string) (*models.Wallet, error) {
 	dbCtx, cancel := withTimeout(ctx)
 	defer cancel()
 
-	query := `SELECT * FROM wallets WHERE id = $1 LIMIT 1;`
+	query := `SELECT w.*, w.balance - (` + fmt.Sprintf(activeHoldsQuery, "w.id") + `) AS available_balance FROM wallets w WHERE w.id = $1 LIMIT 1;`
 	var exists []*models.Wallet
 	err := p.db.SelectContext(dbCtx, &exists, query, walletID)
 	if err != nil {
@@ -35,6 +45,9 @@ func (p *postgresDB) GetWallet(ctx context.Context, walletID string) (*models.Wa
 		}
 		return nil, err // actual error
 	}
+	if len(exists) == 0 {
+		return nil, nil // wallet does not exist
+	}
 	return exists[0], err
 }
 
@@ -54,46 +67,180 @@ func (p *postgresDB) CheckWalletOwner(ctx context.Context, walletID string, user
 	return true, nil
 }
 
-func (p *postgresDB) GetWalletBalance(ctx context.Context, walletID string) (float64, error) {
+func (p *postgresDB) GetWalletBalance(ctx context.Context, walletID string) (commons.Money, error) {
 	dbCtx, cancel := withTimeout(ctx)
 	defer cancel()
 
 	query := `SELECT balance FROM wallets WHERE id = $1;`
-	var balance float64
+	var balance commons.Money
 	err := p.db.GetContext(dbCtx, &balance, query, walletID)
 	if err != nil {
-		return -1, err
+		return commons.ZeroMoney, err
 	}
 	return balance, nil
 }
 
-func (p *postgresDB) GetTransactions(ctx context.Context, walletID string, limit, offset int32) ([]*models.Transaction, error) {
+func (p *postgresDB) GetTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, limit, offset int32) ([]*models.Transaction, error) {
 	dbCtx, cancel := withTimeout(ctx)
 	defer cancel()
 
-	query := `
-		SELECT id, from_wallet_id, to_wallet_id, type, amount, created_at
-		FROM transactions
-		WHERE from_wallet_id = $1 OR to_wallet_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3;
-	`
+	if filter != nil && filter.Cursor != nil {
+		offset = 0
+	}
+	query, args := transactionsQuery(walletID, filter)
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d;", len(args)-1, len(args))
 	var transactions []*models.Transaction
-	err := p.db.SelectContext(dbCtx, &transactions, query, walletID, limit, offset)
+	err := p.db.SelectContext(dbCtx, &transactions, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	// A backward cursor scans towards the start of the history, the page is flipped back afterwards
+	if filter != nil && filter.Cursor != nil && filter.Cursor.Backward {
+		slices.Reverse(transactions)
+	}
 	return transactions, err
 }
 
-func (p *postgresDB) InsertTxnAndGetWalletBalance(ctx context.Context, fromAccount string, toAccount string, amount float64, trsType commons.TransactionType) (float64, error) {
-	// Transactional operation to perform atomic update in transaction table and wallet table
+// StreamTransactions calls fn with every transaction of the wallet matching the filter, in the order of the history,
+// reading one row at a time so that long histories are never loaded at once. It stops at the first error of fn.
+// The query is bounded by ctx only, since a stream can outlast DBOperationTimeout.
+func (p *postgresDB) StreamTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, fn func(*models.Transaction) error) error {
+	query, args := transactionsQuery(walletID, filter)
+	rows, err := p.db.QueryxContext(ctx, query+";", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txn models.Transaction
+		if err := rows.StructScan(&txn); err != nil {
+			return err
+		}
+		if err := fn(&txn); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// transactionsQuery builds the history query of the wallet without pagination. The posting of the wallet carries
+// its sequence number and balance after each transaction.
+func transactionsQuery(walletID string, filter *models.TransactionFilter) (string, []any) {
+	where, args := transactionFilterClause(walletID, filter, postgresDialect)
+	query := fmt.Sprintf(`
+		SELECT id, from_wallet_id, to_wallet_id, type, amount, currency, fee, dest_amount, dest_currency, fx_rate, fx_rate_at, quote_id, hold_id,
+			reversal_of, created_at, (%s) AS reversed_amount, wp.seq, wp.balance_after
+		FROM transactions t
+		LEFT JOIN LATERAL (
+			SELECT p.seq, p.balance_after
+			FROM postings p
+			JOIN journal_entries je ON je.id = p.entry_id
+			WHERE je.transaction_id = t.id AND p.account_id = $1
+			ORDER BY p.seq DESC
+			LIMIT 1
+		) wp ON TRUE
+		WHERE %s
+		ORDER BY %s`, fmt.Sprintf(reversedAmountQuery, "t.id"), where, transactionsOrder(filter))
+	return query, args
+}
+
+// transactionsOrder returns the ORDER BY clause of the history of a wallet, on the wallet sequence number when the
+// filter syncs after one.
+func transactionsOrder(filter *models.TransactionFilter) string {
+	if filter != nil && filter.AfterSeq != nil {
+		return "wp.seq ASC"
+	}
+	backward := filter != nil && filter.Cursor != nil && filter.Cursor.Backward
+	order := "DESC"
+	if filter != nil && filter.Ascending != backward {
+		order = "ASC"
+	}
+	return fmt.Sprintf("t.created_at %s, t.id %s", order, order)
+}
+
+// sqlDialect holds what differs between the databases sharing the transaction history filters.
+type sqlDialect struct {
+	placeholder string // prefix of the numbered parameters
+	moneyParam  string // format of a money parameter compared with a stored amount
+}
+
+var postgresDialect = sqlDialect{placeholder: "$", moneyParam: "%s"}
+
+// transactionFilterClause builds the parameterised WHERE clause selecting the transactions t of the wallet that
+// match the filter. The wallet ID is always the first argument.
+func transactionFilterClause(walletID string, filter *models.TransactionFilter, dialect sqlDialect) (string, []any) {
+	args := []any{walletID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("%s%d", dialect.placeholder, len(args))
+	}
+	money := func(value commons.Money) string {
+		return fmt.Sprintf(dialect.moneyParam, arg(value))
+	}
+	wallet := dialect.placeholder + "1"
+	conditions := []string{fmt.Sprintf("(t.from_wallet_id = %[1]s OR t.to_wallet_id = %[1]s)", wallet)}
+	if filter == nil {
+		return conditions[0], args
+	}
+
+	if len(filter.Types) > 0 {
+		placeholders := make([]string, 0, len(filter.Types))
+		for _, trsType := range filter.Types {
+			placeholders = append(placeholders, arg(trsType))
+		}
+		conditions = append(conditions, fmt.Sprintf("t.type IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "t.created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "t.created_at < "+arg(*filter.To))
+	}
+	// Incoming transfers are filtered on the amount credited to the wallet
+	walletAmount := fmt.Sprintf("(CASE WHEN t.to_wallet_id = %s THEN COALESCE(t.dest_amount, t.amount) ELSE t.amount END)", wallet)
+	if filter.MinAmount != nil {
+		conditions = append(conditions, walletAmount+" >= "+money(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, walletAmount+" <= "+money(*filter.MaxAmount))
+	}
+	if filter.CounterpartyWalletID != "" {
+		counterparty := arg(filter.CounterpartyWalletID)
+		conditions = append(conditions, fmt.Sprintf("((t.from_wallet_id = %[1]s AND t.to_wallet_id = %[2]s) OR (t.to_wallet_id = %[1]s AND t.from_wallet_id = %[2]s))", wallet, counterparty))
+	}
+	switch commons.TransactionDirection(filter.Direction) {
+	case commons.TransactionDirectionCredit:
+		conditions = append(conditions, fmt.Sprintf("(t.to_wallet_id = %s OR t.type = 'deposit')", wallet))
+	case commons.TransactionDirectionDebit:
+		conditions = append(conditions, fmt.Sprintf("(t.from_wallet_id = %s AND t.type <> 'deposit')", wallet))
+	}
+	if cursor := filter.Cursor; cursor != nil {
+		op := "<"
+		if filter.Ascending != cursor.Backward {
+			op = ">"
+		}
+		conditions = append(conditions, fmt.Sprintf("(t.created_at, t.id) %s (%s, %s)", op, arg(cursor.CreatedAt), arg(cursor.ID)))
+	}
+	if filter.AfterSeq != nil {
+		conditions = append(conditions, "wp.seq > "+arg(*filter.AfterSeq))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (p *postgresDB) InsertTxnAndGetWalletBalance(ctx context.Context, txn *models.Transaction) (balance commons.Money, err error) {
+	fromAccount, toAccount, amount, trsType := txn.WalletID, txn.ToWalletID.String, txn.Amount, commons.TransactionType(txn.Type)
+
+	// Transactional operation to perform atomic update in transaction table and wallet table. The rows that are checked
+	// are locked first, which serializes the writers, so read committed is enough. A serializable transaction would
+	// instead be aborted whenever it waited on a wallet updated by the writer before it
 	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelSerializable,
+		Isolation: sql.LevelReadCommitted,
 	})
 	if err != nil {
 		log.Errorf("error creating a transactional context: %v", err)
-		return -1, err
+		return commons.ZeroMoney, err
 	}
 
 	// Transaction roll back and logging
@@ -101,88 +248,153 @@ func (p *postgresDB) InsertTxnAndGetWalletBalance(ctx context.Context, fromAccou
 		now := time.Now()
 		if p := recover(); p != nil {
 			if err := tx.Rollback(); err != nil {
-				log.Errorf("transaction rollback failed after panic. from: %s | to: %s | amount: %f | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
+				log.Errorf("transaction rollback failed after panic. from: %s | to: %s | amount: %s | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
 			} else {
-				log.Infof("transaction rollback succeeded after panic. from: %s | to: %s | amount: %f | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
+				log.Infof("transaction rollback succeeded after panic. from: %s | to: %s | amount: %s | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
 			}
 			return // silently absorb the panic
 		}
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				log.Errorf("transaction rollback failed. from: %s | to: %s | amount: %f | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
+				log.Errorf("transaction rollback failed. from: %s | to: %s | amount: %s | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
 			} else {
-				log.Infof("transaction rollback succeeded. from: %s | to: %s | amount: %f | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
+				log.Infof("transaction rollback succeeded. from: %s | to: %s | amount: %s | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
 			}
 			return
 		}
 		if commitErr := tx.Commit(); commitErr != nil {
-			log.Errorf("transaction commit failed. from: %s | to: %s | amount: %f | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
-			err = commitErr
+			log.Errorf("transaction commit failed. from: %s | to: %s | amount: %s | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
+			balance, err = commons.ZeroMoney, commitErr
 		} else {
-			log.Infof("transaction committed successfully. from: %s | to: %s | amount: %f | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
+			log.Infof("transaction committed successfully. from: %s | to: %s | amount: %s | type: %s | time: %s", fromAccount, toAccount, amount, trsType, now)
 		}
 	}()
 
-	var balance float64
-	// Check the balance of the source wallet before a withdrawal or transfer
+	// Commit the idempotency key with the transaction so that the request cannot be executed twice
+	if txn.IdempotencyKey != nil {
+		err = p.commitIdempotencyKey(tx, ctx, txn.IdempotencyKey)
+		if err != nil {
+			log.Errorf("idempotency key error. from: %s | to: %s, amount: %s | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
+			return commons.ZeroMoney, err
+		}
+		txn.IdempotencyToken = sql.NullString{String: txn.IdempotencyKey.Token, Valid: true}
+	}
+
+	// Lock and consume the quote so that it is executed only once and only before it expires
+	if txn.QuoteID.Valid {
+		err = p.consumeQuote(tx, ctx, txn.QuoteID.String)
+		if err != nil {
+			log.Errorf("quote execution error. from: %s | to: %s, amount: %s | type: %s | quote: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, txn.QuoteID.String, time.Now(), err)
+			return commons.ZeroMoney, err
+		}
+	}
+
+	// Lock and capture the hold. Its funds are released before the available balance is checked
+	if txn.HoldID.Valid {
+		err = p.captureHold(tx, ctx, txn)
+		if err != nil {
+			log.Errorf("hold capture error. from: %s | to: %s, amount: %s | type: %s | hold: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, txn.HoldID.String, time.Now(), err)
+			return commons.ZeroMoney, err
+		}
+	}
+
+	// Lock the reversed transaction so that concurrent reversals cannot exceed its amount
+	if txn.ReversalOf.Valid {
+		err = p.checkReversal(tx, ctx, txn)
+		if err != nil {
+			log.Errorf("reversal error. from: %s | to: %s, amount: %s | type: %s | reversal of: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, txn.ReversalOf.String, time.Now(), err)
+			return commons.ZeroMoney, err
+		}
+	}
+
+	// Lock the wallets before the transaction is timestamped
+	err = p.lockWallets(tx, ctx, txn)
+	if err != nil {
+		log.Errorf("wallet lock error. from: %s | to: %s, amount: %s | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
+		return commons.ZeroMoney, err
+	}
+
+	// Check the available balance of the source wallet before a withdrawal or transfer
 	if trsType == commons.TransactionTypeWithdraw || trsType == commons.TransactionTypeTransfer {
-		query := "SELECT balance FROM wallets WHERE id = $1 FOR UPDATE"
 		readCtx, cancel := withTimeout(ctx)
 		defer cancel()
-		row := tx.QueryRowContext(readCtx, query, fromAccount)
-		err = row.Scan(&balance)
+		var available commons.Money
+		available, err = p.lockAndGetAvailableBalance(tx, readCtx, fromAccount)
 		if err != nil {
-			log.Errorf("transaction select error. from: %s | to: %s, amount: %f | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
-			return -1, err
+			log.Errorf("transaction select error. from: %s | to: %s, amount: %s | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
+			return commons.ZeroMoney, err
 		}
-		if balance < amount {
-			return -1, commons.InsufficientBalanceError
+		// The fee is charged on top of the amount
+		if available.LessThan(txn.DebitedAmount()) {
+			err = commons.InsufficientBalanceError
+			return commons.ZeroMoney, err
 		}
 	}
 
 	// Insert transaction record
-	err = p.addTransactionRecord(tx, ctx, fromAccount, toAccount, amount, trsType)
+	err = p.addTransactionRecord(tx, ctx, txn)
 	if err != nil {
-		log.Errorf("transaction insert error. from: %s | to: %s, amount: %f | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
-		return -1, err
+		log.Errorf("transaction insert error. from: %s | to: %s, amount: %s | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
+		return commons.ZeroMoney, err
 	}
 
-	senderAmount := amount
-	receiverAmount := amount
-	// If it is not a deposit, then the amount should be negative for sender
-	if trsType == commons.TransactionTypeWithdraw || trsType == commons.TransactionTypeTransfer {
-		senderAmount = -amount
-	}
-	// Update the receiver wallet if the transaction is a transfer
-	if trsType == commons.TransactionTypeTransfer {
-		err = p.updateWalletBalance(tx, ctx, toAccount, receiverAmount)
-		if err != nil {
-			log.Errorf("update receiver wallet error. from: %s | to: %s, amount: %f | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
-			return 0, err
-		}
+	// Record the transaction in the ledger. The postings update the cached wallet balances
+	err = p.addJournalEntry(tx, ctx, txn)
+	if err != nil {
+		log.Errorf("journal entry insert error. from: %s | to: %s, amount: %s | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
+		return commons.ZeroMoney, err
 	}
-	// Update sender balance and return it
-	balance, err = p.updateAndGetWalletBalance(tx, ctx, fromAccount, senderAmount)
+
+	// Return the updated sender balance with the sequence number of its posting
+	err = tx.QueryRowContext(ctx, `SELECT balance, seq FROM wallets WHERE id = $1;`, fromAccount).Scan(&balance, &txn.Seq)
 	if err != nil {
-		log.Errorf("update sender wallet error. from: %s | to: %s, amount: %f | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
-		return balance, err
+		log.Errorf("wallet balance select error. from: %s | to: %s, amount: %s | type: %s | time: %s | error: %+v", fromAccount, toAccount, amount, trsType, time.Now(), err)
+		return commons.ZeroMoney, err
 	}
+	txn.BalanceAfter = &balance
 	return balance, err
 }
 
-func (p *postgresDB) addTransactionRecord(tx *sql.Tx, ctx context.Context, fromAccount string, toAccount string, amount float64, trsType commons.TransactionType) error {
+// lockWallets locks the rows of the wallets of the transaction, in the order of their IDs so that concurrent transfers
+// between the same wallets cannot deadlock. The transaction is timestamped with the clock while the locks are held, and
+// its postings with the time of the transaction, so that the postings of a wallet are timestamped in the order of
+// their sequence numbers even though concurrent transactions may start in a different order.
+func (p *postgresDB) lockWallets(tx *sql.Tx, ctx context.Context, txn *models.Transaction) error {
+	toWalletID := txn.WalletID
+	if txn.ToWalletID.Valid {
+		toWalletID = txn.ToWalletID.String
+	}
+	_, err := tx.ExecContext(ctx, `SELECT id FROM wallets WHERE id IN ($1, $2) ORDER BY id FOR UPDATE;`, txn.WalletID, toWalletID)
+	if err != nil {
+		return fmt.Errorf("failed to lock the wallets: %w", err)
+	}
+	return nil
+}
+
+func (p *postgresDB) addTransactionRecord(tx *sql.Tx, ctx context.Context, txn *models.Transaction) error {
 	var query string
 	var args []any
+	trsType := commons.TransactionType(txn.Type)
 	if trsType == commons.TransactionTypeDeposit || trsType == commons.TransactionTypeWithdraw {
 		// If it's a deposit or withdrawal, the fromAccount is sufficient
-		query = `INSERT INTO transactions (from_wallet_id, type, amount) VALUES ($1, $2, $3);`
-		args = []any{fromAccount, trsType, amount}
+		query = `
+			INSERT INTO transactions (from_wallet_id, type, amount, currency, fee, quote_id, hold_id, reversal_of, idempotency_token, created_at)
+			VALUES ($1, $2, $3, (SELECT currency FROM wallets WHERE id = $1), $4, $5, $6, $7, $8, clock_timestamp())
+			RETURNING id, currency, dest_currency, created_at;
+		`
+		args = []any{txn.WalletID, trsType, txn.Amount, txn.Fee, txn.QuoteID, txn.HoldID, txn.ReversalOf, txn.IdempotencyToken}
 	} else {
-		query = `INSERT INTO transactions (from_wallet_id, to_wallet_id, type, amount) VALUES ($1, $2, $3, $4);`
-		args = []any{fromAccount, toAccount, trsType, amount}
+		// Transfers record the credited amount and, for cross-currency transfers, the applied rate
+		query = `
+			INSERT INTO transactions (from_wallet_id, to_wallet_id, type, amount, currency, dest_amount, dest_currency, fx_rate, fx_rate_at, fee, quote_id, hold_id, reversal_of, idempotency_token, created_at)
+			VALUES ($1, $2, $3, $4, (SELECT currency FROM wallets WHERE id = $1), $5, (SELECT currency FROM wallets WHERE id = $2), $6, $7, $8, $9, $10, $11, $12, clock_timestamp())
+			RETURNING id, currency, dest_currency, created_at;
+		`
+		args = []any{txn.WalletID, txn.ToWalletID.String, trsType, txn.Amount, txn.CreditedAmount(), txn.FXRate, txn.FXRateAt, txn.Fee, txn.QuoteID, txn.HoldID, txn.ReversalOf, txn.IdempotencyToken}
 	}
 
-	_, err := tx.ExecContext(ctx, query, args...)
+	// The stored currencies are taken from the wallets so that the ledger postings match the wallet accounts
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&txn.ID, &txn.Currency, &txn.DestCurrency, &txn.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert transaction: %w", err)
 	}
@@ -190,37 +402,204 @@ func (p *postgresDB) addTransactionRecord(tx *sql.Tx, ctx context.Context, fromA
 	return nil
 }
 
-func (p *postgresDB) updateAndGetWalletBalance(tx *sql.Tx, ctx context.Context, walletID string, amount float64) (float64, error) {
+// commitIdempotencyKey marks the idempotency key as committed, inserting it if the idempotency store has not. It
+// returns commons.DuplicateRequestError if another request has committed the key and it has not expired.
+func (p *postgresDB) commitIdempotencyKey(tx *sql.Tx, ctx context.Context, key *models.IdempotencyKey) error {
 	query := `
-		UPDATE wallets
-		SET balance = balance + $1,
-			updated_at = NOW()
-		WHERE id = $2
-		RETURNING balance;
-    `
-	var newBalance float64
-	err := tx.QueryRowContext(ctx, query, amount, walletID).Scan(&newBalance)
+		INSERT INTO idempotency_keys (user_id, token, value, committed, expires_at)
+		VALUES ($1, $2, $3, TRUE, NOW() + make_interval(secs => $4))
+		ON CONFLICT (user_id, token) DO UPDATE
+			SET value      = CASE WHEN idempotency_keys.expires_at <= NOW() THEN EXCLUDED.value ELSE idempotency_keys.value END,
+				committed  = TRUE,
+				expires_at = EXCLUDED.expires_at
+			WHERE NOT idempotency_keys.committed OR idempotency_keys.expires_at <= NOW();
+	`
+	result, err := tx.ExecContext(ctx, query, key.UserID, key.Token, key.Value, key.Retention.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to commit idempotency key: %w", err)
+	}
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return 0, fmt.Errorf("failed to update and fetch wallet balance: %w", err)
+		return fmt.Errorf("failed to commit idempotency key: %w", err)
 	}
-	return newBalance, nil
+	if rows == 0 {
+		return commons.DuplicateRequestError
+	}
+	return nil
 }
 
-func (p *postgresDB) updateWalletBalance(tx *sql.Tx, ctx context.Context, walletID string, amount float64) error {
-	query := `
-		UPDATE wallets
-		SET balance = balance + $1,
-			updated_at = NOW()
-		WHERE id = $2;
-    `
-	_, err := tx.ExecContext(ctx, query, amount, walletID)
+func (p *postgresDB) consumeQuote(tx *sql.Tx, ctx context.Context, quoteID string) error {
+	query := `SELECT executed_at IS NOT NULL, expires_at <= NOW() FROM quotes WHERE id = $1 FOR UPDATE;`
+	var executed, expired bool
+	err := tx.QueryRowContext(ctx, query, quoteID).Scan(&executed, &expired)
+	if err != nil {
+		return fmt.Errorf("failed to lock quote: %w", err)
+	}
+	if executed {
+		return commons.QuoteAlreadyExecutedError
+	}
+	if expired {
+		return commons.QuoteExpiredError
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE quotes SET executed_at = NOW() WHERE id = $1;`, quoteID)
 	if err != nil {
-		return fmt.Errorf("failed to update and fetch wallet balance: %w", err)
+		return fmt.Errorf("failed to mark quote as executed: %w", err)
 	}
 	return nil
 }
 
-func (p *postgresDB) CreateUserWallet(ctx context.Context) (*models.Wallet, error) {
+// captureHold marks the hold as captured for the transaction amount. The remainder of the hold is released.
+func (p *postgresDB) captureHold(tx *sql.Tx, ctx context.Context, txn *models.Transaction) error {
+	query := `SELECT status, expires_at <= NOW(), amount FROM holds WHERE id = $1 AND wallet_id = $2 FOR UPDATE;`
+	var status string
+	var expired bool
+	var held commons.Money
+	err := tx.QueryRowContext(ctx, query, txn.HoldID.String, txn.WalletID).Scan(&status, &expired, &held)
+	if err != nil {
+		return fmt.Errorf("failed to lock hold: %w", err)
+	}
+	if status != string(commons.HoldStatusActive) {
+		return commons.HoldNotActiveError
+	}
+	if expired {
+		return commons.HoldExpiredError
+	}
+	if txn.Amount.GreaterThan(held) {
+		return commons.HoldAmountExceededError
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE holds SET status = 'captured', captured_amount = $2, updated_at = NOW() WHERE id = $1;`,
+		txn.HoldID.String, txn.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to capture hold: %w", err)
+	}
+	return nil
+}
+
+// checkReversal verifies that the reversal does not exceed the amount of the reversed transaction left to reverse.
+func (p *postgresDB) checkReversal(tx *sql.Tx, ctx context.Context, txn *models.Transaction) error {
+	var original, reversed commons.Money
+	err := tx.QueryRowContext(ctx, `SELECT amount FROM transactions WHERE id = $1 FOR UPDATE;`, txn.ReversalOf.String).Scan(&original)
+	if err != nil {
+		return fmt.Errorf("failed to lock reversed transaction: %w", err)
+	}
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(reversedAmountQuery, "$1")+";", txn.ReversalOf.String).Scan(&reversed)
+	if err != nil {
+		return fmt.Errorf("failed to read reversed amount: %w", err)
+	}
+
+	remaining := original.Sub(reversed)
+	if !remaining.IsPositive() {
+		return commons.TransactionAlreadyReversedError
+	}
+	if txn.CreditedAmount().GreaterThan(remaining) {
+		return commons.ReversalAmountExceededError
+	}
+	return nil
+}
+
+// lockAndGetAvailableBalance locks the wallet row so that concurrent debits and holds are serialized, and returns
+// its balance minus the active holds.
+func (p *postgresDB) lockAndGetAvailableBalance(tx *sql.Tx, ctx context.Context, walletID string) (commons.Money, error) {
+	var balance, held commons.Money
+	err := tx.QueryRowContext(ctx, `SELECT balance FROM wallets WHERE id = $1 FOR UPDATE;`, walletID).Scan(&balance)
+	if err != nil {
+		return commons.ZeroMoney, err
+	}
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(activeHoldsQuery, "$1")+";", walletID).Scan(&held)
+	if err != nil {
+		return commons.ZeroMoney, err
+	}
+	return balance.Sub(held), nil
+}
+
+// addJournalEntry records the balanced postings of the transaction. System accounts are created on first use.
+func (p *postgresDB) addJournalEntry(tx *sql.Tx, ctx context.Context, txn *models.Transaction) error {
+	var entryID string
+	err := tx.QueryRowContext(ctx, `INSERT INTO journal_entries (transaction_id) VALUES ($1) RETURNING id;`, txn.ID).Scan(&entryID)
+	if err != nil {
+		return fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	for _, posting := range txn.Postings() {
+		accountID := posting.AccountID
+		if posting.AccountType != string(commons.LedgerAccountWallet) {
+			accountID, err = p.systemAccountID(tx, ctx, posting.AccountType, posting.Currency)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO postings (entry_id, account_id, amount, currency) VALUES ($1, $2, $3, $4);`,
+			entryID, accountID, posting.Amount, posting.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to insert %s posting: %w", posting.AccountType, err)
+		}
+	}
+	return nil
+}
+
+func (p *postgresDB) systemAccountID(tx *sql.Tx, ctx context.Context, accountType string, currency string) (string, error) {
+	query := `
+		WITH created AS (
+			INSERT INTO ledger_accounts (type, currency)
+			VALUES ($1, $2)
+			ON CONFLICT (type, currency) WHERE wallet_id IS NULL DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM created
+		UNION ALL
+		SELECT id FROM ledger_accounts WHERE type = $1 AND currency = $2 AND wallet_id IS NULL;
+	`
+	var accountID string
+	err := tx.QueryRowContext(ctx, query, accountType, currency).Scan(&accountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s account for %s: %w", accountType, currency, err)
+	}
+	return accountID, nil
+}
+
+// CreateWallet opens an additional wallet for an existing user. It returns nil when the user does not exist.
+func (p *postgresDB) CreateWallet(ctx context.Context, userID string, label string, currency string) (*models.Wallet, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO wallets (user_id, label, currency)
+		SELECT id, $2, $3 FROM users WHERE id = $1
+		RETURNING id, user_id, label, currency, balance, balance AS available_balance, created_at, updated_at;
+	`
+	var wallet models.Wallet
+	err := p.db.GetContext(dbCtx, &wallet, query, userID, sql.NullString{String: label, Valid: label != ""}, currency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // user does not exist
+		}
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+	return &wallet, nil
+}
+
+func (p *postgresDB) GetUserWallets(ctx context.Context, userID string) ([]*models.Wallet, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT w.id, w.user_id, w.label, w.currency, w.balance, w.created_at, w.updated_at,
+			w.balance - (` + fmt.Sprintf(activeHoldsQuery, "w.id") + `) AS available_balance
+		FROM wallets w
+		WHERE w.user_id = $1
+		ORDER BY w.created_at, w.id;
+	`
+	var wallets []*models.Wallet
+	err := p.db.SelectContext(dbCtx, &wallets, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+func (p *postgresDB) CreateUserWallet(ctx context.Context, currency string) (*models.Wallet, error) {
 	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transaction: %w", err)
@@ -245,8 +624,8 @@ func (p *postgresDB) CreateUserWallet(ctx context.Context) (*models.Wallet, erro
 	var walletID string
 	var createdAt time.Time
 
-	walletQuery := `INSERT INTO wallets (user_id) VALUES ($1) RETURNING id, created_at`
-	err = tx.QueryRowContext(ctx, walletQuery, userID).Scan(&walletID, &createdAt)
+	walletQuery := `INSERT INTO wallets (user_id, currency) VALUES ($1, $2) RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, walletQuery, userID, currency).Scan(&walletID, &createdAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create wallet: %w", err)
 	}
@@ -254,17 +633,346 @@ func (p *postgresDB) CreateUserWallet(ctx context.Context) (*models.Wallet, erro
 	wallet := &models.Wallet{
 		ID:        walletID,
 		UserID:    userID,
+		Currency:  currency,
+		Balance:   commons.ZeroMoney,
 		CreatedAt: createdAt,
 	}
 
 	return wallet, nil
 }
 
+func (p *postgresDB) CreateQuote(ctx context.Context, quote *models.Quote) (*models.Quote, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO quotes (wallet_id, to_wallet_id, type, amount, currency, fee, dest_amount, dest_currency, fx_rate, fx_rate_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW() + make_interval(secs => $11))
+		RETURNING id, expires_at, created_at;
+	`
+	created := *quote
+	err := p.db.QueryRowContext(dbCtx, query, quote.WalletID, quote.ToWalletID, quote.Type, quote.Amount, quote.Currency, quote.Fee,
+		quote.DestAmount, quote.DestCurrency, quote.FXRate, quote.FXRateAt, commons.QuoteTTL.Seconds()).Scan(&created.ID, &created.ExpiresAt, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quote: %w", err)
+	}
+	return &created, nil
+}
+
+func (p *postgresDB) GetQuote(ctx context.Context, quoteID string) (*models.Quote, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, wallet_id, to_wallet_id, type, amount, currency, fee, dest_amount, dest_currency, fx_rate, fx_rate_at, expires_at, executed_at, created_at
+		FROM quotes
+		WHERE id = $1;
+	`
+	var quote models.Quote
+	err := p.db.GetContext(dbCtx, &quote, query, quoteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // quote does not exist
+		}
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// CreateHold reserves funds of the wallet. It returns commons.InsufficientBalanceError when the available
+// balance does not cover the hold.
+func (p *postgresDB) CreateHold(ctx context.Context, hold *models.Hold, ttl time.Duration) (created *models.Hold, err error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			created, err = nil, fmt.Errorf("failed to commit hold: %w", commitErr)
+		}
+	}()
+
+	if hold.IdempotencyKey != nil {
+		if err = p.commitIdempotencyKey(tx, ctx, hold.IdempotencyKey); err != nil {
+			return nil, err
+		}
+	}
+
+	available, err := p.lockAndGetAvailableBalance(tx, ctx, hold.WalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read available balance: %w", err)
+	}
+	if available.LessThan(hold.Amount) {
+		err = commons.InsufficientBalanceError
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO holds (wallet_id, to_wallet_id, amount, currency, expires_at)
+		VALUES ($1, $2, $3, (SELECT currency FROM wallets WHERE id = $1), NOW() + make_interval(secs => $4))
+		RETURNING id, wallet_id, to_wallet_id, amount, captured_amount, currency, status, expires_at, created_at, updated_at;
+	`
+	created = &models.Hold{}
+	err = tx.QueryRowContext(ctx, query, hold.WalletID, hold.ToWalletID, hold.Amount, ttl.Seconds()).Scan(&created.ID, &created.WalletID,
+		&created.ToWalletID, &created.Amount, &created.CapturedAmount, &created.Currency, &created.Status, &created.ExpiresAt, &created.CreatedAt, &created.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+	return created, nil
+}
+
+// GetTransaction returns the transaction with the amount already reversed, or nil if it does not exist.
+func (p *postgresDB) GetTransaction(ctx context.Context, txnID string) (*models.Transaction, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, from_wallet_id, to_wallet_id, type, amount, currency, fee, dest_amount, dest_currency, fx_rate, fx_rate_at, quote_id, hold_id,
+			reversal_of, created_at, (` + fmt.Sprintf(reversedAmountQuery, "t.id") + `) AS reversed_amount
+		FROM transactions t
+		WHERE id = $1;
+	`
+	var txn models.Transaction
+	err := p.db.GetContext(dbCtx, &txn, query, txnID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // transaction does not exist
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// GetTransactionByIdempotencyToken returns the latest transaction of the wallet created by a request with the
+// idempotency token, or nil if there is none.
+func (p *postgresDB) GetTransactionByIdempotencyToken(ctx context.Context, walletID string, token string) (*models.Transaction, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, from_wallet_id, to_wallet_id, type, amount, currency, fee, dest_amount, dest_currency, fx_rate, fx_rate_at, quote_id, hold_id,
+			reversal_of, idempotency_token, created_at
+		FROM transactions
+		WHERE from_wallet_id = $1 AND idempotency_token = $2
+		ORDER BY created_at DESC
+		LIMIT 1;
+	`
+	var txn models.Transaction
+	err := p.db.GetContext(dbCtx, &txn, query, walletID, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // no transaction was created with the token
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// GetWalletPosting returns the posting of the transaction on the wallet account, carrying the wallet sequence
+// number and the balance of the wallet right after the transaction. It returns nil when the transaction did not
+// touch the wallet.
+func (p *postgresDB) GetWalletPosting(ctx context.Context, walletID string, txnID string) (*models.Posting, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT p.id, p.entry_id, je.transaction_id, p.account_id, 'wallet' AS account_type, p.amount, p.currency, p.seq,
+			p.balance_after, p.created_at
+		FROM postings p
+		JOIN journal_entries je ON je.id = p.entry_id
+		WHERE je.transaction_id = $2 AND p.account_id = $1
+		ORDER BY p.seq DESC
+		LIMIT 1;
+	`
+	var posting models.Posting
+	err := p.db.GetContext(dbCtx, &posting, query, walletID, txnID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &posting, nil
+}
+
+// GetLatestWalletPosting returns the latest posting of the wallet account created at or before asOf. Its balance
+// after is the balance of the wallet at that time. It returns nil when the wallet had no posting yet. The postings are
+// timestamped under the lock of the wallet row, see lockWallets, so created_at never decreases as seq increases even
+// for concurrent transactions.
+func (p *postgresDB) GetLatestWalletPosting(ctx context.Context, walletID string, asOf time.Time) (*models.Posting, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT p.id, p.entry_id, je.transaction_id, p.account_id, 'wallet' AS account_type, p.amount, p.currency, p.seq,
+			p.balance_after, p.created_at
+		FROM postings p
+		JOIN journal_entries je ON je.id = p.entry_id
+		WHERE p.account_id = $1 AND p.seq IS NOT NULL AND p.created_at <= $2
+		ORDER BY p.seq DESC
+		LIMIT 1;
+	`
+	var posting models.Posting
+	err := p.db.GetContext(dbCtx, &posting, query, walletID, asOf)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &posting, nil
+}
+
+func (p *postgresDB) GetHold(ctx context.Context, holdID string) (*models.Hold, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, wallet_id, to_wallet_id, amount, captured_amount, currency, status, expires_at, created_at, updated_at
+		FROM holds
+		WHERE id = $1;
+	`
+	var hold models.Hold
+	err := p.db.GetContext(dbCtx, &hold, query, holdID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // hold does not exist
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (p *postgresDB) GetHeldAmount(ctx context.Context, walletID string) (commons.Money, *time.Time, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var held commons.Money
+	var expiresAt sql.NullTime
+	query := `SELECT COALESCE(SUM(amount), 0), MIN(expires_at) FROM holds WHERE wallet_id = $1 AND status = 'active' AND expires_at > NOW();`
+	err := p.db.QueryRowContext(dbCtx, query, walletID).Scan(&held, &expiresAt)
+	if err != nil {
+		return commons.ZeroMoney, nil, err
+	}
+	if !expiresAt.Valid {
+		return held, nil, nil
+	}
+	return held, &expiresAt.Time, nil
+}
+
+// VoidHold releases an active hold. It returns commons.HoldNotActiveError or commons.HoldExpiredError when the
+// hold has already been captured, voided or has expired.
+func (p *postgresDB) VoidHold(ctx context.Context, holdID string, key *models.IdempotencyKey) (voided *models.Hold, err error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			voided, err = nil, fmt.Errorf("failed to commit hold: %w", commitErr)
+		}
+	}()
+
+	if key != nil {
+		if err = p.commitIdempotencyKey(tx, ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		UPDATE holds
+		SET status = 'voided', updated_at = NOW()
+		WHERE id = $1 AND status = 'active' AND expires_at > NOW()
+		RETURNING id, wallet_id, to_wallet_id, amount, captured_amount, currency, status, expires_at, created_at, updated_at;
+	`
+	voided = &models.Hold{}
+	err = tx.QueryRowContext(ctx, query, holdID).Scan(&voided.ID, &voided.WalletID, &voided.ToWalletID, &voided.Amount,
+		&voided.CapturedAmount, &voided.Currency, &voided.Status, &voided.ExpiresAt, &voided.CreatedAt, &voided.UpdatedAt)
+	if err == nil {
+		return voided, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to void hold: %w", err)
+	}
+
+	var active bool
+	err = tx.QueryRowContext(ctx, `SELECT status = 'active' FROM holds WHERE id = $1;`, holdID).Scan(&active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hold: %w", err)
+	}
+	if active {
+		err = commons.HoldExpiredError
+		return nil, err
+	}
+	err = commons.HoldNotActiveError
+	return nil, err
+}
+
+// ExpireHolds marks the active holds past their expiry as expired and returns how many were expired.
+func (p *postgresDB) ExpireHolds(ctx context.Context) (int64, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(dbCtx, `UPDATE holds SET status = 'expired', updated_at = NOW() WHERE status = 'active' AND expires_at <= NOW();`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (p *postgresDB) GetStatement(ctx context.Context, walletID string, periodStart time.Time, periodEnd time.Time) (*models.Statement, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT * FROM statements WHERE wallet_id = $1 AND period_start = $2 AND period_end = $3;`
+	var statement models.Statement
+	err := p.db.GetContext(dbCtx, &statement, query, walletID, periodStart, periodEnd)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // statement not generated yet
+		}
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// SaveStatement persists the statement of a closed period. When the statement was already saved, e.g. by a
+// concurrent request, the stored one is returned unchanged.
+func (p *postgresDB) SaveStatement(ctx context.Context, statement *models.Statement) (*models.Statement, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO statements (wallet_id, period_start, period_end, content)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (wallet_id, period_start, period_end) DO NOTHING
+		RETURNING *;
+	`
+	// lib/pq sends []byte as bytea, the JSON is passed as text
+	var saved models.Statement
+	err := p.db.GetContext(dbCtx, &saved, query, statement.WalletID, statement.PeriodStart, statement.PeriodEnd, string(statement.Content))
+	if errors.Is(err, sql.ErrNoRows) {
+		return p.GetStatement(ctx, statement.WalletID, statement.PeriodStart, statement.PeriodEnd)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save statement: %w", err)
+	}
+	return &saved, nil
+}
+
 func (p *postgresDB) GetWalletUsers(ctx context.Context) ([]*models.Wallet, error) {
 	dbCtx, cancel := withTimeout(ctx)
 	defer cancel()
 
-	query := `SELECT id, user_id, created_at FROM wallets;`
+	query := `SELECT id, user_id, currency, created_at FROM wallets;`
 	var walletUsers []*models.Wallet
 	err := p.db.SelectContext(dbCtx, &walletUsers, query)
 	if err != nil {
@@ -273,6 +981,89 @@ func (p *postgresDB) GetWalletUsers(ctx context.Context) ([]*models.Wallet, erro
 	return walletUsers, err
 }
 
+// FindBalanceMismatches recomputes the balance of every wallet from its transaction history and returns the wallets
+// whose cached balance differs. The check runs on a single snapshot so that concurrent transactions are not reported.
+func (p *postgresDB) FindBalanceMismatches(ctx context.Context) (walletsChecked int, mismatches []*models.WalletMismatch, err error) {
+	tx, err := p.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // read-only
+	}()
+
+	err = tx.GetContext(ctx, &walletsChecked, `SELECT COUNT(*) FROM wallets;`)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count wallets: %w", err)
+	}
+
+	query := `
+		WITH movements AS (
+			SELECT from_wallet_id AS wallet_id,
+				CASE WHEN type = 'deposit' THEN amount ELSE -(amount + fee) END AS amount
+			FROM transactions
+			UNION ALL
+			SELECT to_wallet_id, COALESCE(dest_amount, amount)
+			FROM transactions
+			WHERE type = 'transfer'
+		), history AS (
+			SELECT wallet_id, SUM(amount) AS expected_balance
+			FROM movements
+			GROUP BY wallet_id
+		)
+		SELECT w.id AS wallet_id, w.currency, w.balance,
+			COALESCE(h.expected_balance, 0) AS expected_balance,
+			w.balance - COALESCE(h.expected_balance, 0) AS drift
+		FROM wallets w
+		LEFT JOIN history h ON h.wallet_id = w.id
+		WHERE w.balance <> COALESCE(h.expected_balance, 0)
+		ORDER BY w.id;
+	`
+	err = tx.SelectContext(ctx, &mismatches, query)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to recompute wallet balances: %w", err)
+	}
+	return walletsChecked, mismatches, nil
+}
+
+func (p *postgresDB) SaveReconciliationReport(ctx context.Context, report *models.ReconciliationReport) (saved *models.ReconciliationReport, err error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			saved, err = nil, fmt.Errorf("failed to commit reconciliation report: %w", commitErr)
+		}
+	}()
+
+	created := *report
+	query := `
+		INSERT INTO reconciliation_reports (started_at, finished_at, wallets_checked, mismatches)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+	`
+	err = tx.QueryRowContext(ctx, query, report.StartedAt, report.FinishedAt, report.WalletsChecked, len(report.Mismatches)).Scan(&created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert reconciliation report: %w", err)
+	}
+
+	for _, mismatch := range report.Mismatches {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO reconciliation_mismatches (report_id, wallet_id, currency, balance, expected_balance, drift)
+			VALUES ($1, $2, $3, $4, $5, $6);
+		`, created.ID, mismatch.WalletID, mismatch.Currency, mismatch.Balance, mismatch.ExpectedBalance, mismatch.Drift)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert reconciliation mismatch: %w", err)
+		}
+	}
+	return &created, nil
+}
+
 func withTimeout(parent context.Context) (context.Context, context.CancelFunc) {
 	return context.WithTimeout(parent, commons.DBOperationTimeout)
 }
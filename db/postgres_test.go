@@ -16,6 +16,7 @@ import (
 
 	"WalletApp/commons"
 	"WalletApp/db"
+	"WalletApp/models"
 )
 
 func TestInsertTxnAndGetWalletBalance(t *testing.T) {
@@ -84,46 +85,110 @@ func TestInsertTxnAndGetWalletBalance(t *testing.T) {
 		}
 	})
 
-	t.Run("successful deposit", func(t *testing.T) {
-		// Perform the deposit and get the wallet balance.
-		balance, err := pdb.InsertTxnAndGetWalletBalance(ctx, "7dbacf5d-3099-4a66-ad3d-2fee93970017", "", 50.0, commons.TransactionTypeDeposit)
-		assert.Nil(t, err)
-		assert.Equal(t, 50.0, balance)
+	testDatabaseConformance(t, ctx, pdb)
 
-		// Verify that the transaction has been recorded in the 'transactions' table.
-		var txnCount int
-		err = sqlxDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE from_wallet_id = $1 AND type = $2", "7dbacf5d-3099-4a66-ad3d-2fee93970017", commons.TransactionTypeDeposit).Scan(&txnCount)
+	t.Run("every journal entry should sum to zero per currency", func(t *testing.T) {
+		var unbalanced int
+		err := sqlxDB.QueryRow(`
+			SELECT COUNT(*) FROM (
+				SELECT entry_id FROM postings GROUP BY entry_id, currency HAVING SUM(amount) <> 0
+			) AS unbalanced_entries`).Scan(&unbalanced)
 		assert.Nil(t, err)
-		assert.Equal(t, 1, txnCount, "Expected one deposit transaction to be recorded.")
+		assert.Equal(t, 0, unbalanced)
 
-		// Verify that the wallet balance in the 'wallets' table has been updated.
-		var walletBalance float64
-		err = sqlxDB.QueryRowContext(ctx, "SELECT balance FROM wallets WHERE id = $1", "7dbacf5d-3099-4a66-ad3d-2fee93970017").Scan(&walletBalance)
+		var drifted int
+		err = sqlxDB.QueryRow(`
+			SELECT COUNT(*) FROM wallets w
+			WHERE w.balance <> (SELECT COALESCE(SUM(p.amount), 0) FROM postings p WHERE p.account_id = w.id)`).Scan(&drifted)
 		assert.Nil(t, err)
-		assert.Equal(t, 50.0, walletBalance, "Wallet balance should reflect the deposit.")
+		assert.Equal(t, 0, drifted)
 	})
 
-	t.Run("withdraw more than balance should rollback", func(t *testing.T) {
-		_, err := sqlxDB.Exec("UPDATE wallets SET balance=150.0 WHERE id=$1", "7dbacf5d-3099-4a66-ad3d-2fee93970017")
+	t.Run("unbalanced journal entry should be rejected at commit", func(t *testing.T) {
+		tx, err := sqlxDB.Begin()
+		assert.Nil(t, err)
+		var txnID, entryID string
+		err = tx.QueryRow(`INSERT INTO transactions (from_wallet_id, type, amount, currency) VALUES ($1, 'deposit', 10, 'USD') RETURNING id`,
+			"7dbacf5d-3099-4a66-ad3d-2fee93970017").Scan(&txnID)
+		assert.Nil(t, err)
+		err = tx.QueryRow(`INSERT INTO journal_entries (transaction_id) VALUES ($1) RETURNING id`, txnID).Scan(&entryID)
 		assert.Nil(t, err)
-		_, err = pdb.InsertTxnAndGetWalletBalance(ctx, "7dbacf5d-3099-4a66-ad3d-2fee93970017", "", 1000.0, commons.TransactionTypeWithdraw)
+		_, err = tx.Exec(`INSERT INTO postings (entry_id, account_id, amount, currency) VALUES ($1, $2, 10, 'USD')`,
+			entryID, "7dbacf5d-3099-4a66-ad3d-2fee93970017")
+		assert.Nil(t, err)
+		assert.NotNil(t, tx.Commit())
+	})
+
+	t.Run("postings should be immutable", func(t *testing.T) {
+		_, err := sqlxDB.Exec(`UPDATE postings SET amount = amount + 1`)
 		assert.NotNil(t, err)
+		_, err = sqlxDB.Exec(`DELETE FROM postings`)
+		assert.NotNil(t, err)
+	})
 
-		var balance float64
-		err = sqlxDB.QueryRow(`SELECT balance FROM wallets WHERE id = $1`, "7dbacf5d-3099-4a66-ad3d-2fee93970017").Scan(&balance)
+	t.Run("reconciliation should report wallets whose balance drifted from the history", func(t *testing.T) {
+		var wallets int
+		err := sqlxDB.QueryRow(`SELECT COUNT(*) FROM wallets`).Scan(&wallets)
 		assert.Nil(t, err)
-		assert.Equal(t, 150.0, balance) // unchanged due to rollback
+
+		// Bypass the ledger to simulate a corrupted cached balance
+		_, err = sqlxDB.Exec("UPDATE wallets SET balance = balance + 5 WHERE id = $1", "2cbcd158-56d2-4d45-8113-d51adf9ef57a")
+		assert.Nil(t, err)
+		defer func() {
+			_, _ = sqlxDB.Exec("UPDATE wallets SET balance = balance - 5 WHERE id = $1", "2cbcd158-56d2-4d45-8113-d51adf9ef57a")
+		}()
+
+		walletsChecked, mismatches, err := pdb.FindBalanceMismatches(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, wallets, walletsChecked)
+		assert.Len(t, mismatches, 1)
+		assert.Equal(t, "2cbcd158-56d2-4d45-8113-d51adf9ef57a", mismatches[0].WalletID)
+		assert.True(t, commons.MustMoney("5").Equal(mismatches[0].Drift))
+
+		report, err := pdb.SaveReconciliationReport(ctx, &models.ReconciliationReport{
+			StartedAt:      time.Now(),
+			FinishedAt:     time.Now(),
+			WalletsChecked: walletsChecked,
+			Mismatches:     mismatches,
+		})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, report.ID)
+
+		var saved int
+		err = sqlxDB.QueryRow(`SELECT COUNT(*) FROM reconciliation_mismatches WHERE report_id = $1`, report.ID).Scan(&saved)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, saved)
 	})
 
-	t.Run("transfer to non-existent wallet should rollback", func(t *testing.T) {
-		_, err := sqlxDB.Exec("UPDATE wallets SET balance=150.0 WHERE id=$1", "7dbacf5d-3099-4a66-ad3d-2fee93970017")
+	t.Run("idempotency key committed by a ledger write should be kept by the store", func(t *testing.T) {
+		userID, walletID := "0a644be3-cdf9-4491-b4ba-1cd8974c0278", "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+		store := db.NewPostgreSQLIdempotencyStore(sqlxDB)
+		ok, err := store.SetWithExpirationIfKeyIsNotSet(ctx, userID, "pg-key", "in-flight", time.Minute)
 		assert.Nil(t, err)
-		_, err = pdb.InsertTxnAndGetWalletBalance(ctx, "7dbacf5d-3099-4a66-ad3d-2fee93970017", "710acea9-142e-4f72-8416-53f032134f08", 20.0, commons.TransactionTypeTransfer)
-		assert.NotNil(t, err)
+		assert.True(t, ok)
+		ok, err = store.SetWithExpirationIfKeyIsNotSet(ctx, userID, "pg-key", "in-flight", time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+
+		key := &models.IdempotencyKey{UserID: userID, Token: "pg-key", Value: "in-flight", Retention: time.Hour}
+		_, err = pdb.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit),
+			Amount: commons.MustMoney("1"), IdempotencyKey: key})
+		assert.Nil(t, err)
+		assert.Nil(t, store.SetWithExpiration(ctx, userID, "pg-key", "response", time.Hour))
+		value, found, err := store.Get(ctx, userID, "pg-key")
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "response", value)
+
+		// the key is kept even if the store releases it, and the write is not repeated
+		assert.Nil(t, store.Delete(ctx, userID, "pg-key"))
+		_, err = pdb.InsertTxnAndGetWalletBalance(ctx, &models.Transaction{WalletID: walletID, Type: string(commons.TransactionTypeDeposit),
+			Amount: commons.MustMoney("1"), IdempotencyKey: key})
+		assert.ErrorIs(t, err, commons.DuplicateRequestError)
 
-		var balance float64
-		err = sqlxDB.QueryRow(`SELECT balance FROM wallets WHERE id = '7dbacf5d-3099-4a66-ad3d-2fee93970017'`).Scan(&balance)
+		// a key not set in the store is committed by the write itself
+		_, found, err = store.Get(ctx, userID, "hold-key")
 		assert.Nil(t, err)
-		assert.Equal(t, 150.0, balance)
+		assert.True(t, found)
 	})
 }
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"WalletApp/commons"
+	"WalletApp/models"
+)
+
+type postgresRateProvider struct {
+	db *sqlx.DB
+}
+
+// NewPostgreSQLRateProvider returns a RateProvider backed by the exchange_rates table.
+func NewPostgreSQLRateProvider(db *sqlx.DB) RateProvider {
+	return &postgresRateProvider{db: db}
+}
+
+func (p *postgresRateProvider) GetRate(ctx context.Context, baseCurrency string, quoteCurrency string) (*models.ExchangeRate, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT base_currency, quote_currency, rate, updated_at
+		FROM exchange_rates
+		WHERE base_currency = $1 AND quote_currency = $2;
+	`
+	var rate models.ExchangeRate
+	err := p.db.GetContext(dbCtx, &rate, query, baseCurrency, quoteCurrency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // rate not available for the currency pair
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+type sqliteRateProvider struct {
+	db *sqlx.DB
+}
+
+// NewSQLiteRateProvider returns a RateProvider backed by the exchange_rates table of a SQLite database.
+func NewSQLiteRateProvider(db *sqlx.DB) RateProvider {
+	return &sqliteRateProvider{db: db}
+}
+
+func (s *sqliteRateProvider) GetRate(ctx context.Context, baseCurrency string, quoteCurrency string) (*models.ExchangeRate, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT base_currency, quote_currency, rate, updated_at
+		FROM exchange_rates
+		WHERE base_currency = ?1 AND quote_currency = ?2;
+	`
+	var rate models.ExchangeRate
+	err := s.db.GetContext(dbCtx, &rate, query, baseCurrency, quoteCurrency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // rate not available for the currency pair
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+type inMemoryRateProvider struct {
+	rates map[string]*models.ExchangeRate
+}
+
+// NewInMemoryRateProvider returns a RateProvider serving a fixed set of rates. Intended for tests and local development.
+func NewInMemoryRateProvider(rates []*models.ExchangeRate) RateProvider {
+	provider := &inMemoryRateProvider{rates: make(map[string]*models.ExchangeRate, len(rates))}
+	for _, rate := range rates {
+		provider.rates[rateKey(rate.BaseCurrency, rate.QuoteCurrency)] = rate
+	}
+	return provider
+}
+
+// NewFileRateProvider loads rates from a local JSON file containing a list of exchange rates, e.g.
+// [{"base_currency": "USD", "quote_currency": "EUR", "rate": "0.92", "updated_at": "2026-01-01T00:00:00Z"}]
+func NewFileRateProvider(path string) (RateProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate file: %w", err)
+	}
+	var rates []*models.ExchangeRate
+	if err := json.Unmarshal(content, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse rate file: %w", err)
+	}
+	for _, rate := range rates {
+		if !rate.Rate.IsPositive() {
+			return nil, fmt.Errorf("invalid rate for %s/%s in rate file", rate.BaseCurrency, rate.QuoteCurrency)
+		}
+	}
+	return NewInMemoryRateProvider(rates), nil
+}
+
+// SampleExchangeRates returns the sample rates seeded by migration/init.sql, served with the in-memory database.
+func SampleExchangeRates() []*models.ExchangeRate {
+	now := time.Now().UTC()
+	rates := make([]*models.ExchangeRate, 0, 6)
+	for _, pair := range []struct{ base, quote, rate string }{
+		{"USD", "EUR", "0.92"}, {"EUR", "USD", "1.087"},
+		{"USD", "GBP", "0.79"}, {"GBP", "USD", "1.265"},
+		{"USD", "JPY", "149.5"}, {"JPY", "USD", "0.00669"},
+	} {
+		rates = append(rates, &models.ExchangeRate{BaseCurrency: pair.base, QuoteCurrency: pair.quote, Rate: commons.MustRate(pair.rate), UpdatedAt: now})
+	}
+	return rates
+}
+
+func (m *inMemoryRateProvider) GetRate(_ context.Context, baseCurrency string, quoteCurrency string) (*models.ExchangeRate, error) {
+	rate, ok := m.rates[rateKey(baseCurrency, quoteCurrency)]
+	if !ok {
+		return nil, nil // rate not available for the currency pair
+	}
+	copied := *rate
+	return &copied, nil
+}
+
+func rateKey(baseCurrency, quoteCurrency string) string {
+	return baseCurrency + "/" + quoteCurrency
+}
@@ -0,0 +1,42 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"WalletApp/commons"
+	"WalletApp/db"
+)
+
+func TestFileRateProvider(t *testing.T) {
+	t.Run("should serve rates loaded from a local file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		content := `[{"base_currency": "USD", "quote_currency": "EUR", "rate": "0.92", "updated_at": "2026-09-30T12:00:00Z"}]`
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+
+		provider, err := db.NewFileRateProvider(path)
+		assert.Nil(t, err)
+
+		rate, err := provider.GetRate(context.Background(), "USD", "EUR")
+		assert.Nil(t, err)
+		assert.NotNil(t, rate)
+		assert.True(t, commons.MustRate("0.92").Equal(rate.Rate))
+
+		missing, err := provider.GetRate(context.Background(), "EUR", "USD")
+		assert.Nil(t, err)
+		assert.Nil(t, missing)
+	})
+
+	t.Run("should reject non-positive rates", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		content := `[{"base_currency": "USD", "quote_currency": "EUR", "rate": "0", "updated_at": "2026-09-30T12:00:00Z"}]`
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+
+		_, err := db.NewFileRateProvider(path)
+		assert.NotNil(t, err)
+	})
+}
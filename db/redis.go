@@ -2,11 +2,23 @@ package db
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// setIfVersionIsNotOlder keeps the version of KEYS[1] under KEYS[2] so that it survives the deletion of the value.
+var setIfVersionIsNotOlder = redis.NewScript(`
+local current = redis.call('GET', KEYS[2])
+if current and tonumber(current) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
 type redisClient struct {
 	client *redis.Client
 }
@@ -21,3 +33,53 @@ func (r *redisClient) SetWithExpirationIfKeyIsNotSet(ctx context.Context, key st
 	resp := r.client.SetNX(ctx, key, value, duration)
 	return resp.Result()
 }
+
+func (r *redisClient) SetWithExpiration(ctx context.Context, key string, value string, duration time.Duration) error {
+	return r.client.Set(ctx, key, value, duration).Err()
+}
+
+func (r *redisClient) SetWithExpirationIfVersionIsNotOlder(ctx context.Context, key string, value string, version int64, duration time.Duration) (bool, error) {
+	set, err := setIfVersionIsNotOlder.Run(ctx, r.client, []string{key, key + ":version"}, value, version, duration.Milliseconds()).Int()
+	return set == 1, err
+}
+
+func (r *redisClient) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (r *redisClient) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, key).Err()
+}
+
+func (r *redisClient) SetBits(ctx context.Context, key string, offsets []uint64) error {
+	pipe := r.client.Pipeline()
+	for _, offset := range offsets {
+		pipe.SetBit(ctx, key, int64(offset), 1)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *redisClient) AreBitsSet(ctx context.Context, key string, offsets []uint64) (bool, error) {
+	pipe := r.client.Pipeline()
+	bits := make([]*redis.IntCmd, len(offsets))
+	for i, offset := range offsets {
+		bits[i] = pipe.GetBit(ctx, key, int64(offset))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	for _, bit := range bits {
+		if bit.Val() == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,969 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+
+	"WalletApp/commons"
+	"WalletApp/models"
+)
+
+// SQLiteDriverName is the database/sql driver of the SQLite databases used with NewSQLiteDB. Its connections convert
+// the amounts, stored as integer thousandths, with the units and money SQL functions.
+const SQLiteDriverName = "sqlite3_wallet"
+
+// sqliteAmountScale is the number of decimal places of the stored amounts, those of NUMERIC(20, 3) in PostgreSQL.
+const sqliteAmountScale = 3
+
+func init() {
+	sql.Register(SQLiteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("units", sqliteUnits, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("money", sqliteMoney, true)
+		},
+	})
+}
+
+// SQLiteDataSourceName returns the data source of the SQLite database file at path. Foreign keys are enforced, readers
+// do not block the writer, and every transaction takes the write lock when it begins so that concurrent ledger writes
+// are serialized before they read any balance.
+func SQLiteDataSourceName(path string) string {
+	return fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+}
+
+// sqliteUnits is the units SQL function converting a decimal amount to integer thousandths. Like NUMERIC(20, 3), it
+// rounds half away from zero.
+func sqliteUnits(amount any) (any, error) {
+	if sqliteNull(amount) {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(fmt.Sprint(amount))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", commons.InvalidMoneyError, amount)
+	}
+	units := d.Shift(sqliteAmountScale).Round(0)
+	if units.GreaterThan(decimal.NewFromInt(math.MaxInt64)) || units.LessThan(decimal.NewFromInt(math.MinInt64)) {
+		return nil, fmt.Errorf("%w: %v is out of range", commons.InvalidMoneyError, amount)
+	}
+	return units.IntPart(), nil
+}
+
+// sqliteMoney is the money SQL function converting integer thousandths back to a decimal amount.
+func sqliteMoney(units any) (any, error) {
+	if sqliteNull(units) {
+		return nil, nil
+	}
+	value, ok := units.(int64)
+	if !ok {
+		return nil, fmt.Errorf("amount %v is not stored as integer thousandths", units)
+	}
+	return decimal.New(value, -sqliteAmountScale).StringFixed(sqliteAmountScale), nil
+}
+
+// sqliteNull reports whether the argument of a SQL function is NULL, which the driver passes as a nil byte slice.
+func sqliteNull(arg any) bool {
+	b, ok := arg.([]byte)
+	return arg == nil || ok && b == nil
+}
+
+var sqliteDialect = sqlDialect{placeholder: "?", moneyParam: "units(%s)"}
+
+// sqliteActiveHoldsQuery sums the funds reserved by the active holds of a wallet at a given time, see activeHoldsQuery.
+const sqliteActiveHoldsQuery = `SELECT COALESCE(SUM(amount), 0) FROM holds WHERE wallet_id = %s AND status = 'active' AND expires_at > %s`
+
+// sqliteReversedAmountQuery sums the amount already reversed of a transaction, see reversedAmountQuery.
+const sqliteReversedAmountQuery = `SELECT COALESCE(SUM(COALESCE(r.dest_amount, r.amount)), 0) FROM transactions r WHERE r.reversal_of = %s`
+
+const sqliteTransactionColumns = `t.id, t.from_wallet_id, t.to_wallet_id, t.type, money(t.amount) AS amount, t.currency, money(t.fee) AS fee,
+	money(t.dest_amount) AS dest_amount, t.dest_currency, t.fx_rate, t.fx_rate_at, t.quote_id, t.hold_id, t.reversal_of, t.created_at`
+
+const sqliteHoldColumns = `id, wallet_id, to_wallet_id, money(amount) AS amount, money(captured_amount) AS captured_amount, currency, status,
+	expires_at, created_at, updated_at`
+
+const sqlitePostingColumns = `p.id, p.entry_id, je.transaction_id, p.account_id, 'wallet' AS account_type, money(p.amount) AS amount, p.currency,
+	p.seq, money(p.balance_after) AS balance_after, p.created_at`
+
+// sqliteDB is a Database kept in a SQLite file for embedded single-node deployments, with the schema of
+// migration/sqlite.sql. The database must be opened with SQLiteDriverName and SQLiteDataSourceName, whose immediate
+// transactions serialize the ledger writes like the row locks of postgresDB.
+type sqliteDB struct {
+	db *sqlx.DB
+}
+
+func NewSQLiteDB(db *sqlx.DB) Database {
+	return &sqliteDB{db: db}
+}
+
+// withSQLiteTx runs fn in a transaction holding the write lock, committed if fn succeeds.
+func (s *sqliteDB) withSQLiteTx(ctx context.Context, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Errorf("transaction rollback failed: %v", rbErr)
+			}
+			return
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			err = fmt.Errorf("failed to commit transaction: %w", commitErr)
+		}
+	}()
+	return fn(tx)
+}
+
+func (s *sqliteDB) GetWallet(ctx context.Context, walletID string) (*models.Wallet, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT w.id, w.user_id, w.label, w.currency, money(w.balance) AS balance, w.seq, w.created_at, w.updated_at,
+			money(w.balance - (` + fmt.Sprintf(sqliteActiveHoldsQuery, "w.id", "?2") + `)) AS available_balance
+		FROM wallets w
+		WHERE w.id = ?1;
+	`
+	var wallet models.Wallet
+	err := s.db.GetContext(dbCtx, &wallet, query, walletID, timestampNow())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // wallet does not exist
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (s *sqliteDB) CheckWalletOwner(ctx context.Context, walletID string, userID string) (bool, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var exists int
+	err := s.db.GetContext(dbCtx, &exists, `SELECT 1 FROM wallets WHERE id = ?1 AND user_id = ?2;`, walletID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil // wallet does not belong to this user
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *sqliteDB) GetWalletBalance(ctx context.Context, walletID string) (commons.Money, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var balance commons.Money
+	err := s.db.GetContext(dbCtx, &balance, `SELECT money(balance) FROM wallets WHERE id = ?1;`, walletID)
+	if err != nil {
+		return commons.ZeroMoney, err
+	}
+	return balance, nil
+}
+
+func (s *sqliteDB) GetTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, limit, offset int32) ([]*models.Transaction, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	if filter != nil && filter.Cursor != nil {
+		offset = 0
+	}
+	query, args := sqliteTransactionsQuery(walletID, filter)
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" LIMIT ?%d OFFSET ?%d;", len(args)-1, len(args))
+	var transactions []*models.Transaction
+	err := s.db.SelectContext(dbCtx, &transactions, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	// A backward cursor scans towards the start of the history, the page is flipped back afterwards
+	if filter != nil && filter.Cursor != nil && filter.Cursor.Backward {
+		slices.Reverse(transactions)
+	}
+	return transactions, nil
+}
+
+// StreamTransactions calls fn with every transaction of the wallet matching the filter, in the order of the history,
+// reading one row at a time. It stops at the first error of fn.
+func (s *sqliteDB) StreamTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, fn func(*models.Transaction) error) error {
+	query, args := sqliteTransactionsQuery(walletID, filter)
+	rows, err := s.db.QueryxContext(ctx, query+";", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txn models.Transaction
+		if err := rows.StructScan(&txn); err != nil {
+			return err
+		}
+		if err := fn(&txn); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// sqliteTransactionsQuery builds the history query of the wallet without pagination, see transactionsQuery. The
+// timestamps of the filter are compared in UTC, as stored.
+func sqliteTransactionsQuery(walletID string, filter *models.TransactionFilter) (string, []any) {
+	if filter != nil {
+		utc := *filter
+		if filter.From != nil {
+			from := filter.From.UTC()
+			utc.From = &from
+		}
+		if filter.To != nil {
+			to := filter.To.UTC()
+			utc.To = &to
+		}
+		if filter.Cursor != nil {
+			cursor := *filter.Cursor
+			cursor.CreatedAt = cursor.CreatedAt.UTC()
+			utc.Cursor = &cursor
+		}
+		filter = &utc
+	}
+	where, args := transactionFilterClause(walletID, filter, sqliteDialect)
+	query := fmt.Sprintf(`
+		SELECT %s, money((%s)) AS reversed_amount, wp.seq, money(wp.balance_after) AS balance_after
+		FROM transactions t
+		LEFT JOIN postings wp ON wp.id = (
+			SELECT p.id
+			FROM postings p
+			JOIN journal_entries je ON je.id = p.entry_id
+			WHERE je.transaction_id = t.id AND p.account_id = ?1
+			ORDER BY p.seq DESC
+			LIMIT 1
+		)
+		WHERE %s
+		ORDER BY %s`, sqliteTransactionColumns, fmt.Sprintf(sqliteReversedAmountQuery, "t.id"), where, transactionsOrder(filter))
+	return query, args
+}
+
+func (s *sqliteDB) InsertTxnAndGetWalletBalance(ctx context.Context, txn *models.Transaction) (balance commons.Money, err error) {
+	trsType := commons.TransactionType(txn.Type)
+
+	err = s.withSQLiteTx(ctx, func(tx *sqlx.Tx) error {
+		// The immediate transaction holds the write lock of the database, which serializes the writers. Taking the time
+		// under it keeps the created_at of the postings of a wallet in the order of their seq, which
+		// GetLatestWalletPosting relies on
+		now := timestampNow()
+		// Commit the idempotency key with the transaction so that the request cannot be executed twice
+		if txn.IdempotencyKey != nil {
+			if err := s.commitIdempotencyKey(tx, ctx, txn.IdempotencyKey, now); err != nil {
+				return err
+			}
+			txn.IdempotencyToken = sql.NullString{String: txn.IdempotencyKey.Token, Valid: true}
+		}
+		if txn.QuoteID.Valid {
+			if err := s.consumeQuote(tx, ctx, txn.QuoteID.String, now); err != nil {
+				return err
+			}
+		}
+		// The captured hold releases its funds before the available balance is checked
+		if txn.HoldID.Valid {
+			if err := s.captureHold(tx, ctx, txn, now); err != nil {
+				return err
+			}
+		}
+		if txn.ReversalOf.Valid {
+			if err := s.checkReversal(tx, ctx, txn); err != nil {
+				return err
+			}
+		}
+		if trsType == commons.TransactionTypeWithdraw || trsType == commons.TransactionTypeTransfer {
+			available, _, err := s.getAvailableBalance(tx, ctx, txn.WalletID, now)
+			if err != nil {
+				return err
+			}
+			// The fee is charged on top of the amount
+			if available.LessThan(txn.DebitedAmount()) {
+				return commons.InsufficientBalanceError
+			}
+		}
+		if err := s.addTransactionRecord(tx, ctx, txn, now); err != nil {
+			return err
+		}
+		// Record the transaction in the ledger. The wallet postings update the cached wallet balances
+		if err := s.addJournalEntry(tx, ctx, txn, now); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `SELECT money(balance), seq FROM wallets WHERE id = ?1;`, txn.WalletID).Scan(&balance, &txn.Seq)
+	})
+	if err != nil {
+		log.Errorf("transaction failed. from: %s | to: %s | amount: %s | type: %s | error: %+v", txn.WalletID, txn.ToWalletID.String, txn.Amount, trsType, err)
+		return commons.ZeroMoney, err
+	}
+	txn.BalanceAfter = &balance
+	return balance, nil
+}
+
+func (s *sqliteDB) addTransactionRecord(tx *sqlx.Tx, ctx context.Context, txn *models.Transaction, now time.Time) error {
+	txnID := uuid.NewString()
+	var query string
+	var args []any
+	if commons.TransactionType(txn.Type) == commons.TransactionTypeTransfer {
+		// Transfers record the credited amount and, for cross-currency transfers, the applied rate
+		query = `
+			INSERT INTO transactions (id, from_wallet_id, to_wallet_id, type, amount, currency, dest_amount, dest_currency, fx_rate, fx_rate_at, fee,
+				quote_id, hold_id, reversal_of, idempotency_token, created_at)
+			VALUES (?1, ?2, ?3, ?4, units(?5), (SELECT currency FROM wallets WHERE id = ?2), units(?6), (SELECT currency FROM wallets WHERE id = ?3),
+				?7, ?8, units(?9), ?10, ?11, ?12, ?13, ?14)
+			RETURNING currency, dest_currency;
+		`
+		fxRateAt := txn.FXRateAt
+		fxRateAt.Time = fxRateAt.Time.UTC()
+		args = []any{txnID, txn.WalletID, txn.ToWalletID.String, txn.Type, txn.Amount, txn.CreditedAmount(), txn.FXRate, fxRateAt, txn.Fee,
+			txn.QuoteID, txn.HoldID, txn.ReversalOf, txn.IdempotencyToken, now}
+	} else {
+		query = `
+			INSERT INTO transactions (id, from_wallet_id, type, amount, currency, fee, quote_id, hold_id, reversal_of, idempotency_token, created_at)
+			VALUES (?1, ?2, ?3, units(?4), (SELECT currency FROM wallets WHERE id = ?2), units(?5), ?6, ?7, ?8, ?9, ?10)
+			RETURNING currency, dest_currency;
+		`
+		args = []any{txnID, txn.WalletID, txn.Type, txn.Amount, txn.Fee, txn.QuoteID, txn.HoldID, txn.ReversalOf, txn.IdempotencyToken, now}
+	}
+
+	// The stored currencies are taken from the wallets so that the ledger postings match the wallet accounts
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&txn.Currency, &txn.DestCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	txn.ID, txn.CreatedAt = txnID, now
+	return nil
+}
+
+// commitIdempotencyKey marks the idempotency key as committed, see postgresDB.commitIdempotencyKey.
+func (s *sqliteDB) commitIdempotencyKey(tx *sqlx.Tx, ctx context.Context, key *models.IdempotencyKey, now time.Time) error {
+	query := `
+		INSERT INTO idempotency_keys (user_id, token, value, committed, expires_at, created_at)
+		VALUES (?1, ?2, ?3, TRUE, ?4, ?5)
+		ON CONFLICT (user_id, token) DO UPDATE
+			SET value      = CASE WHEN idempotency_keys.expires_at <= ?5 THEN excluded.value ELSE idempotency_keys.value END,
+				committed  = TRUE,
+				expires_at = excluded.expires_at
+			WHERE NOT idempotency_keys.committed OR idempotency_keys.expires_at <= ?5;
+	`
+	result, err := tx.ExecContext(ctx, query, key.UserID, key.Token, key.Value, now.Add(key.Retention), now)
+	if err != nil {
+		return fmt.Errorf("failed to commit idempotency key: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to commit idempotency key: %w", err)
+	}
+	if rows == 0 {
+		return commons.DuplicateRequestError
+	}
+	return nil
+}
+
+func (s *sqliteDB) consumeQuote(tx *sqlx.Tx, ctx context.Context, quoteID string, now time.Time) error {
+	var executed, expired bool
+	err := tx.QueryRowContext(ctx, `SELECT executed_at IS NOT NULL, expires_at <= ?2 FROM quotes WHERE id = ?1;`, quoteID, now).Scan(&executed, &expired)
+	if err != nil {
+		return fmt.Errorf("failed to lock quote: %w", err)
+	}
+	if executed {
+		return commons.QuoteAlreadyExecutedError
+	}
+	if expired {
+		return commons.QuoteExpiredError
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE quotes SET executed_at = ?2 WHERE id = ?1;`, quoteID, now)
+	if err != nil {
+		return fmt.Errorf("failed to mark quote as executed: %w", err)
+	}
+	return nil
+}
+
+// captureHold marks the hold as captured for the transaction amount. The remainder of the hold is released.
+func (s *sqliteDB) captureHold(tx *sqlx.Tx, ctx context.Context, txn *models.Transaction, now time.Time) error {
+	query := `SELECT status, expires_at <= ?3, money(amount) FROM holds WHERE id = ?1 AND wallet_id = ?2;`
+	var status string
+	var expired bool
+	var held commons.Money
+	err := tx.QueryRowContext(ctx, query, txn.HoldID.String, txn.WalletID, now).Scan(&status, &expired, &held)
+	if err != nil {
+		return fmt.Errorf("failed to lock hold: %w", err)
+	}
+	if status != string(commons.HoldStatusActive) {
+		return commons.HoldNotActiveError
+	}
+	if expired {
+		return commons.HoldExpiredError
+	}
+	if txn.Amount.GreaterThan(held) {
+		return commons.HoldAmountExceededError
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE holds SET status = 'captured', captured_amount = units(?2), updated_at = ?3 WHERE id = ?1;`,
+		txn.HoldID.String, txn.Amount, now)
+	if err != nil {
+		return fmt.Errorf("failed to capture hold: %w", err)
+	}
+	return nil
+}
+
+// checkReversal verifies that the reversal does not exceed the amount of the reversed transaction left to reverse.
+func (s *sqliteDB) checkReversal(tx *sqlx.Tx, ctx context.Context, txn *models.Transaction) error {
+	var original, reversed commons.Money
+	err := tx.QueryRowContext(ctx, `SELECT money(amount) FROM transactions WHERE id = ?1;`, txn.ReversalOf.String).Scan(&original)
+	if err != nil {
+		return fmt.Errorf("failed to lock reversed transaction: %w", err)
+	}
+	err = tx.QueryRowContext(ctx, "SELECT money(("+fmt.Sprintf(sqliteReversedAmountQuery, "?1")+"));", txn.ReversalOf.String).Scan(&reversed)
+	if err != nil {
+		return fmt.Errorf("failed to read reversed amount: %w", err)
+	}
+
+	remaining := original.Sub(reversed)
+	if !remaining.IsPositive() {
+		return commons.TransactionAlreadyReversedError
+	}
+	if txn.CreditedAmount().GreaterThan(remaining) {
+		return commons.ReversalAmountExceededError
+	}
+	return nil
+}
+
+// getAvailableBalance returns the balance of the wallet minus its active holds, and the currency of the wallet. The
+// transaction already holds the write lock, so the balance cannot change until it ends.
+func (s *sqliteDB) getAvailableBalance(tx *sqlx.Tx, ctx context.Context, walletID string, now time.Time) (commons.Money, string, error) {
+	query := `SELECT money(balance - (` + fmt.Sprintf(sqliteActiveHoldsQuery, "?1", "?2") + `)), currency FROM wallets WHERE id = ?1;`
+	var available commons.Money
+	var currency string
+	err := tx.QueryRowContext(ctx, query, walletID, now).Scan(&available, &currency)
+	if err != nil {
+		return commons.ZeroMoney, "", err
+	}
+	return available, currency, nil
+}
+
+// addJournalEntry records the balanced postings of the transaction. The postings are inserted before the entry,
+// which checks that they balance. Wallet postings take the next sequence number of the wallet and the balance they
+// leave, which can never be negative.
+func (s *sqliteDB) addJournalEntry(tx *sqlx.Tx, ctx context.Context, txn *models.Transaction, now time.Time) error {
+	entryID := uuid.NewString()
+	for _, posting := range txn.Postings() {
+		if posting.AccountType != string(commons.LedgerAccountWallet) {
+			accountID, err := s.systemAccountID(tx, ctx, posting.AccountType, posting.Currency, now)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `INSERT INTO postings (entry_id, account_id, amount, currency, created_at) VALUES (?1, ?2, units(?3), ?4, ?5);`,
+				entryID, accountID, posting.Amount, posting.Currency, now)
+			if err != nil {
+				return fmt.Errorf("failed to insert %s posting: %w", posting.AccountType, err)
+			}
+			continue
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO postings (entry_id, account_id, amount, currency, seq, balance_after, created_at)
+			SELECT ?1, la.id, units(?3), ?4, w.seq + 1, w.balance + units(?3), ?5
+			FROM ledger_accounts la
+			JOIN wallets w ON w.id = la.wallet_id
+			WHERE la.id = ?2;
+		`, entryID, posting.AccountID, posting.Amount, posting.Currency, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert wallet posting: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return fmt.Errorf("failed to insert wallet posting: no ledger account for wallet %s", posting.AccountID)
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO journal_entries (id, transaction_id, created_at) VALUES (?1, ?2, ?3);`, entryID, txn.ID, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteDB) systemAccountID(tx *sqlx.Tx, ctx context.Context, accountType string, currency string, now time.Time) (string, error) {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO ledger_accounts (id, type, currency, created_at)
+		VALUES (?1, ?2, ?3, ?4)
+		ON CONFLICT (type, currency) WHERE wallet_id IS NULL DO NOTHING;
+	`, uuid.NewString(), accountType, currency, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s account for %s: %w", accountType, currency, err)
+	}
+	var accountID string
+	err = tx.GetContext(ctx, &accountID, `SELECT id FROM ledger_accounts WHERE type = ?1 AND currency = ?2 AND wallet_id IS NULL;`, accountType, currency)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s account for %s: %w", accountType, currency, err)
+	}
+	return accountID, nil
+}
+
+// CreateWallet opens an additional wallet for an existing user. It returns nil when the user does not exist.
+func (s *sqliteDB) CreateWallet(ctx context.Context, userID string, label string, currency string) (*models.Wallet, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	wallet := &models.Wallet{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Label:     sql.NullString{String: label, Valid: label != ""},
+		Currency:  currency,
+		CreatedAt: timestampNow(),
+	}
+	wallet.UpdatedAt = wallet.CreatedAt
+	query := `
+		INSERT INTO wallets (id, user_id, label, currency, created_at, updated_at)
+		SELECT ?1, id, ?3, ?4, ?5, ?5 FROM users WHERE id = ?2;
+	`
+	result, err := s.db.ExecContext(dbCtx, query, wallet.ID, userID, wallet.Label, currency, wallet.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+	if rows == 0 {
+		return nil, nil // user does not exist
+	}
+	return wallet, nil
+}
+
+func (s *sqliteDB) GetUserWallets(ctx context.Context, userID string) ([]*models.Wallet, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT w.id, w.user_id, w.label, w.currency, money(w.balance) AS balance, w.seq, w.created_at, w.updated_at,
+			money(w.balance - (` + fmt.Sprintf(sqliteActiveHoldsQuery, "w.id", "?2") + `)) AS available_balance
+		FROM wallets w
+		WHERE w.user_id = ?1
+		ORDER BY w.created_at, w.id;
+	`
+	var wallets []*models.Wallet
+	err := s.db.SelectContext(dbCtx, &wallets, query, userID, timestampNow())
+	if err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+func (s *sqliteDB) CreateUserWallet(ctx context.Context, currency string) (*models.Wallet, error) {
+	now := timestampNow()
+	wallet := &models.Wallet{ID: uuid.NewString(), UserID: uuid.NewString(), Currency: currency, Balance: commons.ZeroMoney, CreatedAt: now}
+	err := s.withSQLiteTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO users (id, created_at) VALUES (?1, ?2);`, wallet.UserID, now); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO wallets (id, user_id, currency, created_at, updated_at) VALUES (?1, ?2, ?3, ?4, ?4);`,
+			wallet.ID, wallet.UserID, currency, now)
+		if err != nil {
+			return fmt.Errorf("failed to create wallet: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+func (s *sqliteDB) CreateQuote(ctx context.Context, quote *models.Quote) (*models.Quote, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	created := *quote
+	created.ID, created.CreatedAt, created.ExecutedAt = uuid.NewString(), timestampNow(), sql.NullTime{}
+	created.ExpiresAt = created.CreatedAt.Add(commons.QuoteTTL)
+	fxRateAt := quote.FXRateAt
+	fxRateAt.Time = fxRateAt.Time.UTC()
+	query := `
+		INSERT INTO quotes (id, wallet_id, to_wallet_id, type, amount, currency, fee, dest_amount, dest_currency, fx_rate, fx_rate_at, expires_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, units(?5), ?6, units(?7), units(?8), ?9, ?10, ?11, ?12, ?13);
+	`
+	_, err := s.db.ExecContext(dbCtx, query, created.ID, quote.WalletID, quote.ToWalletID, quote.Type, quote.Amount, quote.Currency, quote.Fee,
+		quote.DestAmount, quote.DestCurrency, quote.FXRate, fxRateAt, created.ExpiresAt, created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quote: %w", err)
+	}
+	return &created, nil
+}
+
+func (s *sqliteDB) GetQuote(ctx context.Context, quoteID string) (*models.Quote, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, wallet_id, to_wallet_id, type, money(amount) AS amount, currency, money(fee) AS fee, money(dest_amount) AS dest_amount,
+			dest_currency, fx_rate, fx_rate_at, expires_at, executed_at, created_at
+		FROM quotes
+		WHERE id = ?1;
+	`
+	var quote models.Quote
+	err := s.db.GetContext(dbCtx, &quote, query, quoteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // quote does not exist
+		}
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// CreateHold reserves funds of the wallet. It returns commons.InsufficientBalanceError when the available
+// balance does not cover the hold.
+func (s *sqliteDB) CreateHold(ctx context.Context, hold *models.Hold, ttl time.Duration) (*models.Hold, error) {
+	now := timestampNow()
+	created := &models.Hold{
+		ID:             uuid.NewString(),
+		WalletID:       hold.WalletID,
+		ToWalletID:     hold.ToWalletID,
+		Amount:         hold.Amount,
+		CapturedAmount: commons.ZeroMoney,
+		Status:         string(commons.HoldStatusActive),
+		ExpiresAt:      now.Add(ttl),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	err := s.withSQLiteTx(ctx, func(tx *sqlx.Tx) error {
+		if hold.IdempotencyKey != nil {
+			if err := s.commitIdempotencyKey(tx, ctx, hold.IdempotencyKey, now); err != nil {
+				return err
+			}
+		}
+
+		available, currency, err := s.getAvailableBalance(tx, ctx, hold.WalletID, now)
+		if err != nil {
+			return fmt.Errorf("failed to read available balance: %w", err)
+		}
+		if available.LessThan(hold.Amount) {
+			return commons.InsufficientBalanceError
+		}
+		created.Currency = currency
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO holds (id, wallet_id, to_wallet_id, amount, currency, status, expires_at, created_at, updated_at)
+			VALUES (?1, ?2, ?3, units(?4), ?5, ?6, ?7, ?8, ?8);
+		`, created.ID, hold.WalletID, hold.ToWalletID, hold.Amount, currency, created.Status, created.ExpiresAt, now)
+		if err != nil {
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetTransaction returns the transaction with the amount already reversed, or nil if it does not exist.
+func (s *sqliteDB) GetTransaction(ctx context.Context, txnID string) (*models.Transaction, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sqliteTransactionColumns + `, money((` + fmt.Sprintf(sqliteReversedAmountQuery, "t.id") + `)) AS reversed_amount
+		FROM transactions t
+		WHERE t.id = ?1;`
+	var txn models.Transaction
+	err := s.db.GetContext(dbCtx, &txn, query, txnID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // transaction does not exist
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// GetTransactionByIdempotencyToken returns the latest transaction of the wallet created by a request with the
+// idempotency token, or nil if there is none.
+func (s *sqliteDB) GetTransactionByIdempotencyToken(ctx context.Context, walletID string, token string) (*models.Transaction, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sqliteTransactionColumns + `, t.idempotency_token
+		FROM transactions t
+		WHERE t.from_wallet_id = ?1 AND t.idempotency_token = ?2
+		ORDER BY t.created_at DESC
+		LIMIT 1;`
+	var txn models.Transaction
+	err := s.db.GetContext(dbCtx, &txn, query, walletID, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // no transaction was created with the token
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// GetWalletPosting returns the posting of the transaction on the wallet account, or nil when the transaction did not
+// touch the wallet.
+func (s *sqliteDB) GetWalletPosting(ctx context.Context, walletID string, txnID string) (*models.Posting, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sqlitePostingColumns + `
+		FROM postings p
+		JOIN journal_entries je ON je.id = p.entry_id
+		WHERE je.transaction_id = ?2 AND p.account_id = ?1
+		ORDER BY p.seq DESC
+		LIMIT 1;`
+	var posting models.Posting
+	err := s.db.GetContext(dbCtx, &posting, query, walletID, txnID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &posting, nil
+}
+
+// GetLatestWalletPosting returns the latest posting of the wallet account created at or before asOf, or nil when the
+// wallet had no posting yet. Ordering by seq is correct only because the writers are serialized by the write lock and
+// timestamp their postings under it, so created_at never decreases as seq increases.
+func (s *sqliteDB) GetLatestWalletPosting(ctx context.Context, walletID string, asOf time.Time) (*models.Posting, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sqlitePostingColumns + `
+		FROM postings p
+		JOIN journal_entries je ON je.id = p.entry_id
+		WHERE p.account_id = ?1 AND p.seq IS NOT NULL AND p.created_at <= ?2
+		ORDER BY p.seq DESC
+		LIMIT 1;`
+	var posting models.Posting
+	err := s.db.GetContext(dbCtx, &posting, query, walletID, asOf.UTC())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &posting, nil
+}
+
+func (s *sqliteDB) GetHold(ctx context.Context, holdID string) (*models.Hold, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var hold models.Hold
+	err := s.db.GetContext(dbCtx, &hold, `SELECT `+sqliteHoldColumns+` FROM holds WHERE id = ?1;`, holdID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // hold does not exist
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (s *sqliteDB) GetHeldAmount(ctx context.Context, walletID string) (commons.Money, *time.Time, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	now := timestampNow()
+	var held commons.Money
+	query := `SELECT money((` + fmt.Sprintf(sqliteActiveHoldsQuery, "?1", "?2") + `));`
+	if err := s.db.GetContext(dbCtx, &held, query, walletID, now); err != nil {
+		return commons.ZeroMoney, nil, err
+	}
+	// Read as a column rather than an aggregate so that it is decoded as a timestamp
+	var expiresAt time.Time
+	query = `SELECT expires_at FROM holds WHERE wallet_id = ?1 AND status = 'active' AND expires_at > ?2 ORDER BY expires_at LIMIT 1;`
+	err := s.db.GetContext(dbCtx, &expiresAt, query, walletID, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return held, nil, nil
+	}
+	if err != nil {
+		return commons.ZeroMoney, nil, err
+	}
+	return held, &expiresAt, nil
+}
+
+// VoidHold releases an active hold. It returns commons.HoldNotActiveError or commons.HoldExpiredError when the
+// hold has already been captured, voided or has expired.
+func (s *sqliteDB) VoidHold(ctx context.Context, holdID string, key *models.IdempotencyKey) (*models.Hold, error) {
+	now := timestampNow()
+	var hold models.Hold
+	err := s.withSQLiteTx(ctx, func(tx *sqlx.Tx) error {
+		if key != nil {
+			if err := s.commitIdempotencyKey(tx, ctx, key, now); err != nil {
+				return err
+			}
+		}
+
+		var status string
+		var expired bool
+		err := tx.QueryRowContext(ctx, `SELECT status, expires_at <= ?2 FROM holds WHERE id = ?1;`, holdID, now).Scan(&status, &expired)
+		if err != nil {
+			return fmt.Errorf("failed to read hold: %w", err)
+		}
+		if status != string(commons.HoldStatusActive) {
+			return commons.HoldNotActiveError
+		}
+		if expired {
+			return commons.HoldExpiredError
+		}
+
+		if _, err = tx.ExecContext(ctx, `UPDATE holds SET status = 'voided', updated_at = ?2 WHERE id = ?1;`, holdID, now); err != nil {
+			return fmt.Errorf("failed to void hold: %w", err)
+		}
+		return tx.GetContext(ctx, &hold, `SELECT `+sqliteHoldColumns+` FROM holds WHERE id = ?1;`, holdID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ExpireHolds marks the active holds past their expiry as expired and returns how many were expired.
+func (s *sqliteDB) ExpireHolds(ctx context.Context) (int64, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(dbCtx, `UPDATE holds SET status = 'expired', updated_at = ?1 WHERE status = 'active' AND expires_at <= ?1;`, timestampNow())
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *sqliteDB) GetStatement(ctx context.Context, walletID string, periodStart time.Time, periodEnd time.Time) (*models.Statement, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT * FROM statements WHERE wallet_id = ?1 AND period_start = ?2 AND period_end = ?3;`
+	var statement models.Statement
+	err := s.db.GetContext(dbCtx, &statement, query, walletID, periodStart.UTC(), periodEnd.UTC())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // statement not generated yet
+		}
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// SaveStatement persists the statement of a closed period. When the statement was already saved, e.g. by a
+// concurrent request, the stored one is returned unchanged.
+func (s *sqliteDB) SaveStatement(ctx context.Context, statement *models.Statement) (*models.Statement, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO statements (id, wallet_id, period_start, period_end, content, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		ON CONFLICT (wallet_id, period_start, period_end) DO NOTHING;
+	`
+	_, err := s.db.ExecContext(dbCtx, query, uuid.NewString(), statement.WalletID, statement.PeriodStart.UTC(), statement.PeriodEnd.UTC(),
+		string(statement.Content), timestampNow())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save statement: %w", err)
+	}
+	return s.GetStatement(ctx, statement.WalletID, statement.PeriodStart, statement.PeriodEnd)
+}
+
+func (s *sqliteDB) GetWalletUsers(ctx context.Context) ([]*models.Wallet, error) {
+	dbCtx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var walletUsers []*models.Wallet
+	err := s.db.SelectContext(dbCtx, &walletUsers, `SELECT id, user_id, currency, created_at FROM wallets;`)
+	if err != nil {
+		return nil, err
+	}
+	return walletUsers, nil
+}
+
+// FindBalanceMismatches recomputes the balance of every wallet from its transaction history and returns the wallets
+// whose cached balance differs. The check runs in a single transaction so that concurrent transactions are not reported.
+func (s *sqliteDB) FindBalanceMismatches(ctx context.Context) (walletsChecked int, mismatches []*models.WalletMismatch, err error) {
+	err = s.withSQLiteTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &walletsChecked, `SELECT COUNT(*) FROM wallets;`); err != nil {
+			return fmt.Errorf("failed to count wallets: %w", err)
+		}
+
+		query := `
+			WITH movements AS (
+				SELECT from_wallet_id AS wallet_id,
+					CASE WHEN type = 'deposit' THEN amount ELSE -(amount + fee) END AS amount
+				FROM transactions
+				UNION ALL
+				SELECT to_wallet_id, COALESCE(dest_amount, amount)
+				FROM transactions
+				WHERE type = 'transfer'
+			), history AS (
+				SELECT wallet_id, SUM(amount) AS expected_balance
+				FROM movements
+				GROUP BY wallet_id
+			)
+			SELECT w.id AS wallet_id, w.currency, money(w.balance) AS balance,
+				money(COALESCE(h.expected_balance, 0)) AS expected_balance,
+				money(w.balance - COALESCE(h.expected_balance, 0)) AS drift
+			FROM wallets w
+			LEFT JOIN history h ON h.wallet_id = w.id
+			WHERE w.balance <> COALESCE(h.expected_balance, 0)
+			ORDER BY w.id;
+		`
+		if err := tx.SelectContext(ctx, &mismatches, query); err != nil {
+			return fmt.Errorf("failed to recompute wallet balances: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return walletsChecked, mismatches, nil
+}
+
+func (s *sqliteDB) SaveReconciliationReport(ctx context.Context, report *models.ReconciliationReport) (*models.ReconciliationReport, error) {
+	created := *report
+	created.ID = uuid.NewString()
+	err := s.withSQLiteTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO reconciliation_reports (id, started_at, finished_at, wallets_checked, mismatches)
+			VALUES (?1, ?2, ?3, ?4, ?5);
+		`, created.ID, report.StartedAt.UTC(), report.FinishedAt.UTC(), report.WalletsChecked, len(report.Mismatches))
+		if err != nil {
+			return fmt.Errorf("failed to insert reconciliation report: %w", err)
+		}
+
+		for _, mismatch := range report.Mismatches {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO reconciliation_mismatches (report_id, wallet_id, currency, balance, expected_balance, drift)
+				VALUES (?1, ?2, ?3, units(?4), units(?5), units(?6));
+			`, created.ID, mismatch.WalletID, mismatch.Currency, mismatch.Balance, mismatch.ExpectedBalance, mismatch.Drift)
+			if err != nil {
+				return fmt.Errorf("failed to insert reconciliation mismatch: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
@@ -0,0 +1,122 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"WalletApp/commons"
+	"WalletApp/db"
+	"WalletApp/migration"
+	"WalletApp/models"
+)
+
+func TestSQLiteDB(t *testing.T) {
+	ctx := context.Background()
+
+	sqlxDB, err := sqlx.Connect(db.SQLiteDriverName, db.SQLiteDataSourceName(filepath.Join(t.TempDir(), "wallet.db")))
+	if err != nil {
+		t.Fatalf("error while opening the sqlite database: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlxDB.Close() })
+
+	// The schema is applied on every start
+	for i := 0; i < 2; i++ {
+		if _, err = sqlxDB.Exec(migration.SQLiteSchema); err != nil {
+			t.Fatalf("error while executing the schema: %v", err)
+		}
+	}
+
+	sdb := db.NewSQLiteDB(sqlxDB)
+
+	testDatabaseConformance(t, ctx, sdb)
+
+	t.Run("every journal entry should sum to zero per currency", func(t *testing.T) {
+		var unbalanced int
+		err := sqlxDB.QueryRow(`
+			SELECT COUNT(*) FROM (
+				SELECT entry_id FROM postings GROUP BY entry_id, currency HAVING SUM(amount) <> 0
+			) AS unbalanced_entries`).Scan(&unbalanced)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, unbalanced)
+
+		var drifted int
+		err = sqlxDB.QueryRow(`
+			SELECT COUNT(*) FROM wallets w
+			WHERE w.balance <> (SELECT COALESCE(SUM(p.amount), 0) FROM postings p WHERE p.account_id = w.id)`).Scan(&drifted)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, drifted)
+	})
+
+	t.Run("unbalanced journal entry should be rejected", func(t *testing.T) {
+		tx, err := sqlxDB.Begin()
+		assert.Nil(t, err)
+		defer func() { _ = tx.Rollback() }()
+		_, err = tx.Exec(`INSERT INTO transactions (id, from_wallet_id, type, amount, currency, created_at) VALUES ('unbalanced', ?1, 'deposit', 10000, 'USD', ?2)`,
+			"7dbacf5d-3099-4a66-ad3d-2fee93970017", time.Now().UTC())
+		assert.Nil(t, err)
+		_, err = tx.Exec(`INSERT INTO postings (entry_id, account_id, amount, currency, seq, balance_after, created_at)
+			SELECT 'unbalanced', id, 10000, 'USD', seq + 1, balance + 10000, ?2 FROM wallets WHERE id = ?1`,
+			"7dbacf5d-3099-4a66-ad3d-2fee93970017", time.Now().UTC())
+		assert.Nil(t, err)
+		_, err = tx.Exec(`INSERT INTO journal_entries (id, transaction_id, created_at) VALUES ('unbalanced', 'unbalanced', ?1)`, time.Now().UTC())
+		assert.NotNil(t, err)
+	})
+
+	t.Run("wallet balance should never be negative", func(t *testing.T) {
+		_, err := sqlxDB.Exec(`UPDATE wallets SET balance = -1 WHERE id = ?1`, "2cbcd158-56d2-4d45-8113-d51adf9ef57a")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("postings should be immutable", func(t *testing.T) {
+		_, err := sqlxDB.Exec(`UPDATE postings SET amount = amount + 1`)
+		assert.NotNil(t, err)
+		_, err = sqlxDB.Exec(`DELETE FROM postings`)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("reconciliation should report wallets whose balance drifted from the history", func(t *testing.T) {
+		var wallets int
+		err := sqlxDB.QueryRow(`SELECT COUNT(*) FROM wallets`).Scan(&wallets)
+		assert.Nil(t, err)
+
+		// Bypass the ledger to simulate a corrupted cached balance
+		_, err = sqlxDB.Exec("UPDATE wallets SET balance = balance + 5000 WHERE id = ?1", "2cbcd158-56d2-4d45-8113-d51adf9ef57a")
+		assert.Nil(t, err)
+		defer func() {
+			_, _ = sqlxDB.Exec("UPDATE wallets SET balance = balance - 5000 WHERE id = ?1", "2cbcd158-56d2-4d45-8113-d51adf9ef57a")
+		}()
+
+		walletsChecked, mismatches, err := sdb.FindBalanceMismatches(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, wallets, walletsChecked)
+		assert.Len(t, mismatches, 1)
+		assert.Equal(t, "2cbcd158-56d2-4d45-8113-d51adf9ef57a", mismatches[0].WalletID)
+		assert.True(t, commons.MustMoney("5").Equal(mismatches[0].Drift))
+
+		report, err := sdb.SaveReconciliationReport(ctx, &models.ReconciliationReport{
+			StartedAt:      time.Now(),
+			FinishedAt:     time.Now(),
+			WalletsChecked: walletsChecked,
+			Mismatches:     mismatches,
+		})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, report.ID)
+
+		var drift int64
+		err = sqlxDB.QueryRow(`SELECT drift FROM reconciliation_mismatches WHERE report_id = ?1`, report.ID).Scan(&drift)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5000), drift)
+	})
+
+	t.Run("exchange rates should be read from the database", func(t *testing.T) {
+		rate, err := db.NewSQLiteRateProvider(sqlxDB).GetRate(ctx, "USD", "EUR")
+		assert.Nil(t, err)
+		assert.NotNil(t, rate)
+		assert.Equal(t, "0.92", rate.Rate.String())
+	})
+}
@@ -12,16 +12,73 @@ import (
 type Database interface {
 	// Wallet Operations
 	GetWallet(ctx context.Context, walletID string) (*models.Wallet, error)
-	GetWalletBalance(ctx context.Context, walletID string) (float64, error)
-	GetTransactions(ctx context.Context, walletID string, limit, offset int32) ([]*models.Transaction, error)
-	InsertTxnAndGetWalletBalance(ctx context.Context, fromAccount string, toAccount string, amount float64, trsType commons.TransactionType) (balance float64, error error)
+	GetWalletBalance(ctx context.Context, walletID string) (commons.Money, error)
+	GetTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, limit, offset int32) ([]*models.Transaction, error)
+	StreamTransactions(ctx context.Context, walletID string, filter *models.TransactionFilter, fn func(*models.Transaction) error) error
+	GetTransaction(ctx context.Context, txnID string) (*models.Transaction, error)
+	GetTransactionByIdempotencyToken(ctx context.Context, walletID string, token string) (*models.Transaction, error)
+	GetWalletPosting(ctx context.Context, walletID string, txnID string) (*models.Posting, error)
+	GetLatestWalletPosting(ctx context.Context, walletID string, asOf time.Time) (*models.Posting, error)
+	// InsertTxnAndGetWalletBalance records the transaction and returns the balance it leaves on the source wallet. The
+	// Seq and BalanceAfter of txn are set from the posting of the source wallet.
+	InsertTxnAndGetWalletBalance(ctx context.Context, txn *models.Transaction) (balance commons.Money, error error)
 	CheckWalletOwner(ctx context.Context, walletID string, userID string) (bool, error)
+	CreateWallet(ctx context.Context, userID string, label string, currency string) (*models.Wallet, error)
+	GetUserWallets(ctx context.Context, userID string) ([]*models.Wallet, error)
+
+	// Quote operations
+	CreateQuote(ctx context.Context, quote *models.Quote) (*models.Quote, error)
+	GetQuote(ctx context.Context, quoteID string) (*models.Quote, error)
+
+	// Hold operations
+	CreateHold(ctx context.Context, hold *models.Hold, ttl time.Duration) (*models.Hold, error)
+	GetHold(ctx context.Context, holdID string) (*models.Hold, error)
+	// GetHeldAmount returns the funds reserved by the active holds of the wallet that have not expired, and the expiry
+	// of the earliest of them, when the held amount next changes on its own. The expiry is nil without active holds.
+	GetHeldAmount(ctx context.Context, walletID string) (commons.Money, *time.Time, error)
+	// VoidHold releases an active hold, committing the idempotency key with it when key is not nil.
+	VoidHold(ctx context.Context, holdID string, key *models.IdempotencyKey) (*models.Hold, error)
+	ExpireHolds(ctx context.Context) (int64, error)
+
+	// Statement operations
+	GetStatement(ctx context.Context, walletID string, periodStart time.Time, periodEnd time.Time) (*models.Statement, error)
+	SaveStatement(ctx context.Context, statement *models.Statement) (*models.Statement, error)
+
+	// Reconciliation operations
+	FindBalanceMismatches(ctx context.Context) (walletsChecked int, mismatches []*models.WalletMismatch, err error)
+	SaveReconciliationReport(ctx context.Context, report *models.ReconciliationReport) (*models.ReconciliationReport, error)
 
 	// User management operations
-	CreateUserWallet(ctx context.Context) (*models.Wallet, error)
+	CreateUserWallet(ctx context.Context, currency string) (*models.Wallet, error)
 	GetWalletUsers(ctx context.Context) ([]*models.Wallet, error)
 }
 
+// RateProvider supplies exchange rates used for cross-currency transfers.
+// GetRate returns nil when no rate is available for the currency pair.
+type RateProvider interface {
+	GetRate(ctx context.Context, baseCurrency string, quoteCurrency string) (*models.ExchangeRate, error)
+}
+
 type Cache interface {
 	SetWithExpirationIfKeyIsNotSet(ctx context.Context, key string, value string, duration time.Duration) (bool, error)
+	SetWithExpiration(ctx context.Context, key string, value string, duration time.Duration) error
+	// SetWithExpirationIfVersionIsNotOlder sets the key unless it was set with a newer version. It returns false if the
+	// value is rejected. The version is kept after the key is deleted, until it expires.
+	SetWithExpirationIfVersionIsNotOlder(ctx context.Context, key string, value string, version int64, duration time.Duration) (bool, error)
+	// Get returns false if the key is not set.
+	Get(ctx context.Context, key string) (string, bool, error)
+	Delete(ctx context.Context, key string) error
+	// SetBits sets the bits at the offsets of the bitmap kept under the key, creating it if needed.
+	SetBits(ctx context.Context, key string, offsets []uint64) error
+	// AreBitsSet reports whether all bits at the offsets of the bitmap kept under the key are set.
+	AreBitsSet(ctx context.Context, key string, offsets []uint64) (bool, error)
+}
+
+// IdempotencyStore keeps the idempotency keys of the users with the request state stored under them. It follows the
+// Cache contract with the key made of the user and the token. Get returns false if the key is not set or expired.
+type IdempotencyStore interface {
+	SetWithExpirationIfKeyIsNotSet(ctx context.Context, userID string, token string, value string, duration time.Duration) (bool, error)
+	SetWithExpiration(ctx context.Context, userID string, token string, value string, duration time.Duration) error
+	Get(ctx context.Context, userID string, token string) (string, bool, error)
+	Delete(ctx context.Context, userID string, token string) error
 }
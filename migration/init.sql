@@ -10,21 +10,291 @@ CREATE TABLE wallets
 (
     id         UUID PRIMARY KEY        DEFAULT gen_random_uuid(),
     user_id    UUID           NOT NULL REFERENCES users (id),
-    balance    NUMERIC(20, 2) NOT NULL DEFAULT 0.00,
+    label      VARCHAR(64),                           -- optional name given by the owner, e.g. "Savings"
+    currency   CHAR(3)        NOT NULL DEFAULT 'USD', -- ISO 4217 currency code
+    balance    NUMERIC(20, 3) NOT NULL DEFAULT 0.00,
+    seq        BIGINT         NOT NULL DEFAULT 0,     -- sequence number of the latest posting on the wallet
     created_at TIMESTAMP      NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP      NOT NULL DEFAULT NOW()
 );
 
+-- A user can own several wallets, listed through GET /wallet/v1/
+CREATE INDEX idx_wallets_user_id ON wallets (user_id);
+
+-- Priced outcome of a prospective withdrawal or transfer with a locked rate and fee
+CREATE TABLE quotes
+(
+    id            UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    wallet_id     UUID            NOT NULL REFERENCES wallets (id),
+    to_wallet_id  UUID REFERENCES wallets (id), -- NULL unless it's a transfer
+    type          VARCHAR(20)     NOT NULL CHECK (type IN ('withdrawal', 'transfer')),
+    amount        NUMERIC(20, 3)  NOT NULL CHECK (amount > 0),
+    currency      CHAR(3)         NOT NULL,
+    fee           NUMERIC(20, 3)  NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    dest_amount   NUMERIC(20, 3) CHECK (dest_amount > 0),
+    dest_currency CHAR(3),
+    fx_rate       NUMERIC(20, 10) CHECK (fx_rate > 0),
+    fx_rate_at    TIMESTAMP,
+    expires_at    TIMESTAMP       NOT NULL,
+    executed_at   TIMESTAMP,
+    created_at    TIMESTAMP       NOT NULL DEFAULT NOW()
+);
+
+-- Funds reserved on a wallet. Active holds that have not expired reduce the available balance
+CREATE TABLE holds
+(
+    id              UUID PRIMARY KEY        DEFAULT gen_random_uuid(),
+    wallet_id       UUID           NOT NULL REFERENCES wallets (id),
+    to_wallet_id    UUID REFERENCES wallets (id), -- captured as a transfer to this wallet, otherwise as a withdrawal
+    amount          NUMERIC(20, 3) NOT NULL CHECK (amount > 0),
+    captured_amount NUMERIC(20, 3) NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    currency        CHAR(3)        NOT NULL,
+    status          VARCHAR(20)    NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    expires_at      TIMESTAMP      NOT NULL,
+    created_at      TIMESTAMP      NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP      NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_holds_active ON holds (wallet_id, expires_at) WHERE status = 'active';
+
 CREATE TABLE transactions
 (
     id             UUID PRIMARY KEY        DEFAULT gen_random_uuid(),
     from_wallet_id UUID           NOT NULL REFERENCES wallets (id),
     to_wallet_id   UUID REFERENCES wallets (id), -- NULL unless it's a transfer
     type           VARCHAR(20)    NOT NULL CHECK (type IN ('deposit', 'withdrawal', 'transfer')),
-    amount         NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    amount         NUMERIC(20, 3) NOT NULL CHECK (amount > 0), -- amount debited from / credited to from_wallet_id
+    currency       CHAR(3)        NOT NULL DEFAULT 'USD',
+    dest_amount    NUMERIC(20, 3) CHECK (dest_amount > 0),     -- amount credited to to_wallet_id on transfers
+    dest_currency  CHAR(3),
+    fx_rate        NUMERIC(20, 10) CHECK (fx_rate > 0),        -- set only for cross-currency transfers
+    fx_rate_at     TIMESTAMP,
+    fee            NUMERIC(20, 3) NOT NULL DEFAULT 0 CHECK (fee >= 0), -- charged to from_wallet_id on top of the amount
+    quote_id       UUID UNIQUE REFERENCES quotes (id),                -- a quote can be executed only once
+    hold_id        UUID UNIQUE REFERENCES holds (id),                 -- a hold can be captured only once
+    reversal_of    UUID REFERENCES transactions (id),                 -- set on the compensating transaction of a reversal
+    idempotency_token TEXT,                                           -- token of the request that created the transaction
     created_at     TIMESTAMP      NOT NULL DEFAULT NOW()
 );
 
+-- The history of a wallet is the union of its outgoing and incoming transactions, paged on (created_at, id)
+CREATE INDEX idx_transactions_from_wallet_created_at_id ON transactions (from_wallet_id, created_at DESC, id DESC);
+CREATE INDEX idx_transactions_to_wallet_created_at_id ON transactions (to_wallet_id, created_at DESC, id DESC) WHERE to_wallet_id IS NOT NULL;
+CREATE INDEX idx_transactions_reversal_of ON transactions (reversal_of) WHERE reversal_of IS NOT NULL;
+CREATE INDEX idx_transactions_from_wallet_idempotency_token ON transactions (from_wallet_id, idempotency_token) WHERE idempotency_token IS NOT NULL;
+
+-- Double-entry ledger. Every transaction is recorded as a journal entry whose postings sum to zero per currency.
+-- Each wallet has a wallet account sharing its ID. System accounts record money entering (cash_in) or leaving
+-- (cash_out) the platform, collected fees and currency conversions (fx), once per currency.
+CREATE TABLE ledger_accounts
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    type       VARCHAR(20) NOT NULL CHECK (type IN ('wallet', 'cash_in', 'cash_out', 'fees', 'fx')),
+    wallet_id  UUID UNIQUE REFERENCES wallets (id), -- set only for wallet accounts
+    currency   CHAR(3)     NOT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT NOW(),
+    UNIQUE (id, currency),
+    CHECK ((type = 'wallet') = (wallet_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX idx_ledger_accounts_system ON ledger_accounts (type, currency) WHERE wallet_id IS NULL;
+
+CREATE TABLE journal_entries
+(
+    id             UUID PRIMARY KEY   DEFAULT gen_random_uuid(),
+    transaction_id UUID      NOT NULL UNIQUE REFERENCES transactions (id),
+    created_at     TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE postings
+(
+    id         BIGSERIAL PRIMARY KEY,
+    entry_id   UUID           NOT NULL REFERENCES journal_entries (id),
+    account_id UUID           NOT NULL,
+    amount        NUMERIC(20, 3) NOT NULL CHECK (amount <> 0), -- positive credits the account, negative debits it
+    currency      CHAR(3)        NOT NULL,
+    seq           BIGINT,                                      -- per-wallet sequence number, NULL on system accounts
+    balance_after NUMERIC(20, 3),                              -- wallet balance after the posting, NULL on system accounts
+    created_at    TIMESTAMP      NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (account_id, currency) REFERENCES ledger_accounts (id, currency)
+);
+
+CREATE INDEX idx_postings_entry_id ON postings (entry_id);
+CREATE INDEX idx_postings_account_id ON postings (account_id);
+CREATE UNIQUE INDEX idx_postings_account_seq ON postings (account_id, seq) WHERE seq IS NOT NULL;
+-- Point-in-time balances read the balance left by the latest wallet posting before a given time
+CREATE INDEX idx_postings_account_created_at ON postings (account_id, created_at) WHERE seq IS NOT NULL;
+
+-- Every wallet gets its ledger account when it is created
+CREATE
+OR REPLACE FUNCTION create_wallet_ledger_account()
+RETURNS TRIGGER AS $$
+BEGIN
+INSERT INTO ledger_accounts(id, type, wallet_id, currency)
+VALUES (NEW.id, 'wallet', NEW.id, NEW.currency);
+RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER wallet_ledger_account
+    AFTER INSERT
+    ON wallets
+    FOR EACH ROW
+    EXECUTE FUNCTION create_wallet_ledger_account();
+
+-- wallets.balance is a cached value derived from the postings of the wallet account. The update locks the wallet
+-- row, so every wallet posting gets the next sequence number of the wallet and the balance it leaves. The posting is
+-- timestamped like its transaction, which the ledger writes timestamp with the clock once they hold the locks of all
+-- the wallets of the transaction, rather than with its start. created_at then never decreases as seq increases, so the
+-- balance as of a time is the one after the latest posting by seq, and a statement lists the transactions of the
+-- postings after its opening balance
+CREATE
+OR REPLACE FUNCTION apply_posting_to_wallet_balance()
+RETURNS TRIGGER AS $$
+BEGIN
+UPDATE wallets
+SET balance    = balance + NEW.amount,
+    seq        = seq + 1,
+    updated_at = NOW()
+WHERE id = (SELECT wallet_id FROM ledger_accounts WHERE id = NEW.account_id)
+RETURNING seq, balance INTO NEW.seq, NEW.balance_after;
+NEW.created_at := COALESCE((SELECT t.created_at
+                            FROM journal_entries je
+                            JOIN transactions t ON t.id = je.transaction_id
+                            WHERE je.id = NEW.entry_id), clock_timestamp());
+RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER posting_wallet_balance
+    BEFORE INSERT
+    ON postings
+    FOR EACH ROW
+    EXECUTE FUNCTION apply_posting_to_wallet_balance();
+
+-- The ledger is append-only. Mistakes are corrected with new journal entries
+CREATE
+OR REPLACE FUNCTION prevent_posting_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'Postings cannot be updated or deleted';
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER postings_immutable
+    BEFORE UPDATE OR DELETE
+    ON postings
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_posting_changes();
+
+-- Checked at commit time so that all postings of an entry can be inserted first
+CREATE
+OR REPLACE FUNCTION check_journal_entry_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    entry UUID;
+BEGIN
+    IF
+TG_TABLE_NAME = 'journal_entries' THEN
+        entry := NEW.id;
+ELSE
+        entry := NEW.entry_id;
+END IF;
+    IF
+(SELECT COUNT(*) FROM postings WHERE entry_id = entry) < 2 THEN
+        RAISE EXCEPTION 'Journal entry % must have at least two postings', entry;
+END IF;
+    IF
+EXISTS (SELECT 1 FROM postings WHERE entry_id = entry GROUP BY currency HAVING SUM(amount) <> 0) THEN
+        RAISE EXCEPTION 'Journal entry % does not sum to zero', entry;
+END IF;
+RETURN NULL;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER journal_entry_balanced
+    AFTER INSERT
+    ON journal_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_journal_entry_balanced();
+
+CREATE CONSTRAINT TRIGGER posting_entry_balanced
+    AFTER INSERT
+    ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Outcome of the balance reconciliation job. Only written when RECONCILIATION_REPORTS is enabled
+CREATE TABLE reconciliation_reports
+(
+    id              UUID PRIMARY KEY   DEFAULT gen_random_uuid(),
+    started_at      TIMESTAMP NOT NULL,
+    finished_at     TIMESTAMP NOT NULL,
+    wallets_checked INTEGER   NOT NULL,
+    mismatches      INTEGER   NOT NULL
+);
+
+CREATE TABLE reconciliation_mismatches
+(
+    report_id        UUID           NOT NULL REFERENCES reconciliation_reports (id),
+    wallet_id        UUID           NOT NULL REFERENCES wallets (id),
+    currency         CHAR(3)        NOT NULL,
+    balance          NUMERIC(20, 3) NOT NULL, -- cached wallets.balance
+    expected_balance NUMERIC(20, 3) NOT NULL, -- recomputed from the transaction history
+    drift            NUMERIC(20, 3) NOT NULL, -- balance - expected_balance
+    PRIMARY KEY (report_id, wallet_id)
+);
+
+-- Statements of closed periods, stored as rendered so that they never change
+CREATE TABLE statements
+(
+    id           UUID PRIMARY KEY   DEFAULT gen_random_uuid(),
+    wallet_id    UUID      NOT NULL REFERENCES wallets (id),
+    period_start TIMESTAMP NOT NULL,
+    period_end   TIMESTAMP NOT NULL, -- exclusive
+    content      JSONB     NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (wallet_id, period_start, period_end)
+);
+
+-- Idempotency keys of the users with the state of the request that used them. The ledger write of a request
+-- commits its key in the same transaction, so a committed key cannot be used again until it expires.
+CREATE TABLE idempotency_keys
+(
+    user_id    UUID      NOT NULL REFERENCES users (id),
+    token      TEXT      NOT NULL,
+    value      TEXT      NOT NULL,               -- request fingerprint and, once known, its response
+    committed  BOOLEAN   NOT NULL DEFAULT FALSE, -- set by the ledger write of the request
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, token)
+);
+
+-- Exchange rates used for cross-currency transfers. 1 base_currency = rate quote_currency
+CREATE TABLE exchange_rates
+(
+    base_currency  CHAR(3)         NOT NULL,
+    quote_currency CHAR(3)         NOT NULL,
+    rate           NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    updated_at     TIMESTAMP       NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (base_currency, quote_currency)
+);
+
+-- ADD SAMPLE EXCHANGE RATES
+INSERT INTO exchange_rates(base_currency, quote_currency, rate)
+VALUES ('USD', 'EUR', 0.9200000000),
+       ('EUR', 'USD', 1.0870000000),
+       ('USD', 'GBP', 0.7900000000),
+       ('GBP', 'USD', 1.2650000000),
+       ('USD', 'JPY', 149.5000000000),
+       ('JPY', 'USD', 0.0066900000);
+
 -- ADD SAMPLE USERS
 INSERT INTO users(id)
 VALUES ('0a644be3-cdf9-4491-b4ba-1cd8974c0278');
@@ -0,0 +1,8 @@
+// Package migration holds the database schemas. init.sql is applied by the PostgreSQL container, sqlite.sql is
+// embedded in the binary and applied whenever a SQLite database is opened.
+package migration
+
+import _ "embed"
+
+//go:embed sqlite.sql
+var SQLiteSchema string
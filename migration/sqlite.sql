@@ -0,0 +1,274 @@
+-- SQLite schema equivalent to init.sql for embedded single-node deployments. It is applied on every start, so every
+-- statement is idempotent.
+--
+-- Differences with init.sql:
+--   * amounts are stored as INTEGER thousandths, the scale of NUMERIC(20, 3), so that the ledger arithmetic of the
+--     triggers stays exact. The units() and money() functions registered by the driver convert them from and to
+--     decimal text. Exchange rates are never computed on and are stored as decimal text.
+--   * IDs and timestamps are set by the application. Timestamps are stored as UTC text, which sorts chronologically.
+--   * SQLite has no deferred triggers. The postings of a journal entry are inserted before the entry, whose foreign
+--     key is checked at commit, and the entry is checked when it is inserted. No posting can be added afterwards.
+
+CREATE TABLE IF NOT EXISTS users
+(
+    id         TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS wallets
+(
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT      NOT NULL REFERENCES users (id),
+    label      TEXT CHECK (length(label) <= 64),      -- optional name given by the owner, e.g. "Savings"
+    currency   TEXT      NOT NULL DEFAULT 'USD',      -- ISO 4217 currency code
+    balance    INTEGER   NOT NULL DEFAULT 0,
+    seq        INTEGER   NOT NULL DEFAULT 0,          -- sequence number of the latest posting on the wallet
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallets_user_id ON wallets (user_id);
+
+-- Priced outcome of a prospective withdrawal or transfer with a locked rate and fee
+CREATE TABLE IF NOT EXISTS quotes
+(
+    id            TEXT PRIMARY KEY,
+    wallet_id     TEXT      NOT NULL REFERENCES wallets (id),
+    to_wallet_id  TEXT REFERENCES wallets (id), -- NULL unless it's a transfer
+    type          TEXT      NOT NULL CHECK (type IN ('withdrawal', 'transfer')),
+    amount        INTEGER   NOT NULL CHECK (amount > 0),
+    currency      TEXT      NOT NULL,
+    fee           INTEGER   NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    dest_amount   INTEGER CHECK (dest_amount > 0),
+    dest_currency TEXT,
+    fx_rate       TEXT CHECK (CAST(fx_rate AS REAL) > 0),
+    fx_rate_at    TIMESTAMP,
+    expires_at    TIMESTAMP NOT NULL,
+    executed_at   TIMESTAMP,
+    created_at    TIMESTAMP NOT NULL
+);
+
+-- Funds reserved on a wallet. Active holds that have not expired reduce the available balance
+CREATE TABLE IF NOT EXISTS holds
+(
+    id              TEXT PRIMARY KEY,
+    wallet_id       TEXT      NOT NULL REFERENCES wallets (id),
+    to_wallet_id    TEXT REFERENCES wallets (id), -- captured as a transfer to this wallet, otherwise as a withdrawal
+    amount          INTEGER   NOT NULL CHECK (amount > 0),
+    captured_amount INTEGER   NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    currency        TEXT      NOT NULL,
+    status          TEXT      NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    expires_at      TIMESTAMP NOT NULL,
+    created_at      TIMESTAMP NOT NULL,
+    updated_at      TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_holds_active ON holds (wallet_id, expires_at) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS transactions
+(
+    id                TEXT PRIMARY KEY,
+    from_wallet_id    TEXT      NOT NULL REFERENCES wallets (id),
+    to_wallet_id      TEXT REFERENCES wallets (id), -- NULL unless it's a transfer
+    type              TEXT      NOT NULL CHECK (type IN ('deposit', 'withdrawal', 'transfer')),
+    amount            INTEGER   NOT NULL CHECK (amount > 0), -- amount debited from / credited to from_wallet_id
+    currency          TEXT      NOT NULL DEFAULT 'USD',
+    dest_amount       INTEGER CHECK (dest_amount > 0),       -- amount credited to to_wallet_id on transfers
+    dest_currency     TEXT,
+    fx_rate           TEXT CHECK (CAST(fx_rate AS REAL) > 0), -- set only for cross-currency transfers
+    fx_rate_at        TIMESTAMP,
+    fee               INTEGER   NOT NULL DEFAULT 0 CHECK (fee >= 0), -- charged to from_wallet_id on top of the amount
+    quote_id          TEXT UNIQUE REFERENCES quotes (id),            -- a quote can be executed only once
+    hold_id           TEXT UNIQUE REFERENCES holds (id),             -- a hold can be captured only once
+    reversal_of       TEXT REFERENCES transactions (id),             -- set on the compensating transaction of a reversal
+    idempotency_token TEXT,                                          -- token of the request that created the transaction
+    created_at        TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_from_wallet_created_at_id ON transactions (from_wallet_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_to_wallet_created_at_id ON transactions (to_wallet_id, created_at DESC, id DESC) WHERE to_wallet_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_reversal_of ON transactions (reversal_of) WHERE reversal_of IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_from_wallet_idempotency_token ON transactions (from_wallet_id, idempotency_token) WHERE idempotency_token IS NOT NULL;
+
+-- Double-entry ledger, see init.sql
+CREATE TABLE IF NOT EXISTS ledger_accounts
+(
+    id         TEXT PRIMARY KEY,
+    type       TEXT      NOT NULL CHECK (type IN ('wallet', 'cash_in', 'cash_out', 'fees', 'fx')),
+    wallet_id  TEXT UNIQUE REFERENCES wallets (id), -- set only for wallet accounts
+    currency   TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    UNIQUE (id, currency),
+    CHECK ((type = 'wallet') = (wallet_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_system ON ledger_accounts (type, currency) WHERE wallet_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS journal_entries
+(
+    id             TEXT PRIMARY KEY,
+    transaction_id TEXT      NOT NULL UNIQUE REFERENCES transactions (id),
+    created_at     TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS postings
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id      TEXT      NOT NULL REFERENCES journal_entries (id) DEFERRABLE INITIALLY DEFERRED,
+    account_id    TEXT      NOT NULL,
+    amount        INTEGER   NOT NULL CHECK (amount <> 0), -- positive credits the account, negative debits it
+    currency      TEXT      NOT NULL,
+    seq           INTEGER,                                -- per-wallet sequence number, NULL on system accounts
+    balance_after INTEGER,                                -- wallet balance after the posting, NULL on system accounts
+    created_at    TIMESTAMP NOT NULL,
+    FOREIGN KEY (account_id, currency) REFERENCES ledger_accounts (id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_postings_entry_id ON postings (entry_id);
+CREATE INDEX IF NOT EXISTS idx_postings_account_id ON postings (account_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_postings_account_seq ON postings (account_id, seq) WHERE seq IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_postings_account_created_at ON postings (account_id, created_at) WHERE seq IS NOT NULL;
+
+-- Every wallet gets its ledger account when it is created
+CREATE TRIGGER IF NOT EXISTS wallet_ledger_account
+    AFTER INSERT
+    ON wallets
+BEGIN
+    INSERT INTO ledger_accounts(id, type, wallet_id, currency, created_at)
+    VALUES (NEW.id, 'wallet', NEW.id, NEW.currency, NEW.created_at);
+END;
+
+-- wallets.balance is a cached value derived from the postings of the wallet account. SQLite triggers cannot set the
+-- inserted row, so a wallet posting must carry the next sequence number of the wallet and the balance it leaves
+CREATE TRIGGER IF NOT EXISTS posting_wallet_sequence
+    BEFORE INSERT
+    ON postings
+    WHEN NEW.seq IS NOT (SELECT w.seq + 1 FROM wallets w JOIN ledger_accounts la ON la.wallet_id = w.id WHERE la.id = NEW.account_id)
+        OR NEW.balance_after IS NOT (SELECT w.balance + NEW.amount FROM wallets w JOIN ledger_accounts la ON la.wallet_id = w.id WHERE la.id = NEW.account_id)
+BEGIN
+    SELECT RAISE(ABORT, 'Wallet postings must carry the next sequence number and balance of the wallet');
+END;
+
+CREATE TRIGGER IF NOT EXISTS posting_wallet_balance
+    AFTER INSERT
+    ON postings
+BEGIN
+    UPDATE wallets
+    SET balance    = balance + NEW.amount,
+        seq        = seq + 1,
+        updated_at = NEW.created_at
+    WHERE id = (SELECT wallet_id FROM ledger_accounts WHERE id = NEW.account_id);
+END;
+
+-- The ledger is append-only. Mistakes are corrected with new journal entries
+CREATE TRIGGER IF NOT EXISTS postings_immutable_update
+    BEFORE UPDATE
+    ON postings
+BEGIN
+    SELECT RAISE(ABORT, 'Postings cannot be updated or deleted');
+END;
+
+CREATE TRIGGER IF NOT EXISTS postings_immutable_delete
+    BEFORE DELETE
+    ON postings
+BEGIN
+    SELECT RAISE(ABORT, 'Postings cannot be updated or deleted');
+END;
+
+CREATE TRIGGER IF NOT EXISTS journal_entry_balanced
+    AFTER INSERT
+    ON journal_entries
+    WHEN (SELECT COUNT(*) FROM postings WHERE entry_id = NEW.id) < 2
+        OR EXISTS (SELECT 1 FROM postings WHERE entry_id = NEW.id GROUP BY currency HAVING SUM(amount) <> 0)
+BEGIN
+    SELECT RAISE(ABORT, 'Journal entry must have at least two postings summing to zero per currency');
+END;
+
+CREATE TRIGGER IF NOT EXISTS posting_entry_open
+    BEFORE INSERT
+    ON postings
+    WHEN EXISTS (SELECT 1 FROM journal_entries WHERE id = NEW.entry_id)
+BEGIN
+    SELECT RAISE(ABORT, 'Postings cannot be added to a recorded journal entry');
+END;
+
+-- Outcome of the balance reconciliation job. Only written when RECONCILIATION_REPORTS is enabled
+CREATE TABLE IF NOT EXISTS reconciliation_reports
+(
+    id              TEXT PRIMARY KEY,
+    started_at      TIMESTAMP NOT NULL,
+    finished_at     TIMESTAMP NOT NULL,
+    wallets_checked INTEGER   NOT NULL,
+    mismatches      INTEGER   NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_mismatches
+(
+    report_id        TEXT    NOT NULL REFERENCES reconciliation_reports (id),
+    wallet_id        TEXT    NOT NULL REFERENCES wallets (id),
+    currency         TEXT    NOT NULL,
+    balance          INTEGER NOT NULL, -- cached wallets.balance
+    expected_balance INTEGER NOT NULL, -- recomputed from the transaction history
+    drift            INTEGER NOT NULL, -- balance - expected_balance
+    PRIMARY KEY (report_id, wallet_id)
+);
+
+-- Statements of closed periods, stored as rendered so that they never change
+CREATE TABLE IF NOT EXISTS statements
+(
+    id           TEXT PRIMARY KEY,
+    wallet_id    TEXT      NOT NULL REFERENCES wallets (id),
+    period_start TIMESTAMP NOT NULL,
+    period_end   TIMESTAMP NOT NULL, -- exclusive
+    content      TEXT      NOT NULL CHECK (json_valid(content)),
+    created_at   TIMESTAMP NOT NULL,
+    UNIQUE (wallet_id, period_start, period_end)
+);
+
+-- Idempotency keys of the users committed by the ledger writes of their requests, see init.sql
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    user_id    TEXT      NOT NULL REFERENCES users (id),
+    token      TEXT      NOT NULL,
+    value      TEXT      NOT NULL,
+    committed  BOOLEAN   NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (user_id, token)
+);
+
+-- Exchange rates used for cross-currency transfers. 1 base_currency = rate quote_currency
+CREATE TABLE IF NOT EXISTS exchange_rates
+(
+    base_currency  TEXT      NOT NULL,
+    quote_currency TEXT      NOT NULL,
+    rate           TEXT      NOT NULL CHECK (CAST(rate AS REAL) > 0),
+    updated_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (base_currency, quote_currency)
+);
+
+-- ADD SAMPLE EXCHANGE RATES
+INSERT OR IGNORE INTO exchange_rates(base_currency, quote_currency, rate)
+VALUES ('USD', 'EUR', '0.9200000000'),
+       ('EUR', 'USD', '1.0870000000'),
+       ('USD', 'GBP', '0.7900000000'),
+       ('GBP', 'USD', '1.2650000000'),
+       ('USD', 'JPY', '149.5000000000'),
+       ('JPY', 'USD', '0.0066900000');
+
+-- ADD SAMPLE USERS
+INSERT OR IGNORE INTO users(id)
+VALUES ('0a644be3-cdf9-4491-b4ba-1cd8974c0278'),
+       ('abe1f04a-68df-4e13-bd0d-5365ca9fdb0e');
+-- ADD SAMPLE WALLETS
+INSERT OR IGNORE INTO wallets(id, user_id)
+VALUES ('7dbacf5d-3099-4a66-ad3d-2fee93970017', '0a644be3-cdf9-4491-b4ba-1cd8974c0278'),
+       ('2cbcd158-56d2-4d45-8113-d51adf9ef57a', 'abe1f04a-68df-4e13-bd0d-5365ca9fdb0e');
+
+CREATE TRIGGER IF NOT EXISTS balance_check
+    BEFORE UPDATE
+    ON wallets
+    WHEN NEW.balance < 0
+BEGIN
+    SELECT RAISE(ABORT, 'Balance cannot be negative');
+END;
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+
+	"WalletApp/commons"
+)
+
+type ExchangeRate struct {
+	BaseCurrency  string       `db:"base_currency" json:"base_currency"`
+	QuoteCurrency string       `db:"quote_currency" json:"quote_currency"`
+	Rate          commons.Rate `db:"rate" json:"rate"`
+	UpdatedAt     time.Time    `db:"updated_at" json:"updated_at"`
+}
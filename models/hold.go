@@ -0,0 +1,38 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"WalletApp/commons"
+)
+
+// Hold reserves funds of a wallet without debiting them. Capturing the hold records a withdrawal, or a transfer
+// when a recipient wallet is set, for up to the held amount and releases the remainder.
+type Hold struct {
+	ID             string         `db:"id"`
+	WalletID       string         `db:"wallet_id"`
+	ToWalletID     sql.NullString `db:"to_wallet_id"` // captured as a transfer to this wallet when set
+	Amount         commons.Money  `db:"amount"`
+	CapturedAmount commons.Money  `db:"captured_amount"`
+	Currency       string         `db:"currency"`
+	Status         string         `db:"status"` // active, captured, voided, expired
+	ExpiresAt      time.Time      `db:"expires_at"`
+	CreatedAt      time.Time      `db:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at"`
+
+	IdempotencyKey *IdempotencyKey `db:"-"` // committed with the hold when set
+}
+
+// IsActive reports whether the hold still reserves funds at the given time.
+func (h *Hold) IsActive(now time.Time) bool {
+	return h.Status == string(commons.HoldStatusActive) && now.Before(h.ExpiresAt)
+}
+
+// TransactionType returns the type of the transaction recorded when the hold is captured.
+func (h *Hold) TransactionType() commons.TransactionType {
+	if h.ToWalletID.Valid {
+		return commons.TransactionTypeTransfer
+	}
+	return commons.TransactionTypeWithdraw
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// IdempotencyKey is committed by the write of the request that uses it, in the same SQL transaction, so that the
+// request cannot be executed again with the key until it expires, whatever the idempotency store in front of it.
+type IdempotencyKey struct {
+	UserID    string
+	Token     string
+	Value     string        // stored under the key if the store has not set it
+	Retention time.Duration // how long the key is kept once committed
+}
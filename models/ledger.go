@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"WalletApp/commons"
+)
+
+// LedgerAccount is an account of the double-entry ledger. Wallet accounts share the ID of their wallet,
+// system accounts exist once per type and currency.
+type LedgerAccount struct {
+	ID        string         `db:"id"`
+	Type      string         `db:"type"` // wallet, cash_in, cash_out, fees, fx
+	WalletID  sql.NullString `db:"wallet_id"`
+	Currency  string         `db:"currency"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+// JournalEntry groups the postings recorded for a single transaction.
+type JournalEntry struct {
+	ID            string    `db:"id"`
+	TransactionID string    `db:"transaction_id"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// Posting is a single line of a journal entry. A positive amount credits the account and increases its balance,
+// a negative amount debits it. The postings of a journal entry sum to zero in every currency.
+//
+// Postings of wallet accounts are numbered per wallet and record the wallet balance they leave. Both are assigned by
+// the database under the wallet row lock.
+type Posting struct {
+	ID            int64          `db:"id"`
+	EntryID       string         `db:"entry_id"`
+	TransactionID string         `db:"transaction_id"` // read through the journal entry
+	AccountID     string         `db:"account_id"`     // wallet ID for wallet accounts, resolved by the database for system accounts
+	AccountType   string         `db:"account_type"`
+	Amount        commons.Money  `db:"amount"`
+	Currency      string         `db:"currency"`
+	Seq           sql.NullInt64  `db:"seq"`
+	BalanceAfter  *commons.Money `db:"balance_after"`
+	CreatedAt     time.Time      `db:"created_at"`
+}
+
+// Postings returns the balanced postings that record the transaction in the ledger.
+//
+//   - deposit: the wallet is credited and the cash-in account is debited.
+//   - withdrawal: the wallet is debited with the amount and fee, the cash-out and fee accounts are credited.
+//     A withdrawal reversing a deposit credits the cash-in account instead.
+//   - transfer: the sender is debited with the amount and fee, the recipient is credited and the fee account
+//     collects the fee. Cross-currency transfers go through the FX account of each currency so that every
+//     currency balances on its own.
+func (t *Transaction) Postings() []*Posting {
+	var postings []*Posting
+	switch commons.TransactionType(t.Type) {
+	case commons.TransactionTypeDeposit:
+		postings = append(postings,
+			walletPosting(t.WalletID, t.Amount, t.Currency),
+			systemPosting(commons.LedgerAccountCashIn, t.Amount.Neg(), t.Currency),
+		)
+	case commons.TransactionTypeWithdraw:
+		account := commons.LedgerAccountCashOut
+		if t.ReversalOf.Valid {
+			account = commons.LedgerAccountCashIn
+		}
+		postings = append(postings,
+			walletPosting(t.WalletID, t.DebitedAmount().Neg(), t.Currency),
+			systemPosting(account, t.Amount, t.Currency),
+		)
+	case commons.TransactionTypeTransfer:
+		postings = append(postings, walletPosting(t.WalletID, t.DebitedAmount().Neg(), t.Currency))
+		destCurrency := t.Currency
+		if t.DestCurrency.Valid {
+			destCurrency = t.DestCurrency.String
+		}
+		if destCurrency != t.Currency {
+			postings = append(postings,
+				systemPosting(commons.LedgerAccountFX, t.Amount, t.Currency),
+				systemPosting(commons.LedgerAccountFX, t.CreditedAmount().Neg(), destCurrency),
+			)
+		}
+		postings = append(postings, walletPosting(t.ToWalletID.String, t.CreditedAmount(), destCurrency))
+	}
+	if t.Fee.IsPositive() {
+		postings = append(postings, systemPosting(commons.LedgerAccountFees, t.Fee, t.Currency))
+	}
+	return postings
+}
+
+func walletPosting(walletID string, amount commons.Money, currency string) *Posting {
+	return &Posting{AccountID: walletID, AccountType: string(commons.LedgerAccountWallet), Amount: amount, Currency: currency}
+}
+
+func systemPosting(accountType commons.LedgerAccountType, amount commons.Money, currency string) *Posting {
+	return &Posting{AccountType: string(accountType), Amount: amount, Currency: currency}
+}
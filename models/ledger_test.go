@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"WalletApp/commons"
+)
+
+func TestTransaction_Postings(t *testing.T) {
+	destAmount := commons.MustMoney("92")
+
+	tests := []struct {
+		name     string
+		txn      *Transaction
+		expected map[string]commons.Money // keyed by account type and currency, or wallet ID for wallet accounts
+	}{
+		{
+			name: "deposit credits the wallet from the cash-in account",
+			txn:  &Transaction{WalletID: "w1", Type: "deposit", Amount: commons.MustMoney("100"), Currency: "USD"},
+			expected: map[string]commons.Money{
+				"w1":          commons.MustMoney("100"),
+				"cash_in/USD": commons.MustMoney("-100"),
+			},
+		},
+		{
+			name: "withdrawal debits the wallet with the fee",
+			txn:  &Transaction{WalletID: "w1", Type: "withdrawal", Amount: commons.MustMoney("100"), Fee: commons.MustMoney("1.5"), Currency: "USD"},
+			expected: map[string]commons.Money{
+				"w1":           commons.MustMoney("-101.5"),
+				"cash_out/USD": commons.MustMoney("100"),
+				"fees/USD":     commons.MustMoney("1.5"),
+			},
+		},
+		{
+			name: "deposit reversal returns the money through the cash-in account",
+			txn: &Transaction{WalletID: "w1", Type: "withdrawal", Amount: commons.MustMoney("40"), Currency: "USD",
+				ReversalOf: sql.NullString{String: "t1", Valid: true}},
+			expected: map[string]commons.Money{
+				"w1":          commons.MustMoney("-40"),
+				"cash_in/USD": commons.MustMoney("40"),
+			},
+		},
+		{
+			name: "same-currency transfer moves the amount between wallets",
+			txn: &Transaction{WalletID: "w1", ToWalletID: sql.NullString{String: "w2", Valid: true}, Type: "transfer",
+				Amount: commons.MustMoney("20"), Currency: "USD", DestCurrency: sql.NullString{String: "USD", Valid: true}},
+			expected: map[string]commons.Money{
+				"w1": commons.MustMoney("-20"),
+				"w2": commons.MustMoney("20"),
+			},
+		},
+		{
+			name: "cross-currency transfer goes through the fx accounts",
+			txn: &Transaction{WalletID: "w1", ToWalletID: sql.NullString{String: "w2", Valid: true}, Type: "transfer",
+				Amount: commons.MustMoney("100"), Fee: commons.MustMoney("0.5"), Currency: "USD",
+				DestAmount: &destAmount, DestCurrency: sql.NullString{String: "EUR", Valid: true}},
+			expected: map[string]commons.Money{
+				"w1":       commons.MustMoney("-100.5"),
+				"fx/USD":   commons.MustMoney("100"),
+				"fx/EUR":   commons.MustMoney("-92"),
+				"w2":       commons.MustMoney("92"),
+				"fees/USD": commons.MustMoney("0.5"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postings := tt.txn.Postings()
+			assert.Len(t, postings, len(tt.expected))
+
+			sums := map[string]commons.Money{}
+			for _, posting := range postings {
+				key := posting.AccountID
+				if posting.AccountType != string(commons.LedgerAccountWallet) {
+					key = posting.AccountType + "/" + posting.Currency
+				}
+				expected, ok := tt.expected[key]
+				assert.True(t, ok, "unexpected posting to %s", key)
+				assert.True(t, expected.Equal(posting.Amount), "posting to %s: expected %s, got %s", key, expected, posting.Amount)
+				sums[posting.Currency] = sums[posting.Currency].Add(posting.Amount)
+			}
+			for currency, sum := range sums {
+				assert.True(t, sum.IsZero(), "postings in %s do not sum to zero", currency)
+			}
+		})
+	}
+}
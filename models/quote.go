@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"WalletApp/commons"
+)
+
+// Quote is the priced outcome of a prospective transfer or withdrawal. Executing a quote moves
+// exactly the quoted amounts, provided it has not expired or been executed already.
+type Quote struct {
+	ID           string         `db:"id"`
+	WalletID     string         `db:"wallet_id"`
+	ToWalletID   sql.NullString `db:"to_wallet_id"`
+	Type         string         `db:"type"` // withdrawal, transfer
+	Amount       commons.Money  `db:"amount"`
+	Currency     string         `db:"currency"`
+	Fee          commons.Money  `db:"fee"`
+	DestAmount   *commons.Money `db:"dest_amount"`
+	DestCurrency sql.NullString `db:"dest_currency"`
+	FXRate       *commons.Rate  `db:"fx_rate"`
+	FXRateAt     sql.NullTime   `db:"fx_rate_at"`
+	ExpiresAt    time.Time      `db:"expires_at"`
+	ExecutedAt   sql.NullTime   `db:"executed_at"`
+	CreatedAt    time.Time      `db:"created_at"`
+}
+
+// Transaction returns the transaction that executes the quote.
+func (q *Quote) Transaction() *Transaction {
+	return &Transaction{
+		WalletID:     q.WalletID,
+		ToWalletID:   q.ToWalletID,
+		Type:         q.Type,
+		Amount:       q.Amount,
+		Currency:     q.Currency,
+		Fee:          q.Fee,
+		DestAmount:   q.DestAmount,
+		DestCurrency: q.DestCurrency,
+		FXRate:       q.FXRate,
+		FXRateAt:     q.FXRateAt,
+		QuoteID:      sql.NullString{String: q.ID, Valid: true},
+	}
+}
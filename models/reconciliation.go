@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"WalletApp/commons"
+)
+
+// ReconciliationReport is the outcome of recomputing every wallet balance from the transaction history.
+type ReconciliationReport struct {
+	ID             string            `db:"id" json:"report_id,omitempty"`
+	StartedAt      time.Time         `db:"started_at" json:"started_at"`
+	FinishedAt     time.Time         `db:"finished_at" json:"finished_at"`
+	WalletsChecked int               `db:"wallets_checked" json:"wallets_checked"`
+	Mismatches     []*WalletMismatch `db:"-" json:"mismatches"`
+}
+
+// WalletMismatch is a wallet whose cached balance differs from the balance computed from its history.
+// Drift is the cached balance minus the expected balance.
+type WalletMismatch struct {
+	WalletID        string        `db:"wallet_id" json:"wallet_id"`
+	Currency        string        `db:"currency" json:"currency"`
+	Balance         commons.Money `db:"balance" json:"balance"`
+	ExpectedBalance commons.Money `db:"expected_balance" json:"expected_balance"`
+	Drift           commons.Money `db:"drift" json:"drift"`
+}
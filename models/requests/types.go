@@ -1,16 +1,48 @@
 package requests
 
+import "WalletApp/commons"
+
 type BaseTransactionRequest struct {
-	Amount           float64 `json:"amount"`
-	IdempotencyToken string  `json:"idempotency_token"`
+	Amount           commons.Money `json:"amount"`
+	IdempotencyToken string        `json:"idempotency_token"`
 }
 type DepositRequest struct {
 	BaseTransactionRequest
 }
 type WithdrawRequest struct {
 	BaseTransactionRequest
+	QuoteID string `json:"quote_id"` // when set, the withdrawal is executed with the quoted amount and fee
 }
 type TransferRequest struct {
 	BaseTransactionRequest
 	ToAccount string `json:"recipient_wallet_id"`
+	QuoteID   string `json:"quote_id"` // when set, the transfer is executed with the quoted amounts, fee and rate
+}
+type HoldRequest struct {
+	BaseTransactionRequest
+	ToAccount string `json:"recipient_wallet_id"` // when set, the hold is captured as a transfer to this wallet
+	ExpiresIn int64  `json:"expires_in_seconds"`  // defaults to commons.DefaultHoldTTL
+}
+type CaptureHoldRequest struct {
+	BaseTransactionRequest // the amount is optional and defaults to the full held amount
+}
+type VoidHoldRequest struct {
+	IdempotencyToken string `json:"idempotency_token"`
+}
+type ReversalRequest struct {
+	BaseTransactionRequest // the amount is optional and defaults to the amount left to reverse
+}
+type QuoteRequest struct {
+	Type      string        `json:"type"` // withdrawal, transfer
+	Amount    commons.Money `json:"amount"`
+	ToAccount string        `json:"recipient_wallet_id"`
+}
+
+type CreateWalletRequest struct {
+	Label    string `json:"label"`
+	Currency string `json:"currency"`
+}
+
+type CreateUserRequest struct {
+	Currency string `json:"currency"`
 }
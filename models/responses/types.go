@@ -1,7 +1,11 @@
 package responses
 
 import (
+	"context"
+	"io"
 	"time"
+
+	"WalletApp/commons"
 )
 
 type Response struct {
@@ -12,15 +16,135 @@ type Response struct {
 }
 
 type WalletBalanceResponse struct {
-	WalletID string  `json:"wallet_id"`
-	Balance  float64 `json:"balance"`
+	WalletID         string         `json:"wallet_id"`
+	TransactionID    string         `json:"transaction_id,omitempty"` // transaction recorded by the call, or the last one included as of AsOf
+	Balance          commons.Money  `json:"balance"`
+	AvailableBalance *commons.Money `json:"available_balance,omitempty"` // balance minus active holds
+	Currency         string         `json:"currency"`
+	Seq              *int64         `json:"seq,omitempty"`   // sequence number of the latest transaction on the wallet
+	AsOf             *time.Time     `json:"as_of,omitempty"` // set on point-in-time balances
 }
 
+// TransactionHistory describes a transaction from the point of view of the wallet whose history is listed.
+// SignedAmount is the amount credited to (positive) or debited from (negative) that wallet in its own currency,
+// fees included.
 type TransactionHistory struct {
-	Type       string    `json:"type"` // deposit, withdrawal, transfer
-	Amount     float64   `json:"amount"`
-	ToWalletID string    `json:"recipient_wallet_id,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID                   string         `json:"id"`
+	Type                 string         `json:"type"`      // deposit, withdrawal, transfer
+	Direction            string         `json:"direction"` // credit, debit
+	CounterpartyWalletID string         `json:"counterparty_wallet_id,omitempty"`
+	SignedAmount         commons.Money  `json:"signed_amount"`
+	Amount               commons.Money  `json:"amount"`
+	Currency             string         `json:"currency"`
+	Fee                  commons.Money  `json:"fee"`
+	ToWalletID           string         `json:"recipient_wallet_id,omitempty"`
+	RecipientAmount      *commons.Money `json:"recipient_amount,omitempty"`
+	RecipientCurrency    string         `json:"recipient_currency,omitempty"`
+	ExchangeRate         *commons.Rate  `json:"exchange_rate,omitempty"`
+	ExchangeRateAt       *time.Time     `json:"exchange_rate_at,omitempty"`
+	HoldID               string         `json:"hold_id,omitempty"`
+	ReversalOf           string         `json:"reversal_of,omitempty"`     // ID of the transaction reversed by this one
+	ReversedAmount       *commons.Money `json:"reversed_amount,omitempty"` // amount of this transaction already reversed
+	Seq                  int64          `json:"seq,omitempty"`             // sequence number of the transaction on the wallet
+	BalanceAfter         *commons.Money `json:"balance_after,omitempty"`   // balance of the wallet right after the transaction
+	CreatedAt            time.Time      `json:"created_at"`
+}
+
+// StatementResponse lists the transactions of a wallet over a period, oldest first. The balance after each
+// transaction is the running balance of the statement, from the opening to the closing balance. Statements of
+// closed periods are persisted and never change.
+type StatementResponse struct {
+	WalletID       string                `json:"wallet_id"`
+	Currency       string                `json:"currency"`
+	PeriodStart    time.Time             `json:"period_start"`
+	PeriodEnd      time.Time             `json:"period_end"` // exclusive
+	Closed         bool                  `json:"closed"`
+	OpeningBalance commons.Money         `json:"opening_balance"`
+	ClosingBalance commons.Money         `json:"closing_balance"`
+	Totals         []*StatementTotal     `json:"totals"`
+	Transactions   []*TransactionHistory `json:"transactions"`
+	GeneratedAt    time.Time             `json:"generated_at"`
+}
+
+// ExportResponse is a transaction export ready to be streamed. The handler writes it to the response body once the
+// request has been authorized, so Write runs after the handler returned and must not use the request context.
+type ExportResponse struct {
+	ContentType string
+	FileName    string
+	Write       func(ctx context.Context, w io.Writer) error
+}
+
+// StatementTotal sums the transactions of one type in a statement.
+type StatementTotal struct {
+	Type    string        `json:"type"`
+	Count   int           `json:"count"`
+	Credits commons.Money `json:"credits"`
+	Debits  commons.Money `json:"debits"` // fees included
+}
+
+// TransactionDetailResponse describes a transaction from the point of view of one of its wallets.
+type TransactionDetailResponse struct {
+	*TransactionHistory
+	WalletID string `json:"wallet_id"`
+}
+
+// OperationResponse tells the outcome of the request made on a wallet with an idempotency token. A completed
+// operation has the resulting transaction and the wallet balance right after it, unless it did not create a
+// transaction, e.g. a hold. A failed operation has the reason it was rejected.
+type OperationResponse struct {
+	WalletID         string         `json:"wallet_id"`
+	IdempotencyToken string         `json:"idempotency_token"`
+	Status           string         `json:"status"` // pending, completed, failed
+	TransactionID    string         `json:"transaction_id,omitempty"`
+	Type             string         `json:"type,omitempty"`
+	BalanceAfter     *commons.Money `json:"balance_after,omitempty"`
+	Currency         string         `json:"currency,omitempty"`
+	Reason           string         `json:"reason,omitempty"`
+}
+
+// QuoteResponse describes the exact outcome of a prospective transaction. DebitAmount is the amount plus fee
+// taken from the wallet and CreditAmount is the amount received in CreditCurrency.
+type QuoteResponse struct {
+	QuoteID           string        `json:"quote_id"`
+	WalletID          string        `json:"wallet_id"`
+	Type              string        `json:"type"` // withdrawal, transfer
+	Currency          string        `json:"currency"`
+	Amount            commons.Money `json:"amount"`
+	Fee               commons.Money `json:"fee"`
+	DebitAmount       commons.Money `json:"debit_amount"`
+	CreditAmount      commons.Money `json:"credit_amount"`
+	CreditCurrency    string        `json:"credit_currency"`
+	RecipientWalletID string        `json:"recipient_wallet_id,omitempty"`
+	ExchangeRate      *commons.Rate `json:"exchange_rate,omitempty"`
+	ExchangeRateAt    *time.Time    `json:"exchange_rate_at,omitempty"`
+	ExpiresAt         time.Time     `json:"expires_at"`
+}
+
+type WalletResponse struct {
+	WalletID         string        `json:"wallet_id"`
+	UserID           string        `json:"user_id"`
+	Label            string        `json:"label,omitempty"`
+	Currency         string        `json:"currency"`
+	Balance          commons.Money `json:"balance"`
+	AvailableBalance commons.Money `json:"available_balance"`
+	CreatedAt        time.Time     `json:"created_at"`
+}
+
+type WalletListResponse struct {
+	UserID  string            `json:"user_id"`
+	Wallets []*WalletResponse `json:"wallets"`
+}
+
+type HoldResponse struct {
+	HoldID            string        `json:"hold_id"`
+	WalletID          string        `json:"wallet_id"`
+	RecipientWalletID string        `json:"recipient_wallet_id,omitempty"`
+	Amount            commons.Money `json:"amount"`
+	CapturedAmount    commons.Money `json:"captured_amount"`
+	Currency          string        `json:"currency"`
+	Status            string        `json:"status"`
+	ExpiresAt         time.Time     `json:"expires_at"`
+	CreatedAt         time.Time     `json:"created_at"`
 }
 
 type TransactionHistoryResponse struct {
@@ -28,11 +152,15 @@ type TransactionHistoryResponse struct {
 	Transactions []*TransactionHistory `json:"transactions"`
 	Limit        int32                 `json:"limit"`
 	Offset       int32                 `json:"offset"`
+	LatestSeq    int64                 `json:"latest_seq"` // sequence number of the latest transaction on the wallet
+	NextCursor   string                `json:"next_cursor,omitempty"`
+	PrevCursor   string                `json:"prev_cursor,omitempty"`
 }
 
 type UserWallet struct {
 	UserID    string    `json:"user_id"`
 	WalletID  string    `json:"wallet_id"`
+	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Statement is a wallet statement persisted once its period is closed. The content is stored as rendered so
+// that the statement never changes afterwards.
+type Statement struct {
+	ID          string    `db:"id"`
+	WalletID    string    `db:"wallet_id"`
+	PeriodStart time.Time `db:"period_start"`
+	PeriodEnd   time.Time `db:"period_end"` // exclusive
+	Content     []byte    `db:"content"`    // JSON
+	CreatedAt   time.Time `db:"created_at"`
+}
@@ -2,14 +2,98 @@ package models
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"time"
+
+	"WalletApp/commons"
 )
 
 type Transaction struct {
-	ID         string         `db:"id"`
-	WalletID   string         `db:"from_wallet_id"`
-	Type       string         `db:"type"` // deposit, withdrawal, transfer
-	Amount     float64        `db:"amount"`
-	ToWalletID sql.NullString `db:"to_wallet_id,omitempty"`
-	CreatedAt  time.Time      `db:"created_at"`
+	ID           string         `db:"id"`
+	WalletID     string         `db:"from_wallet_id"`
+	Type         string         `db:"type"` // deposit, withdrawal, transfer
+	Amount       commons.Money  `db:"amount"`
+	Currency     string         `db:"currency"`
+	Fee          commons.Money  `db:"fee"` // charged to from_wallet_id on top of the amount
+	ToWalletID   sql.NullString `db:"to_wallet_id,omitempty"`
+	DestAmount   *commons.Money `db:"dest_amount"`   // amount credited to the recipient of a transfer
+	DestCurrency sql.NullString `db:"dest_currency"` // currency of the recipient wallet of a transfer
+	FXRate       *commons.Rate  `db:"fx_rate"`       // set only for cross-currency transfers
+	FXRateAt     sql.NullTime   `db:"fx_rate_at"`    // timestamp of the rate used for the conversion
+	QuoteID      sql.NullString `db:"quote_id"`      // set when the transaction executes a quote
+	HoldID       sql.NullString `db:"hold_id"`       // set when the transaction captures a hold
+	ReversalOf   sql.NullString `db:"reversal_of"`   // set when the transaction reverses another transaction
+	CreatedAt    time.Time      `db:"created_at"`
+
+	IdempotencyToken sql.NullString `db:"idempotency_token"` // token of the request that created the transaction
+
+	ReversedAmount commons.Money `db:"reversed_amount"` // amount already reversed, computed on read
+
+	// Sequence number and balance of the wallet whose history is read, taken from its posting
+	Seq          sql.NullInt64  `db:"seq"`
+	BalanceAfter *commons.Money `db:"balance_after"`
+
+	IdempotencyKey *IdempotencyKey `db:"-"` // committed with the transaction when set
+}
+
+// TransactionFilter narrows down the transaction history of a wallet. Zero values do not filter. Amounts and
+// directions are seen from the wallet: the amount of an incoming transfer is the amount credited to the wallet.
+type TransactionFilter struct {
+	Types                []string           // deposit, withdrawal, transfer
+	From                 *time.Time         // inclusive
+	To                   *time.Time         // exclusive
+	MinAmount            *commons.Money     // inclusive, fees excluded
+	MaxAmount            *commons.Money     // inclusive, fees excluded
+	CounterpartyWalletID string             // other wallet of a transfer
+	Direction            string             // credit, debit
+	Ascending            bool               // oldest first instead of newest first
+	Cursor               *TransactionCursor // returns the page after (or before) the cursor instead of using the offset
+	AfterSeq             *int64             // only transactions after this wallet sequence number, in sequence order
+}
+
+// TransactionCursor is a position in the transaction history of a wallet, keyed on (created_at, id) so that pages
+// stay stable when new transactions arrive. A backward cursor returns the page before the position.
+type TransactionCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Ascending bool      `json:"asc,omitempty"` // sort order of the history the cursor was taken from
+	Backward  bool      `json:"back,omitempty"`
+}
+
+// Encode returns the opaque representation of the cursor exposed to clients.
+func (c *TransactionCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTransactionCursor parses a cursor returned by Encode.
+func DecodeTransactionCursor(value string) (*TransactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, commons.InvalidCursorError
+	}
+	var cursor TransactionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.CreatedAt.IsZero() {
+		return nil, commons.InvalidCursorError
+	}
+	return &cursor, nil
+}
+
+// DebitedAmount returns the total amount debited from the source wallet of a withdrawal or transfer.
+func (t *Transaction) DebitedAmount() commons.Money {
+	return t.Amount.Add(t.Fee)
+}
+
+// ReversibleAmount returns the amount of the transaction that can still be reversed.
+func (t *Transaction) ReversibleAmount() commons.Money {
+	return t.Amount.Sub(t.ReversedAmount)
+}
+
+// CreditedAmount returns the amount credited to the recipient wallet of a transfer.
+func (t *Transaction) CreditedAmount() commons.Money {
+	if t.DestAmount != nil {
+		return *t.DestAmount
+	}
+	return t.Amount
 }
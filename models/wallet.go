@@ -1,11 +1,20 @@
 package models
 
-import "time"
+import (
+	"database/sql"
+	"time"
+
+	"WalletApp/commons"
+)
 
 type Wallet struct {
-	ID        string    `db:"id"`
-	UserID    string    `db:"user_id"`
-	Balance   float64   `db:"balance"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID               string         `db:"id"`
+	UserID           string         `db:"user_id"`
+	Label            sql.NullString `db:"label"`
+	Currency         string         `db:"currency"`
+	Balance          commons.Money  `db:"balance"`
+	AvailableBalance commons.Money  `db:"available_balance"` // balance minus active holds, computed on read
+	Seq              int64          `db:"seq"`               // sequence number of the latest posting on the wallet
+	CreatedAt        time.Time      `db:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at"`
 }
@@ -1,13 +1,169 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	"WalletApp/commons"
+	"WalletApp/models"
 	"WalletApp/models/responses"
 )
 
-func successBalanceResponse(walletID string, balance float64, msg string) responses.Response {
-	return getResponse(responses.WalletBalanceResponse{WalletID: walletID, Balance: balance}, msg, http.StatusOK, nil)
+func successBalanceResponse(walletID, txnID string, balance commons.Money, currency string, msg string) responses.Response {
+	return getResponse(responses.WalletBalanceResponse{WalletID: walletID, TransactionID: txnID, Balance: balance, Currency: currency}, msg, http.StatusOK, nil)
+}
+
+// transactionHistory describes the transaction from the point of view of the given wallet. The recipient of a
+// transfer sees a credit of the converted amount from the sender, and the sender a debit of the amount and fee.
+func transactionHistory(tx *models.Transaction, walletID string) *responses.TransactionHistory {
+	direction, counterparty := transactionDirection(tx, walletID)
+	signedAmount := tx.DebitedAmount().Neg()
+	if direction == commons.TransactionDirectionCredit {
+		signedAmount = tx.CreditedAmount()
+	}
+	history := &responses.TransactionHistory{
+		ID:                   tx.ID,
+		Type:                 tx.Type,
+		Direction:            string(direction),
+		CounterpartyWalletID: counterparty,
+		SignedAmount:         signedAmount,
+		Amount:               tx.Amount,
+		Currency:             tx.Currency,
+		Fee:                  tx.Fee,
+		ToWalletID:           tx.ToWalletID.String,
+		RecipientAmount:      tx.DestAmount,
+		RecipientCurrency:    tx.DestCurrency.String,
+		ExchangeRate:         tx.FXRate,
+		HoldID:               tx.HoldID.String,
+		ReversalOf:           tx.ReversalOf.String,
+		Seq:                  tx.Seq.Int64,
+		BalanceAfter:         tx.BalanceAfter,
+		CreatedAt:            tx.CreatedAt,
+	}
+	if tx.ReversedAmount.IsPositive() {
+		history.ReversedAmount = &tx.ReversedAmount
+	}
+	if tx.FXRateAt.Valid {
+		history.ExchangeRateAt = &tx.FXRateAt.Time
+	}
+	return history
+}
+
+// transactionDirection returns whether the transaction credits or debits the wallet, and the other wallet of a
+// transfer. Deposits and withdrawals have no counterparty wallet.
+func transactionDirection(tx *models.Transaction, walletID string) (commons.TransactionDirection, string) {
+	switch commons.TransactionType(tx.Type) {
+	case commons.TransactionTypeDeposit:
+		return commons.TransactionDirectionCredit, ""
+	case commons.TransactionTypeTransfer:
+		if tx.ToWalletID.String == walletID {
+			return commons.TransactionDirectionCredit, tx.WalletID
+		}
+		return commons.TransactionDirectionDebit, tx.ToWalletID.String
+	default:
+		return commons.TransactionDirectionDebit, ""
+	}
+}
+
+func walletResponse(wallet *models.Wallet) *responses.WalletResponse {
+	return &responses.WalletResponse{
+		WalletID:         wallet.ID,
+		UserID:           wallet.UserID,
+		Label:            wallet.Label.String,
+		Currency:         wallet.Currency,
+		Balance:          wallet.Balance,
+		AvailableBalance: wallet.AvailableBalance,
+		CreatedAt:        wallet.CreatedAt,
+	}
+}
+
+// reversalTransaction builds the compensating transaction that reverses the amount of the original deposit or
+// transfer. The amount credited back to the sender of a transfer is recorded as the credited amount of the reversal.
+func reversalTransaction(original *models.Transaction, amount commons.Money) (*models.Transaction, error) {
+	reversalOf := sql.NullString{String: original.ID, Valid: true}
+	if commons.TransactionType(original.Type) == commons.TransactionTypeDeposit {
+		return &models.Transaction{
+			WalletID:   original.WalletID,
+			Type:       string(commons.TransactionTypeWithdraw),
+			Amount:     amount,
+			Currency:   original.Currency,
+			ReversalOf: reversalOf,
+		}, nil
+	}
+
+	txn := &models.Transaction{
+		WalletID:   original.ToWalletID.String,
+		ToWalletID: sql.NullString{String: original.WalletID, Valid: true},
+		Type:       string(commons.TransactionTypeTransfer),
+		Amount:     amount,
+		Currency:   original.Currency,
+		ReversalOf: reversalOf,
+	}
+	if !original.DestCurrency.Valid || original.DestCurrency.String == original.Currency {
+		return txn, nil
+	}
+
+	// The recipient gives back the share of the converted amount matching the reversed amount. Prorating the
+	// cumulative reversed amount makes the partial reversals add up to the converted amount.
+	places, err := commons.CurrencyDecimalPlaces(original.DestCurrency.String)
+	if err != nil {
+		return nil, err
+	}
+	credited := original.CreditedAmount()
+	reversed := original.ReversedAmount
+	debit := credited.Prorate(reversed.Add(amount), original.Amount, places).Sub(credited.Prorate(reversed, original.Amount, places))
+	if !debit.IsPositive() {
+		return nil, errors.New("amount is too small to be reversed")
+	}
+	creditBack := amount
+	txn.Amount = debit
+	txn.Currency = original.DestCurrency.String
+	txn.DestAmount = &creditBack
+	txn.DestCurrency = sql.NullString{String: original.Currency, Valid: true}
+	return txn, nil
+}
+
+func holdResponse(hold *models.Hold) *responses.HoldResponse {
+	return &responses.HoldResponse{
+		HoldID:            hold.ID,
+		WalletID:          hold.WalletID,
+		RecipientWalletID: hold.ToWalletID.String,
+		Amount:            hold.Amount,
+		CapturedAmount:    hold.CapturedAmount,
+		Currency:          hold.Currency,
+		Status:            hold.Status,
+		ExpiresAt:         hold.ExpiresAt,
+		CreatedAt:         hold.CreatedAt,
+	}
+}
+
+func quoteResponse(quote *models.Quote) responses.QuoteResponse {
+	resp := responses.QuoteResponse{
+		QuoteID:           quote.ID,
+		WalletID:          quote.WalletID,
+		Type:              quote.Type,
+		Currency:          quote.Currency,
+		Amount:            quote.Amount,
+		Fee:               quote.Fee,
+		DebitAmount:       quote.Amount.Add(quote.Fee),
+		CreditAmount:      quote.Amount,
+		CreditCurrency:    quote.Currency,
+		RecipientWalletID: quote.ToWalletID.String,
+		ExchangeRate:      quote.FXRate,
+		ExpiresAt:         quote.ExpiresAt,
+	}
+	if quote.DestAmount != nil {
+		resp.CreditAmount = *quote.DestAmount
+		resp.CreditCurrency = quote.DestCurrency.String
+	}
+	if quote.FXRateAt.Valid {
+		resp.ExchangeRateAt = &quote.FXRateAt.Time
+	}
+	return resp
 }
 
 func internalError(msg string, err error) responses.Response {
@@ -18,8 +174,16 @@ func unauthorizedResponse() responses.Response {
 	return getResponse(nil, "unauthorized access to the wallet", http.StatusUnauthorized, nil)
 }
 
+func walletNotFoundResponse() responses.Response {
+	return getResponse(nil, "wallet does not exist", http.StatusNotFound, nil)
+}
+
 func duplicateRequestResponse() responses.Response {
-	return getResponse(nil, "duplicate request: idempotency key already exists", http.StatusConflict, nil)
+	return getResponse(nil, "duplicate request: idempotency key already used", http.StatusConflict, nil)
+}
+
+func requestInProgressResponse() responses.Response {
+	return getResponse(nil, "duplicate request: a request with this idempotency key is still in progress", http.StatusConflict, nil)
 }
 
 func badRequest(msg string) responses.Response {
@@ -34,3 +198,63 @@ func getResponse(data any, message string, status int, err error) responses.Resp
 		Error:   err,
 	}
 }
+
+// balanceBefore returns the balance left on the wallet by its latest posting before t, or zero if there is none.
+func (v *walletServiceV1) balanceBefore(ctx context.Context, walletID string, t time.Time) (commons.Money, error) {
+	// Timestamps are stored with microsecond precision
+	posting, err := v.DB.GetLatestWalletPosting(ctx, walletID, t.Add(-time.Microsecond))
+	if err != nil {
+		return commons.ZeroMoney, err
+	}
+	if posting == nil || posting.BalanceAfter == nil {
+		return commons.ZeroMoney, nil
+	}
+	return *posting.BalanceAfter, nil
+}
+
+// buildStatement lists the transactions from the opening balance of the period, keeping the running balance and the
+// totals of each transaction type in the order they first appear.
+func buildStatement(wallet *models.Wallet, opening commons.Money, transactions []*models.Transaction) *responses.StatementResponse {
+	statement := &responses.StatementResponse{
+		WalletID:       wallet.ID,
+		Currency:       wallet.Currency,
+		OpeningBalance: opening,
+		Totals:         []*responses.StatementTotal{},
+		Transactions:   make([]*responses.TransactionHistory, 0, len(transactions)),
+		GeneratedAt:    time.Now().UTC(),
+	}
+
+	running := statement.OpeningBalance
+	totals := make(map[string]*responses.StatementTotal)
+	for _, tx := range transactions {
+		history := transactionHistory(tx, wallet.ID)
+		running = running.Add(history.SignedAmount)
+		balance := running
+		history.BalanceAfter = &balance
+		statement.Transactions = append(statement.Transactions, history)
+
+		total, ok := totals[tx.Type]
+		if !ok {
+			total = &responses.StatementTotal{Type: tx.Type, Credits: commons.ZeroMoney, Debits: commons.ZeroMoney}
+			totals[tx.Type] = total
+			statement.Totals = append(statement.Totals, total)
+		}
+		total.Count++
+		if history.SignedAmount.IsNegative() {
+			total.Debits = total.Debits.Add(history.SignedAmount.Neg())
+		} else {
+			total.Credits = total.Credits.Add(history.SignedAmount)
+		}
+	}
+	statement.ClosingBalance = running
+	return statement
+}
+
+// statementResponse returns a persisted statement as it was rendered when its period closed.
+func statementResponse(stored *models.Statement) responses.Response {
+	var statement responses.StatementResponse
+	if err := json.Unmarshal(stored.Content, &statement); err != nil {
+		return internalError("failed to read the statement", err)
+	}
+	return getResponse(statement, "statement retrieval successful", http.StatusOK, nil)
+}
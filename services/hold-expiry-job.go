@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"WalletApp/db"
+)
+
+// RunHoldExpiryJob marks the holds past their expiry as expired every interval until the context is cancelled.
+// Expired holds no longer reserve funds even before this job runs. The job only keeps their status accurate.
+func RunHoldExpiryJob(ctx context.Context, dbClient db.Database, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := dbClient.ExpireHolds(ctx)
+			if err != nil {
+				log.Errorf("failed to expire holds: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Infof("expired %d holds", expired)
+			}
+		}
+	}
+}
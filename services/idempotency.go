@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"WalletApp/commons"
+	"WalletApp/models"
+	"WalletApp/models/responses"
+)
+
+// idempotencyRecord is what is kept in the idempotency store for a key. Status is zero while the first request
+// using the key is in flight, and the final response of that request afterwards.
+type idempotencyRecord struct {
+	Fingerprint string          `json:"fingerprint"`
+	WalletID    string          `json:"wallet_id,omitempty"`
+	Status      int             `json:"status,omitempty"`
+	Message     string          `json:"message,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+func (r *idempotencyRecord) response() responses.Response {
+	resp := responses.Response{Status: r.Status, Message: r.Message}
+	if len(r.Data) > 0 {
+		resp.Data = r.Data
+	}
+	if r.Error != "" {
+		resp.Error = errors.New(r.Error)
+	}
+	return resp
+}
+
+// idempotent runs the request under the idempotency key of the user, Stripe style. The first request with a key
+// runs fn and its final response is kept for the idempotency retention. A retry with the same key and payload
+// gets that response back, or a conflict while the first request is still in flight. Reusing the key for a
+// different payload is rejected. Internal errors are not kept, so the request can be retried with the same key.
+//
+// fn passes the key to its write so that the key is committed in the same SQL transaction. A request whose key
+// was committed by another one, e.g. after the store lost it, gets a conflict instead of being executed twice.
+//
+// Parameters:
+//   - ctx: Context used for cancellation and timeout control.
+//   - token: Idempotency key provided by the client.
+//   - action: The operation, part of the payload fingerprint.
+//   - userID: ID of the user making the request. Keys are scoped to the user.
+//   - walletID: The wallet the operation acts on, part of the payload fingerprint.
+//   - resourceID: The counterparty wallet, quote, hold or transaction of the operation, if any, part of the payload
+//     fingerprint.
+//   - amount: Amount of the operation, part of the payload fingerprint.
+//   - fn: Performs the operation, committing the given key with its write.
+//
+// Returns:
+//   - The response of fn, or the stored response of the first request with the key.
+func (v *walletServiceV1) idempotent(ctx context.Context, token, action, userID, walletID, resourceID string, amount commons.Money, fn func(key *models.IdempotencyKey) responses.Response) responses.Response {
+	target := walletID
+	if resourceID != "" {
+		target = fmt.Sprintf("%s-%s", walletID, resourceID)
+	}
+	fingerprint := fmt.Sprintf("%s-%s-%s", action, target, amount)
+	inFlight, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, WalletID: walletID})
+	if err != nil {
+		return internalError(fmt.Sprintf("error while checking idempotency for %s action", action), err)
+	}
+	ok, err := v.Idempotency.SetWithExpirationIfKeyIsNotSet(ctx, userID, token, string(inFlight), commons.IdempotencyCacheTTL)
+	if errors.Is(err, commons.CacheFullError) {
+		// the store refuses new keys until the kept ones expire
+		log.Warnf("idempotency store is full, refusing key '%s' of user %s", token, userID)
+		return getResponse(nil, "too many requests, retry later", http.StatusServiceUnavailable, err)
+	}
+	if err != nil {
+		log.Errorf("idempotency check failed for key '%s' of user %s: %v", token, userID, err)
+		return internalError(fmt.Sprintf("error while checking idempotency for %s action", action), err)
+	}
+	if !ok {
+		return v.replayIdempotentRequest(ctx, token, userID, fingerprint, action)
+	}
+
+	retention := v.IdempotencyRetention
+	if retention <= 0 {
+		retention = commons.IdempotencyResponseTTL
+	}
+	resp := fn(&models.IdempotencyKey{UserID: userID, Token: token, Value: string(inFlight), Retention: retention})
+	if errors.Is(resp.Error, commons.DuplicateRequestError) {
+		// the key belongs to the request that committed it
+		return duplicateRequestResponse()
+	}
+	if resp.Status >= http.StatusInternalServerError {
+		if err := v.Idempotency.Delete(ctx, userID, token); err != nil {
+			log.Errorf("failed to release idempotency key '%s' of user %s: %v", token, userID, err)
+		}
+		return resp
+	}
+
+	record := idempotencyRecord{Fingerprint: fingerprint, WalletID: walletID, Status: resp.Status, Message: resp.Message}
+	if resp.Error != nil {
+		record.Error = resp.Error.Error()
+	}
+	if resp.Data != nil {
+		if record.Data, err = json.Marshal(resp.Data); err != nil {
+			log.Errorf("failed to encode the response of idempotency key '%s' of user %s: %v", token, userID, err)
+			return resp
+		}
+	}
+	value, err := json.Marshal(record)
+	if err == nil {
+		err = v.Idempotency.SetWithExpiration(ctx, userID, token, string(value), retention)
+	}
+	if err != nil {
+		log.Errorf("failed to store the response of idempotency key '%s' of user %s: %v", token, userID, err)
+	}
+	return resp
+}
+
+// replayIdempotentRequest answers a request whose idempotency key is already taken.
+func (v *walletServiceV1) replayIdempotentRequest(ctx context.Context, token, userID, fingerprint, action string) responses.Response {
+	record, err := v.getIdempotencyRecord(ctx, userID, token)
+	if err != nil {
+		log.Errorf("idempotency check failed for key '%s' of user %s: %v", token, userID, err)
+		return internalError(fmt.Sprintf("error while checking idempotency for %s action", action), err)
+	}
+	if record == nil {
+		// the first request failed and released the key in the meantime
+		return requestInProgressResponse()
+	}
+	if record.Fingerprint != fingerprint {
+		return getResponse(nil, "idempotency key already used for a different request", http.StatusUnprocessableEntity, nil)
+	}
+	if record.Status == 0 {
+		return requestInProgressResponse()
+	}
+	return record.response()
+}
+
+// getIdempotencyRecord returns the record kept for the idempotency key of the user, or nil if it is not set.
+func (v *walletServiceV1) getIdempotencyRecord(ctx context.Context, userID, token string) (*idempotencyRecord, error) {
+	value, found, err := v.Idempotency.Get(ctx, userID, token)
+	if err != nil || !found {
+		return nil, err
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"WalletApp/commons"
+	mocks2 "WalletApp/db/mocks"
+	"WalletApp/models"
+	"WalletApp/models/responses"
+)
+
+// expectIdempotencyKeyInFlight makes the idempotency key look taken by the same request, still in flight.
+func expectIdempotencyKeyInFlight(idempotencyMock *mocks2.MockIdempotencyStore) {
+	var inFlight string
+	idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, value string, _ time.Duration) (bool, error) {
+			inFlight = value
+			return false, nil
+		})
+	idempotencyMock.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, string, string) (string, bool, error) {
+		return inFlight, true, nil
+	})
+}
+
+func TestWalletServiceV1_Idempotency(t *testing.T) {
+	walletID := "1234"
+	userID := "2222"
+	idempotencyKey := "test-key"
+	amount := commons.MustMoney("120")
+
+	// setup returns a service over an idempotency store mock in which the wallet of the user can be deposited into
+	setup := func(t *testing.T) (*walletServiceV1, *mocks2.MockDatabase, *mocks2.MockIdempotencyStore) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil).AnyTimes()
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil).AnyTimes()
+		return &walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}, databaseMock, idempotencyMock
+	}
+
+	t.Run("should replay the stored response to a retry with the same key and payload", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		var stored string
+		gomock.InOrder(
+			idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), commons.IdempotencyCacheTTL).Return(true, nil),
+			idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), "2222", "test-key", gomock.Any(), commons.IdempotencyResponseTTL).
+				DoAndReturn(func(_ context.Context, _ string, _ string, value string, _ time.Duration) error {
+					stored = value
+					return nil
+				}),
+			idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), commons.IdempotencyCacheTTL).Return(false, nil),
+			idempotencyMock.EXPECT().Get(gomock.Any(), "2222", "test-key").DoAndReturn(func(context.Context, string, string) (string, bool, error) {
+				return stored, true, nil
+			}),
+		)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+			txn.ID = "txn-1"
+			return commons.MustMoney("120"), nil
+		}).Times(1)
+
+		first := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		retry := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusOK, retry.Status)
+		assert.Equal(t, first.Message, retry.Message)
+		firstData, _ := json.Marshal(first.Data)
+		retryData, _ := json.Marshal(retry.Data)
+		assert.JSONEq(t, string(firstData), string(retryData))
+	})
+
+	t.Run("should replay a stored bad request response", func(t *testing.T) {
+		walletService, _, idempotencyMock := setup(t)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		idempotencyMock.EXPECT().Get(gomock.Any(), "2222", "test-key").
+			Return(`{"fingerprint":"withdrawal-1234-120","status":400,"message":"insufficient balance error"}`, true, nil)
+		resp := walletService.Withdraw(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "insufficient balance error", resp.Message)
+		assert.Nil(t, resp.Data)
+	})
+
+	t.Run("should return unprocessable entity response if the key was used for a different payload", func(t *testing.T) {
+		walletService, _, idempotencyMock := setup(t)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		idempotencyMock.EXPECT().Get(gomock.Any(), "2222", "test-key").
+			Return(`{"fingerprint":"deposit-1234-80","status":200,"message":"deposit successful"}`, true, nil)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Status)
+		assert.Equal(t, "idempotency key already used for a different request", resp.Message)
+	})
+
+	t.Run("should return conflict response if the key was released while the retry was checking it", func(t *testing.T) {
+		walletService, _, idempotencyMock := setup(t)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		idempotencyMock.EXPECT().Get(gomock.Any(), "2222", "test-key").Return("", false, nil)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusConflict, resp.Status)
+	})
+
+	t.Run("should commit the key with the ledger write and keep the response for the configured retention", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		walletService.IdempotencyRetention = 30 * 24 * time.Hour
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), commons.IdempotencyCacheTTL).Return(true, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			key := txn.IdempotencyKey
+			return key != nil && key.UserID == "2222" && key.Token == "test-key" && key.Retention == 30*24*time.Hour &&
+				key.Value == `{"fingerprint":"deposit-1234-120","wallet_id":"1234"}`
+		})).Return(commons.MustMoney("120"), nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), "2222", "test-key", gomock.Any(), 30*24*time.Hour).Return(nil)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+	})
+
+	t.Run("should return conflict response without touching the key if another request committed it", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, commons.DuplicateRequestError)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusConflict, resp.Status)
+		assert.Equal(t, "duplicate request: idempotency key already used", resp.Message)
+	})
+
+	t.Run("should release the key if the request fails with an internal error", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		gomock.InOrder(
+			idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), gomock.Any()).Return(true, nil),
+			databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.Money{}, errors.New("test db error")),
+			idempotencyMock.EXPECT().Delete(gomock.Any(), "2222", "test-key").Return(nil),
+		)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusInternalServerError, resp.Status)
+	})
+
+	t.Run("should return service unavailable response without running the request if the store is full", func(t *testing.T) {
+		walletService, _, idempotencyMock := setup(t)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), gomock.Any()).Return(false, commons.CacheFullError)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.Status)
+	})
+
+	// expectStoredResponse keeps the response of the first request with the key and replays it to the retry
+	expectStoredResponse := func(idempotencyMock *mocks2.MockIdempotencyStore) {
+		var stored string
+		gomock.InOrder(
+			idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), gomock.Any()).Return(true, nil),
+			idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), "2222", "test-key", gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ string, _ string, value string, _ time.Duration) error {
+					stored = value
+					return nil
+				}),
+			idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), "2222", "test-key", gomock.Any(), gomock.Any()).Return(false, nil),
+			idempotencyMock.EXPECT().Get(gomock.Any(), "2222", "test-key").DoAndReturn(func(context.Context, string, string) (string, bool, error) {
+				return stored, true, nil
+			}),
+		)
+	}
+
+	t.Run("should replay the stored response to a retry of a completed capture", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		hold := &models.Hold{ID: "hold-1", WalletID: walletID, Amount: amount, Currency: "USD", Status: string(commons.HoldStatusActive),
+			ExpiresAt: time.Now().Add(time.Hour)}
+		databaseMock.EXPECT().GetHold(gomock.Any(), "hold-1").DoAndReturn(func(context.Context, string) (*models.Hold, error) {
+			held := *hold
+			return &held, nil
+		}).Times(2)
+		expectStoredResponse(idempotencyMock)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+			txn.ID = "txn-1"
+			hold.Status = string(commons.HoldStatusCaptured)
+			return commons.MustMoney("30"), nil
+		}).Times(1)
+
+		first := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, "hold-1", commons.ZeroMoney, userID)
+		retry := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, "hold-1", commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusOK, first.Status)
+		assert.Equal(t, http.StatusOK, retry.Status)
+		assert.Equal(t, "hold captured successfully", retry.Message)
+		firstData, _ := json.Marshal(first.Data)
+		retryData, _ := json.Marshal(retry.Data)
+		assert.JSONEq(t, string(firstData), string(retryData))
+	})
+
+	t.Run("should replay the stored response to a retry of a completed full reversal", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		original := &models.Transaction{ID: "txn-1", WalletID: walletID, Type: string(commons.TransactionTypeDeposit), Amount: amount, Currency: "USD"}
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), "txn-1").DoAndReturn(func(context.Context, string) (*models.Transaction, error) {
+			txn := *original
+			return &txn, nil
+		}).Times(2)
+		expectStoredResponse(idempotencyMock)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+			txn.ID = "txn-2"
+			original.ReversedAmount = amount
+			return commons.ZeroMoney, nil
+		}).Times(1)
+
+		first := walletService.ReverseTransaction(context.Background(), idempotencyKey, walletID, "txn-1", commons.ZeroMoney, userID)
+		retry := walletService.ReverseTransaction(context.Background(), idempotencyKey, walletID, "txn-1", commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusOK, first.Status)
+		assert.Equal(t, http.StatusOK, retry.Status)
+		assert.Equal(t, "reversal successful", retry.Message)
+		firstData, _ := json.Marshal(first.Data)
+		retryData, _ := json.Marshal(retry.Data)
+		assert.JSONEq(t, string(firstData), string(retryData))
+	})
+}
+
+func TestWalletServiceV1_GetOperation(t *testing.T) {
+	walletID := "1234"
+	userID := "2222"
+	token := "test-key"
+
+	// setup returns a service in which the user owns the wallet and no transaction has the token
+	setup := func(t *testing.T) (*walletServiceV1, *mocks2.MockDatabase, *mocks2.MockIdempotencyStore) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		return &walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}, databaseMock, idempotencyMock
+	}
+	operation := func(t *testing.T, resp responses.Response) responses.OperationResponse {
+		assert.Equal(t, http.StatusOK, resp.Status)
+		data, ok := resp.Data.(responses.OperationResponse)
+		assert.True(t, ok, "cannot cast the response")
+		return data
+	}
+
+	t.Run("should return unauthorized response if the user does not own the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(false, nil)
+		resp := walletService.GetOperation(context.Background(), walletID, token, userID)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	})
+
+	t.Run("should return the transaction and the balance after it once the operation is recorded", func(t *testing.T) {
+		walletService, databaseMock, _ := setup(t)
+		balance := commons.MustMoney("70")
+		databaseMock.EXPECT().GetTransactionByIdempotencyToken(gomock.Any(), walletID, token).
+			Return(&models.Transaction{ID: "txn-1", WalletID: walletID, Type: "deposit", Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWalletPosting(gomock.Any(), walletID, "txn-1").Return(&models.Posting{BalanceAfter: &balance}, nil)
+		data := operation(t, walletService.GetOperation(context.Background(), walletID, token, userID))
+		assert.Equal(t, "completed", data.Status)
+		assert.Equal(t, "txn-1", data.TransactionID)
+		assert.Equal(t, "deposit", data.Type)
+		assert.True(t, balance.Equal(*data.BalanceAfter))
+		assert.Equal(t, "USD", data.Currency)
+	})
+
+	t.Run("should return pending while the request is in flight", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		databaseMock.EXPECT().GetTransactionByIdempotencyToken(gomock.Any(), walletID, token).Return(nil, nil)
+		idempotencyMock.EXPECT().Get(gomock.Any(), userID, token).Return(`{"fingerprint":"deposit-1234-10","wallet_id":"1234"}`, true, nil)
+		data := operation(t, walletService.GetOperation(context.Background(), walletID, token, userID))
+		assert.Equal(t, "pending", data.Status)
+		assert.Empty(t, data.TransactionID)
+	})
+
+	t.Run("should return failed with the reason if the request was rejected", func(t *testing.T) {
+		walletService, databaseMock, idempotencyMock := setup(t)
+		databaseMock.EXPECT().GetTransactionByIdempotencyToken(gomock.Any(), walletID, token).Return(nil, nil)
+		idempotencyMock.EXPECT().Get(gomock.Any(), userID, token).
+			Return(`{"fingerprint":"withdrawal-1234-10","wallet_id":"1234","status":400,"message":"insufficient balance error"}`, true, nil)
+		data := operation(t, walletService.GetOperation(context.Background(), walletID, token, userID))
+		assert.Equal(t, "failed", data.Status)
+		assert.Equal(t, "insufficient balance error", data.Reason)
+	})
+
+	for name, value := range map[string]string{
+		"unknown":                "",
+		"used on another wallet": `{"fingerprint":"deposit-5678-10","wallet_id":"5678"}`,
+	} {
+		t.Run("should return failed if the token is "+name, func(t *testing.T) {
+			walletService, databaseMock, idempotencyMock := setup(t)
+			databaseMock.EXPECT().GetTransactionByIdempotencyToken(gomock.Any(), walletID, token).Return(nil, nil)
+			idempotencyMock.EXPECT().Get(gomock.Any(), userID, token).Return(value, value != "", nil)
+			data := operation(t, walletService.GetOperation(context.Background(), walletID, token, userID))
+			assert.Equal(t, "failed", data.Status)
+			assert.Equal(t, "no operation was applied with the idempotency token", data.Reason)
+		})
+	}
+}
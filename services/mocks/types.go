@@ -10,9 +10,12 @@
 package mocks
 
 import (
+	commons "WalletApp/commons"
+	models "WalletApp/models"
 	responses "WalletApp/models/responses"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -41,8 +44,64 @@ func (m *MockWalletService) EXPECT() *MockWalletServiceMockRecorder {
 	return m.recorder
 }
 
+// CaptureHold mocks base method.
+func (m *MockWalletService) CaptureHold(ctx context.Context, idempotencyKey, walletID, holdID string, amount commons.Money, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, idempotencyKey, walletID, holdID, amount, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockWalletServiceMockRecorder) CaptureHold(ctx, idempotencyKey, walletID, holdID, amount, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockWalletService)(nil).CaptureHold), ctx, idempotencyKey, walletID, holdID, amount, userID)
+}
+
+// CreateHold mocks base method.
+func (m *MockWalletService) CreateHold(ctx context.Context, idempotencyKey, walletID, toAccount string, amount commons.Money, ttl time.Duration, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, idempotencyKey, walletID, toAccount, amount, ttl, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockWalletServiceMockRecorder) CreateHold(ctx, idempotencyKey, walletID, toAccount, amount, ttl, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockWalletService)(nil).CreateHold), ctx, idempotencyKey, walletID, toAccount, amount, ttl, userID)
+}
+
+// CreateQuote mocks base method.
+func (m *MockWalletService) CreateQuote(ctx context.Context, walletID, toAccount string, trsType commons.TransactionType, amount commons.Money, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateQuote", ctx, walletID, toAccount, trsType, amount, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// CreateQuote indicates an expected call of CreateQuote.
+func (mr *MockWalletServiceMockRecorder) CreateQuote(ctx, walletID, toAccount, trsType, amount, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateQuote", reflect.TypeOf((*MockWalletService)(nil).CreateQuote), ctx, walletID, toAccount, trsType, amount, userID)
+}
+
+// CreateWallet mocks base method.
+func (m *MockWalletService) CreateWallet(ctx context.Context, userID, label, currency string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWallet", ctx, userID, label, currency)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// CreateWallet indicates an expected call of CreateWallet.
+func (mr *MockWalletServiceMockRecorder) CreateWallet(ctx, userID, label, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWallet", reflect.TypeOf((*MockWalletService)(nil).CreateWallet), ctx, userID, label, currency)
+}
+
 // Deposit mocks base method.
-func (m *MockWalletService) Deposit(ctx context.Context, idempotencyKey, toAccount string, amount float64, userID string) responses.Response {
+func (m *MockWalletService) Deposit(ctx context.Context, idempotencyKey, toAccount string, amount commons.Money, userID string) responses.Response {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Deposit", ctx, idempotencyKey, toAccount, amount, userID)
 	ret0, _ := ret[0].(responses.Response)
@@ -55,6 +114,34 @@ func (mr *MockWalletServiceMockRecorder) Deposit(ctx, idempotencyKey, toAccount,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockWalletService)(nil).Deposit), ctx, idempotencyKey, toAccount, amount, userID)
 }
 
+// ExecuteQuote mocks base method.
+func (m *MockWalletService) ExecuteQuote(ctx context.Context, idempotencyKey, walletID, quoteID string, trsType commons.TransactionType, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecuteQuote", ctx, idempotencyKey, walletID, quoteID, trsType, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// ExecuteQuote indicates an expected call of ExecuteQuote.
+func (mr *MockWalletServiceMockRecorder) ExecuteQuote(ctx, idempotencyKey, walletID, quoteID, trsType, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteQuote", reflect.TypeOf((*MockWalletService)(nil).ExecuteQuote), ctx, idempotencyKey, walletID, quoteID, trsType, userID)
+}
+
+// ExportTransactions mocks base method.
+func (m *MockWalletService) ExportTransactions(ctx context.Context, walletID, userID string, format commons.ExportFormat, periodStart, periodEnd time.Time) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportTransactions", ctx, walletID, userID, format, periodStart, periodEnd)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// ExportTransactions indicates an expected call of ExportTransactions.
+func (mr *MockWalletServiceMockRecorder) ExportTransactions(ctx, walletID, userID, format, periodStart, periodEnd any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportTransactions", reflect.TypeOf((*MockWalletService)(nil).ExportTransactions), ctx, walletID, userID, format, periodStart, periodEnd)
+}
+
 // GetBalance mocks base method.
 func (m *MockWalletService) GetBalance(ctx context.Context, walletID, userID string) responses.Response {
 	m.ctrl.T.Helper()
@@ -69,22 +156,106 @@ func (mr *MockWalletServiceMockRecorder) GetBalance(ctx, walletID, userID any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockWalletService)(nil).GetBalance), ctx, walletID, userID)
 }
 
+// GetBalanceAsOf mocks base method.
+func (m *MockWalletService) GetBalanceAsOf(ctx context.Context, walletID, userID string, asOf time.Time) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceAsOf", ctx, walletID, userID, asOf)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// GetBalanceAsOf indicates an expected call of GetBalanceAsOf.
+func (mr *MockWalletServiceMockRecorder) GetBalanceAsOf(ctx, walletID, userID, asOf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceAsOf", reflect.TypeOf((*MockWalletService)(nil).GetBalanceAsOf), ctx, walletID, userID, asOf)
+}
+
+// GetOperation mocks base method.
+func (m *MockWalletService) GetOperation(ctx context.Context, walletID, idempotencyToken, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOperation", ctx, walletID, idempotencyToken, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// GetOperation indicates an expected call of GetOperation.
+func (mr *MockWalletServiceMockRecorder) GetOperation(ctx, walletID, idempotencyToken, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOperation", reflect.TypeOf((*MockWalletService)(nil).GetOperation), ctx, walletID, idempotencyToken, userID)
+}
+
+// GetStatement mocks base method.
+func (m *MockWalletService) GetStatement(ctx context.Context, walletID, userID string, periodStart, periodEnd time.Time) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatement", ctx, walletID, userID, periodStart, periodEnd)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// GetStatement indicates an expected call of GetStatement.
+func (mr *MockWalletServiceMockRecorder) GetStatement(ctx, walletID, userID, periodStart, periodEnd any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatement", reflect.TypeOf((*MockWalletService)(nil).GetStatement), ctx, walletID, userID, periodStart, periodEnd)
+}
+
+// GetTransaction mocks base method.
+func (m *MockWalletService) GetTransaction(ctx context.Context, walletID, txnID, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransaction", ctx, walletID, txnID, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// GetTransaction indicates an expected call of GetTransaction.
+func (mr *MockWalletServiceMockRecorder) GetTransaction(ctx, walletID, txnID, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockWalletService)(nil).GetTransaction), ctx, walletID, txnID, userID)
+}
+
 // GetTransactionHistory mocks base method.
-func (m *MockWalletService) GetTransactionHistory(ctx context.Context, walletID, userID string, limit, offset int32) responses.Response {
+func (m *MockWalletService) GetTransactionHistory(ctx context.Context, walletID, userID string, filter *models.TransactionFilter, limit, offset int32) responses.Response {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransactionHistory", ctx, walletID, userID, limit, offset)
+	ret := m.ctrl.Call(m, "GetTransactionHistory", ctx, walletID, userID, filter, limit, offset)
 	ret0, _ := ret[0].(responses.Response)
 	return ret0
 }
 
 // GetTransactionHistory indicates an expected call of GetTransactionHistory.
-func (mr *MockWalletServiceMockRecorder) GetTransactionHistory(ctx, walletID, userID, limit, offset any) *gomock.Call {
+func (mr *MockWalletServiceMockRecorder) GetTransactionHistory(ctx, walletID, userID, filter, limit, offset any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionHistory", reflect.TypeOf((*MockWalletService)(nil).GetTransactionHistory), ctx, walletID, userID, limit, offset)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionHistory", reflect.TypeOf((*MockWalletService)(nil).GetTransactionHistory), ctx, walletID, userID, filter, limit, offset)
+}
+
+// GetWallets mocks base method.
+func (m *MockWalletService) GetWallets(ctx context.Context, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWallets", ctx, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// GetWallets indicates an expected call of GetWallets.
+func (mr *MockWalletServiceMockRecorder) GetWallets(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWallets", reflect.TypeOf((*MockWalletService)(nil).GetWallets), ctx, userID)
+}
+
+// ReverseTransaction mocks base method.
+func (m *MockWalletService) ReverseTransaction(ctx context.Context, idempotencyKey, walletID, txnID string, amount commons.Money, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseTransaction", ctx, idempotencyKey, walletID, txnID, amount, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// ReverseTransaction indicates an expected call of ReverseTransaction.
+func (mr *MockWalletServiceMockRecorder) ReverseTransaction(ctx, idempotencyKey, walletID, txnID, amount, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseTransaction", reflect.TypeOf((*MockWalletService)(nil).ReverseTransaction), ctx, idempotencyKey, walletID, txnID, amount, userID)
 }
 
 // Transfer mocks base method.
-func (m *MockWalletService) Transfer(ctx context.Context, idempotencyKey, toAccount, fromAccount string, amount float64, userID string) responses.Response {
+func (m *MockWalletService) Transfer(ctx context.Context, idempotencyKey, toAccount, fromAccount string, amount commons.Money, userID string) responses.Response {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Transfer", ctx, idempotencyKey, toAccount, fromAccount, amount, userID)
 	ret0, _ := ret[0].(responses.Response)
@@ -97,8 +268,22 @@ func (mr *MockWalletServiceMockRecorder) Transfer(ctx, idempotencyKey, toAccount
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockWalletService)(nil).Transfer), ctx, idempotencyKey, toAccount, fromAccount, amount, userID)
 }
 
+// VoidHold mocks base method.
+func (m *MockWalletService) VoidHold(ctx context.Context, idempotencyKey, walletID, holdID, userID string) responses.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VoidHold", ctx, idempotencyKey, walletID, holdID, userID)
+	ret0, _ := ret[0].(responses.Response)
+	return ret0
+}
+
+// VoidHold indicates an expected call of VoidHold.
+func (mr *MockWalletServiceMockRecorder) VoidHold(ctx, idempotencyKey, walletID, holdID, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoidHold", reflect.TypeOf((*MockWalletService)(nil).VoidHold), ctx, idempotencyKey, walletID, holdID, userID)
+}
+
 // Withdraw mocks base method.
-func (m *MockWalletService) Withdraw(ctx context.Context, idempotencyKey, fromAccount string, amount float64, userID string) responses.Response {
+func (m *MockWalletService) Withdraw(ctx context.Context, idempotencyKey, fromAccount string, amount commons.Money, userID string) responses.Response {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Withdraw", ctx, idempotencyKey, fromAccount, amount, userID)
 	ret0, _ := ret[0].(responses.Response)
@@ -111,6 +296,45 @@ func (mr *MockWalletServiceMockRecorder) Withdraw(ctx, idempotencyKey, fromAccou
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockWalletService)(nil).Withdraw), ctx, idempotencyKey, fromAccount, amount, userID)
 }
 
+// MockReconciliationService is a mock of ReconciliationService interface.
+type MockReconciliationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconciliationServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockReconciliationServiceMockRecorder is the mock recorder for MockReconciliationService.
+type MockReconciliationServiceMockRecorder struct {
+	mock *MockReconciliationService
+}
+
+// NewMockReconciliationService creates a new mock instance.
+func NewMockReconciliationService(ctrl *gomock.Controller) *MockReconciliationService {
+	mock := &MockReconciliationService{ctrl: ctrl}
+	mock.recorder = &MockReconciliationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciliationService) EXPECT() *MockReconciliationServiceMockRecorder {
+	return m.recorder
+}
+
+// Reconcile mocks base method.
+func (m *MockReconciliationService) Reconcile(ctx context.Context) (*models.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconcile", ctx)
+	ret0, _ := ret[0].(*models.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reconcile indicates an expected call of Reconcile.
+func (mr *MockReconciliationServiceMockRecorder) Reconcile(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockReconciliationService)(nil).Reconcile), ctx)
+}
+
 // MockUserService is a mock of UserService interface.
 type MockUserService struct {
 	ctrl     *gomock.Controller
@@ -136,17 +360,17 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 }
 
 // CreateUser mocks base method.
-func (m *MockUserService) CreateUser(ctx context.Context) responses.Response {
+func (m *MockUserService) CreateUser(ctx context.Context, currency string) responses.Response {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", ctx)
+	ret := m.ctrl.Call(m, "CreateUser", ctx, currency)
 	ret0, _ := ret[0].(responses.Response)
 	return ret0
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockUserServiceMockRecorder) CreateUser(ctx any) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) CreateUser(ctx, currency any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserService)(nil).CreateUser), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserService)(nil).CreateUser), ctx, currency)
 }
 
 // GetUsers mocks base method.
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"WalletApp/db"
+	"WalletApp/models"
+)
+
+type reconciliationServiceV1 struct {
+	DB          db.Database
+	SaveReports bool // persist every report in the reconciliation_reports table for ops
+}
+
+func NewReconciliationService(dbClient db.Database, saveReports bool) ReconciliationService {
+	return &reconciliationServiceV1{
+		DB:          dbClient,
+		SaveReports: saveReports,
+	}
+}
+
+// Reconcile recomputes the balance of every wallet from its transaction history and compares it with the
+// cached wallets.balance.
+//
+// Every mismatch is logged with the wallet ID and the drift so that ops can investigate it. When reports are
+// enabled, the report and its mismatches are also written to the database.
+//
+// Parameters:
+//   - ctx: Context for managing timeouts and cancellations.
+//
+// Returns:
+//   - The reconciliation report with the number of wallets checked and the mismatching wallets, or an error
+//     if the balances could not be recomputed or the report could not be saved.
+func (r *reconciliationServiceV1) Reconcile(ctx context.Context) (*models.ReconciliationReport, error) {
+	report := &models.ReconciliationReport{StartedAt: time.Now().UTC()}
+	walletsChecked, mismatches, err := r.DB.FindBalanceMismatches(ctx)
+	if err != nil {
+		log.Errorf("balance reconciliation failed: %v", err)
+		return nil, err
+	}
+	report.WalletsChecked = walletsChecked
+	report.Mismatches = mismatches
+	if report.Mismatches == nil {
+		report.Mismatches = []*models.WalletMismatch{}
+	}
+	report.FinishedAt = time.Now().UTC()
+
+	for _, mismatch := range report.Mismatches {
+		log.Errorf("balance mismatch. wallet: %s | currency: %s | balance: %s | expected: %s | drift: %s",
+			mismatch.WalletID, mismatch.Currency, mismatch.Balance, mismatch.ExpectedBalance, mismatch.Drift)
+	}
+	log.Infof("balance reconciliation finished. wallets checked: %d | mismatches: %d", report.WalletsChecked, len(report.Mismatches))
+
+	if r.SaveReports {
+		saved, err := r.DB.SaveReconciliationReport(ctx, report)
+		if err != nil {
+			log.Errorf("failed to save reconciliation report: %v", err)
+			return report, fmt.Errorf("failed to save reconciliation report: %w", err)
+		}
+		report = saved
+	}
+	return report, nil
+}
+
+// RunReconciliationJob reconciles the wallet balances every interval until the context is cancelled.
+// Failures are logged and retried on the next tick.
+func RunReconciliationJob(ctx context.Context, service ReconciliationService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = service.Reconcile(ctx) // errors are logged by the service
+		}
+	}
+}
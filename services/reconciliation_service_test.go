@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"WalletApp/commons"
+	mocks2 "WalletApp/db/mocks"
+	"WalletApp/models"
+)
+
+func TestReconciliationServiceV1_Reconcile(t *testing.T) {
+	mismatch := &models.WalletMismatch{
+		WalletID:        "1234",
+		Currency:        "USD",
+		Balance:         commons.MustMoney("110"),
+		ExpectedBalance: commons.MustMoney("100"),
+		Drift:           commons.MustMoney("10"),
+	}
+
+	t.Run("should return an error if the balances cannot be recomputed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		service := reconciliationServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().FindBalanceMismatches(gomock.Any()).Return(0, nil, errors.New("test db error"))
+		report, err := service.Reconcile(context.Background())
+		assert.Nil(t, report)
+		assert.EqualError(t, err, "test db error")
+	})
+
+	t.Run("should report the mismatching wallets without saving the report", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		service := reconciliationServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().FindBalanceMismatches(gomock.Any()).Return(3, []*models.WalletMismatch{mismatch}, nil)
+		report, err := service.Reconcile(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, 3, report.WalletsChecked)
+		assert.Equal(t, []*models.WalletMismatch{mismatch}, report.Mismatches)
+		assert.False(t, report.FinishedAt.Before(report.StartedAt))
+	})
+
+	t.Run("should return an empty list of mismatches if all balances match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		service := reconciliationServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().FindBalanceMismatches(gomock.Any()).Return(3, nil, nil)
+		report, err := service.Reconcile(context.Background())
+		assert.Nil(t, err)
+		assert.NotNil(t, report.Mismatches)
+		assert.Empty(t, report.Mismatches)
+	})
+
+	t.Run("should save the report if reports are enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		service := reconciliationServiceV1{DB: databaseMock, SaveReports: true}
+		databaseMock.EXPECT().FindBalanceMismatches(gomock.Any()).Return(3, []*models.WalletMismatch{mismatch}, nil)
+		databaseMock.EXPECT().SaveReconciliationReport(gomock.Any(), gomock.Cond(func(report *models.ReconciliationReport) bool {
+			return report.WalletsChecked == 3 && len(report.Mismatches) == 1
+		})).DoAndReturn(func(_ context.Context, report *models.ReconciliationReport) (*models.ReconciliationReport, error) {
+			saved := *report
+			saved.ID = "report-1"
+			return &saved, nil
+		})
+		report, err := service.Reconcile(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, "report-1", report.ID)
+	})
+
+	t.Run("should return an error if the report cannot be saved", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		service := reconciliationServiceV1{DB: databaseMock, SaveReports: true}
+		databaseMock.EXPECT().FindBalanceMismatches(gomock.Any()).Return(3, nil, nil)
+		databaseMock.EXPECT().SaveReconciliationReport(gomock.Any(), gomock.Any()).Return(nil, errors.New("test db error"))
+		report, err := service.Reconcile(context.Background())
+		assert.NotNil(t, report)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRunReconciliationJob(t *testing.T) {
+	t.Run("should reconcile on every tick until the context is cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		service := &reconciliationServiceV1{DB: databaseMock}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		databaseMock.EXPECT().FindBalanceMismatches(gomock.Any()).DoAndReturn(func(context.Context) (int, []*models.WalletMismatch, error) {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+			return 1, nil, nil
+		}).MinTimes(2) // a tick may be ready at the same time as the cancellation
+
+		done := make(chan struct{})
+		go func() {
+			RunReconciliationJob(ctx, service, time.Millisecond)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("reconciliation job did not stop after the context was cancelled")
+		}
+	})
+}
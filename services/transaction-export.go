@@ -0,0 +1,366 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"WalletApp/commons"
+	"WalletApp/models/responses"
+)
+
+// transactionExporter writes a transaction export one transaction at a time, so that exports of long histories
+// are streamed rather than built in memory.
+type transactionExporter interface {
+	begin() error
+	write(tx *responses.TransactionHistory) error
+	end() error
+}
+
+// exportInfo describes the wallet and period of an export. The opening and closing balances are the balances of
+// the wallet right before the start and the end of the period.
+type exportInfo struct {
+	walletID       string
+	currency       string
+	from           time.Time
+	to             time.Time
+	openingBalance commons.Money
+	closingBalance commons.Money
+	generatedAt    time.Time
+}
+
+// exportContentTypes maps the export formats to their content type and file extension.
+var exportContentTypes = map[commons.ExportFormat][2]string{
+	commons.ExportFormatCSV:     {"text/csv; charset=utf-8", "csv"},
+	commons.ExportFormatOFX:     {"application/x-ofx", "ofx"},
+	commons.ExportFormatCAMT053: {"application/xml", "xml"},
+}
+
+func newTransactionExporter(format commons.ExportFormat, w io.Writer, info exportInfo) transactionExporter {
+	switch format {
+	case commons.ExportFormatOFX:
+		return &ofxExporter{xmlExporter: newXMLExporter(w), info: info}
+	case commons.ExportFormatCAMT053:
+		return &camtExporter{xmlExporter: newXMLExporter(w), info: info}
+	default:
+		return &csvExporter{w: csv.NewWriter(w), info: info}
+	}
+}
+
+// formatAmount writes the amount with the decimal places of the currency.
+func formatAmount(amount commons.Money, currency string) string {
+	places, err := commons.CurrencyDecimalPlaces(currency)
+	if err != nil {
+		return amount.String()
+	}
+	return amount.StringFixed(places)
+}
+
+// exportFee returns the fee charged to the wallet, i.e. the fee of the transactions it is debited for.
+func exportFee(tx *responses.TransactionHistory) commons.Money {
+	if tx.Direction == string(commons.TransactionDirectionDebit) {
+		return tx.Fee
+	}
+	return commons.ZeroMoney
+}
+
+type csvExporter struct {
+	w    *csv.Writer
+	info exportInfo
+}
+
+func (e *csvExporter) begin() error {
+	return e.w.Write([]string{"transaction_id", "created_at", "type", "direction", "counterparty_wallet_id", "amount", "fee",
+		"currency", "balance_after", "reversal_of"})
+}
+
+// write records the signed amount of the transaction in the wallet currency, fee included.
+func (e *csvExporter) write(tx *responses.TransactionHistory) error {
+	balanceAfter := ""
+	if tx.BalanceAfter != nil {
+		balanceAfter = formatAmount(*tx.BalanceAfter, e.info.currency)
+	}
+	return e.w.Write([]string{
+		tx.ID,
+		tx.CreatedAt.UTC().Format(time.RFC3339Nano),
+		tx.Type,
+		tx.Direction,
+		tx.CounterpartyWalletID,
+		formatAmount(tx.SignedAmount, e.info.currency),
+		formatAmount(exportFee(tx), e.info.currency),
+		e.info.currency,
+		balanceAfter,
+		tx.ReversalOf,
+	})
+}
+
+func (e *csvExporter) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// xmlExporter streams an XML document, opening the enclosing elements in begin and closing them in end.
+type xmlExporter struct {
+	w    io.Writer
+	enc  *xml.Encoder
+	open []string
+}
+
+func newXMLExporter(w io.Writer) *xmlExporter {
+	return &xmlExporter{w: w, enc: xml.NewEncoder(w)}
+}
+
+func (e *xmlExporter) start(name string, attrs ...xml.Attr) error {
+	e.open = append(e.open, name)
+	return e.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+}
+
+func (e *xmlExporter) element(name string, value any) error {
+	return e.enc.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+func (e *xmlExporter) finish(name string) error {
+	e.open = e.open[:len(e.open)-1]
+	return e.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+}
+
+func (e *xmlExporter) finishAll() error {
+	for len(e.open) > 0 {
+		if err := e.finish(e.open[len(e.open)-1]); err != nil {
+			return err
+		}
+	}
+	return e.enc.Flush()
+}
+
+// ofxExporter writes an OFX 2.2 bank statement. The wallet is the account and every transaction a STMTTRN
+// whose amount is signed from the point of view of the wallet.
+type ofxExporter struct {
+	*xmlExporter
+	info exportInfo
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+func (e *ofxExporter) begin() error {
+	header := xml.Header + `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	if _, err := io.WriteString(e.w, header); err != nil {
+		return err
+	}
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+	steps := []func() error{
+		func() error { return e.start("OFX") },
+		func() error { return e.start("SIGNONMSGSRSV1") },
+		func() error { return e.start("SONRS") },
+		func() error { return e.element("STATUS", ok) },
+		func() error { return e.element("DTSERVER", ofxTime(e.info.generatedAt)) },
+		func() error { return e.element("LANGUAGE", "ENG") },
+		func() error { return e.finish("SONRS") },
+		func() error { return e.finish("SIGNONMSGSRSV1") },
+		func() error { return e.start("BANKMSGSRSV1") },
+		func() error { return e.start("STMTTRNRS") },
+		func() error { return e.element("TRNUID", "0") },
+		func() error { return e.element("STATUS", ok) },
+		func() error { return e.start("STMTRS") },
+		func() error { return e.element("CURDEF", e.info.currency) },
+		func() error {
+			return e.element("BANKACCTFROM", struct {
+				BankID   string `xml:"BANKID"`
+				AcctID   string `xml:"ACCTID"`
+				AcctType string `xml:"ACCTTYPE"`
+			}{"WALLETAPP", e.info.walletID, "CHECKING"})
+		},
+		func() error { return e.start("BANKTRANLIST") },
+		func() error { return e.element("DTSTART", ofxTime(e.info.from)) },
+		func() error { return e.element("DTEND", ofxTime(e.info.to)) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ofxExporter) write(tx *responses.TransactionHistory) error {
+	trnType := "DEBIT"
+	switch commons.TransactionType(tx.Type) {
+	case commons.TransactionTypeDeposit:
+		trnType = "DEP"
+	case commons.TransactionTypeTransfer:
+		trnType = "XFER"
+	}
+	memo := ""
+	if tx.CounterpartyWalletID != "" {
+		memo = "counterparty " + tx.CounterpartyWalletID
+	}
+	if tx.ReversalOf != "" {
+		memo = strings.TrimSpace(memo + " reversal of " + tx.ReversalOf)
+	}
+	return e.element("STMTTRN", ofxTransaction{
+		Type:   trnType,
+		Posted: ofxTime(tx.CreatedAt),
+		Amount: formatAmount(tx.SignedAmount, e.info.currency),
+		FITID:  tx.ID,
+		Name:   tx.Type,
+		Memo:   memo,
+	})
+}
+
+func (e *ofxExporter) end() error {
+	if err := e.finish("BANKTRANLIST"); err != nil {
+		return err
+	}
+	err := e.element("LEDGERBAL", struct {
+		Amount string `xml:"BALAMT"`
+		AsOf   string `xml:"DTASOF"`
+	}{formatAmount(e.info.closingBalance, e.info.currency), ofxTime(e.info.to)})
+	if err != nil {
+		return err
+	}
+	return e.finishAll()
+}
+
+// camtExporter writes an ISO 20022 camt.053.001.08 bank to customer statement with the opening and closing
+// booked balances of the period and one booked entry per transaction.
+type camtExporter struct {
+	*xmlExporter
+	info exportInfo
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDateTime struct {
+	DateTime string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Type      string       `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount   `xml:"Amt"`
+	Indicator string       `xml:"CdtDbtInd"`
+	Date      camtDateTime `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Reference      string       `xml:"NtryRef"`
+	Amount         camtAmount   `xml:"Amt"`
+	Indicator      string       `xml:"CdtDbtInd"`
+	Reversal       bool         `xml:"RvslInd,omitempty"`
+	Status         string       `xml:"Sts>Cd"`
+	BookingDate    camtDateTime `xml:"BookgDt"`
+	ValueDate      camtDateTime `xml:"ValDt"`
+	ServicerRef    string       `xml:"AcctSvcrRef"`
+	BankCode       string       `xml:"BkTxCd>Prtry>Cd"`
+	AdditionalInfo string       `xml:"AddtlNtryInf,omitempty"`
+}
+
+// camtReference shortens a UUID to the 35 characters allowed for camt.053 identifiers.
+func camtReference(id string) string {
+	return strings.ReplaceAll(id, "-", "")
+}
+
+func camtTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (e *camtExporter) amount(amount commons.Money) (camtAmount, string) {
+	indicator := "CRDT"
+	if amount.IsNegative() {
+		amount, indicator = amount.Neg(), "DBIT"
+	}
+	return camtAmount{Currency: e.info.currency, Value: formatAmount(amount, e.info.currency)}, indicator
+}
+
+func (e *camtExporter) balance(code string, amount commons.Money, at time.Time) error {
+	amt, indicator := e.amount(amount)
+	return e.element("Bal", camtBalance{Type: code, Amount: amt, Indicator: indicator, Date: camtDateTime{camtTime(at)}})
+}
+
+func (e *camtExporter) begin() error {
+	if _, err := io.WriteString(e.w, xml.Header); err != nil {
+		return err
+	}
+	statementID := camtReference(e.info.walletID)[:8] + e.info.from.UTC().Format("20060102") + e.info.to.UTC().Format("20060102")
+	created := camtTime(e.info.generatedAt)
+	steps := []func() error{
+		func() error {
+			return e.start("Document", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: "urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"})
+		},
+		func() error { return e.start("BkToCstmrStmt") },
+		func() error {
+			return e.element("GrpHdr", struct {
+				MsgID   string `xml:"MsgId"`
+				Created string `xml:"CreDtTm"`
+			}{statementID + e.info.generatedAt.UTC().Format("150405"), created})
+		},
+		func() error { return e.start("Stmt") },
+		func() error { return e.element("Id", statementID) },
+		func() error { return e.element("CreDtTm", created) },
+		func() error {
+			return e.element("FrToDt", struct {
+				From string `xml:"FrDtTm"`
+				To   string `xml:"ToDtTm"`
+			}{camtTime(e.info.from), camtTime(e.info.to)})
+		},
+		func() error {
+			return e.element("Acct", struct {
+				ID       string `xml:"Id>Othr>Id"`
+				Currency string `xml:"Ccy"`
+			}{e.info.walletID, e.info.currency})
+		},
+		func() error { return e.balance("OPBD", e.info.openingBalance, e.info.from) },
+		func() error { return e.balance("CLBD", e.info.closingBalance, e.info.to) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *camtExporter) write(tx *responses.TransactionHistory) error {
+	amount, indicator := e.amount(tx.SignedAmount)
+	info := ""
+	if tx.CounterpartyWalletID != "" {
+		info = "counterparty " + tx.CounterpartyWalletID
+	}
+	booked := camtDateTime{camtTime(tx.CreatedAt)}
+	return e.element("Ntry", camtEntry{
+		Reference:      camtReference(tx.ID),
+		Amount:         amount,
+		Indicator:      indicator,
+		Reversal:       tx.ReversalOf != "",
+		Status:         "BOOK",
+		BookingDate:    booked,
+		ValueDate:      booked,
+		ServicerRef:    camtReference(tx.ID),
+		BankCode:       tx.Type,
+		AdditionalInfo: info,
+	})
+}
+
+func (e *camtExporter) end() error {
+	return e.finishAll()
+}
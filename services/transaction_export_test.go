@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"WalletApp/commons"
+	mocks2 "WalletApp/db/mocks"
+	"WalletApp/models"
+	"WalletApp/models/responses"
+)
+
+func TestWalletServiceV1_ExportTransactions(t *testing.T) {
+	walletID := "7dbacf5d-3099-4a66-ad3d-2fee93970017"
+	userID := "2222"
+	periodStart := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	opening, closing := commons.MustMoney("100"), commons.MustMoney("129.5")
+	transactions := []*models.Transaction{
+		{ID: "8f1b3c52-7f3e-4d4b-9a51-3c0f1c0b6e11", WalletID: walletID, Type: "deposit", Amount: commons.MustMoney("50"), Currency: "EUR",
+			CreatedAt: periodStart.Add(time.Hour)},
+		{ID: "2c1f0e8a-4b59-4d0c-8f0e-7c3b1a2d9e44", WalletID: walletID, ToWalletID: sql.NullString{String: "2cbcd158-56d2-4d45-8113-d51adf9ef57a", Valid: true},
+			Type: "transfer", Amount: commons.MustMoney("20"), Fee: commons.MustMoney("0.5"), Currency: "EUR", CreatedAt: periodStart.Add(2 * time.Hour)},
+	}
+
+	// export prepares the export with a database returning the balances and streaming the transactions
+	export := func(t *testing.T, format commons.ExportFormat) string {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "EUR"}, nil)
+		databaseMock.EXPECT().GetLatestWalletPosting(gomock.Any(), walletID, periodStart.Add(-time.Microsecond)).Return(&models.Posting{BalanceAfter: &opening}, nil)
+		databaseMock.EXPECT().GetLatestWalletPosting(gomock.Any(), walletID, periodEnd.Add(-time.Microsecond)).Return(&models.Posting{BalanceAfter: &closing}, nil)
+		databaseMock.EXPECT().StreamTransactions(gomock.Any(), walletID, gomock.Cond(func(filter *models.TransactionFilter) bool {
+			return filter.From.Equal(periodStart) && filter.To.Equal(periodEnd) && filter.Ascending
+		}), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, _ *models.TransactionFilter, fn func(*models.Transaction) error) error {
+			for _, tx := range transactions {
+				if err := fn(tx); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		resp := walletService.ExportTransactions(context.Background(), walletID, userID, format, periodStart, periodEnd)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		data, ok := resp.Data.(responses.ExportResponse)
+		if !assert.True(t, ok, "cannot cast the response") {
+			return ""
+		}
+		var out bytes.Buffer
+		assert.NoError(t, data.Write(context.Background(), &out))
+		return out.String()
+	}
+
+	t.Run("should return bad request response for an unknown format", func(t *testing.T) {
+		walletService := walletServiceV1{}
+		resp := walletService.ExportTransactions(context.Background(), walletID, userID, "xlsx", periodStart, periodEnd)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+	})
+
+	t.Run("should return unauthorized response if the user does not own the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(false, nil)
+		resp := walletService.ExportTransactions(context.Background(), walletID, userID, commons.ExportFormatCSV, periodStart, periodEnd)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	})
+
+	t.Run("should export signed amounts in the wallet currency as CSV", func(t *testing.T) {
+		records, err := csv.NewReader(strings.NewReader(export(t, commons.ExportFormatCSV))).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 3)
+		assert.Equal(t, "transaction_id", records[0][0])
+		assert.Equal(t, []string{transactions[0].ID, "2026-09-01T01:00:00Z", "deposit", "credit", "", "50.00", "0.00", "EUR", "", ""}, records[1])
+		assert.Equal(t, "-20.50", records[2][5])
+		assert.Equal(t, "0.50", records[2][6])
+		assert.Equal(t, "2cbcd158-56d2-4d45-8113-d51adf9ef57a", records[2][4])
+	})
+
+	t.Run("should export an OFX bank statement with the closing balance", func(t *testing.T) {
+		out := export(t, commons.ExportFormatOFX)
+		assert.True(t, strings.Contains(out, `<?OFX OFXHEADER="200" VERSION="220"`))
+		var ofx struct {
+			Currency     string `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>CURDEF"`
+			Account      string `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKACCTFROM>ACCTID"`
+			Transactions []struct {
+				Type   string `xml:"TRNTYPE"`
+				Amount string `xml:"TRNAMT"`
+				FITID  string `xml:"FITID"`
+			} `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>STMTTRN"`
+			Balance string `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>LEDGERBAL>BALAMT"`
+		}
+		assert.NoError(t, xml.Unmarshal([]byte(out), &ofx))
+		assert.Equal(t, "EUR", ofx.Currency)
+		assert.Equal(t, walletID, ofx.Account)
+		assert.Len(t, ofx.Transactions, 2)
+		assert.Equal(t, "DEP", ofx.Transactions[0].Type)
+		assert.Equal(t, "XFER", ofx.Transactions[1].Type)
+		assert.Equal(t, "-20.50", ofx.Transactions[1].Amount)
+		assert.Equal(t, transactions[1].ID, ofx.Transactions[1].FITID)
+		assert.Equal(t, "129.50", ofx.Balance)
+	})
+
+	t.Run("should export a camt.053 statement with opening and closing balances", func(t *testing.T) {
+		var document struct {
+			XMLName   xml.Name
+			Statement struct {
+				ID       string `xml:"Id"`
+				Balances []struct {
+					Type   string `xml:"Tp>CdOrPrtry>Cd"`
+					Amount string `xml:"Amt"`
+				} `xml:"Bal"`
+				Entries []struct {
+					Reference string `xml:"NtryRef"`
+					Amount    struct {
+						Currency string `xml:"Ccy,attr"`
+						Value    string `xml:",chardata"`
+					} `xml:"Amt"`
+					Indicator string `xml:"CdtDbtInd"`
+				} `xml:"Ntry"`
+			} `xml:"BkToCstmrStmt>Stmt"`
+		}
+		assert.NoError(t, xml.Unmarshal([]byte(export(t, commons.ExportFormatCAMT053)), &document))
+		assert.Equal(t, "urn:iso:std:iso:20022:tech:xsd:camt.053.001.08", document.XMLName.Space)
+		assert.LessOrEqual(t, len(document.Statement.ID), 35)
+		assert.Len(t, document.Statement.Balances, 2)
+		assert.Equal(t, "OPBD", document.Statement.Balances[0].Type)
+		assert.Equal(t, "100.00", document.Statement.Balances[0].Amount)
+		assert.Equal(t, "CLBD", document.Statement.Balances[1].Type)
+		assert.Equal(t, "129.50", document.Statement.Balances[1].Amount)
+		assert.Len(t, document.Statement.Entries, 2)
+		assert.Equal(t, "8f1b3c527f3e4d4b9a513c0f1c0b6e11", document.Statement.Entries[0].Reference)
+		assert.Equal(t, "CRDT", document.Statement.Entries[0].Indicator)
+		assert.Equal(t, "DBIT", document.Statement.Entries[1].Indicator)
+		assert.Equal(t, "20.50", document.Statement.Entries[1].Amount.Value)
+		assert.Equal(t, "EUR", document.Statement.Entries[1].Amount.Currency)
+	})
+}
@@ -2,20 +2,40 @@ package services
 
 import (
 	"context"
+	"time"
 
+	"WalletApp/commons"
+	"WalletApp/models"
 	"WalletApp/models/responses"
 )
 
 //go:generate mockgen -source=types.go -destination=mocks/types.go -package=mocks
 type WalletService interface {
-	Deposit(ctx context.Context, idempotencyKey string, toAccount string, amount float64, userID string) responses.Response
-	Withdraw(ctx context.Context, idempotencyKey string, fromAccount string, amount float64, userID string) responses.Response
-	Transfer(ctx context.Context, idempotencyKey string, toAccount string, fromAccount string, amount float64, userID string) responses.Response
+	Deposit(ctx context.Context, idempotencyKey string, toAccount string, amount commons.Money, userID string) responses.Response
+	Withdraw(ctx context.Context, idempotencyKey string, fromAccount string, amount commons.Money, userID string) responses.Response
+	Transfer(ctx context.Context, idempotencyKey string, toAccount string, fromAccount string, amount commons.Money, userID string) responses.Response
+	CreateQuote(ctx context.Context, walletID string, toAccount string, trsType commons.TransactionType, amount commons.Money, userID string) responses.Response
+	ExecuteQuote(ctx context.Context, idempotencyKey string, walletID string, quoteID string, trsType commons.TransactionType, userID string) responses.Response
+	CreateHold(ctx context.Context, idempotencyKey string, walletID string, toAccount string, amount commons.Money, ttl time.Duration, userID string) responses.Response
+	CaptureHold(ctx context.Context, idempotencyKey string, walletID string, holdID string, amount commons.Money, userID string) responses.Response
+	VoidHold(ctx context.Context, idempotencyKey string, walletID string, holdID string, userID string) responses.Response
+	ReverseTransaction(ctx context.Context, idempotencyKey string, walletID string, txnID string, amount commons.Money, userID string) responses.Response
+	GetTransaction(ctx context.Context, walletID string, txnID string, userID string) responses.Response
+	GetOperation(ctx context.Context, walletID string, idempotencyToken string, userID string) responses.Response
 	GetBalance(ctx context.Context, walletID string, userID string) responses.Response
-	GetTransactionHistory(ctx context.Context, walletID string, userID string, limit int32, offset int32) responses.Response
+	GetBalanceAsOf(ctx context.Context, walletID string, userID string, asOf time.Time) responses.Response
+	GetTransactionHistory(ctx context.Context, walletID string, userID string, filter *models.TransactionFilter, limit int32, offset int32) responses.Response
+	GetStatement(ctx context.Context, walletID string, userID string, periodStart time.Time, periodEnd time.Time) responses.Response
+	ExportTransactions(ctx context.Context, walletID string, userID string, format commons.ExportFormat, periodStart time.Time, periodEnd time.Time) responses.Response
+	CreateWallet(ctx context.Context, userID string, label string, currency string) responses.Response
+	GetWallets(ctx context.Context, userID string) responses.Response
+}
+
+type ReconciliationService interface {
+	Reconcile(ctx context.Context) (*models.ReconciliationReport, error)
 }
 
 type UserService interface {
-	CreateUser(ctx context.Context) responses.Response
+	CreateUser(ctx context.Context, currency string) responses.Response
 	GetUsers(ctx context.Context) responses.Response
 }
@@ -26,13 +26,14 @@ func NewUserService(dbClient db.Database) UserService {
 //
 // Parameters:
 //   - ctx: Context for managing request lifecycle, including timeout and cancellation.
+//   - currency: ISO 4217 currency code of the wallet. Must be a supported currency.
 //
 // Returns:
 //   - A Response struct containing the created user's wallet information (user ID, wallet ID,
 //     and creation timestamp), a success message, HTTP status code 201, or an internal
 //     error response if the operation fails.
-func (u userServiceV1) CreateUser(ctx context.Context) responses.Response {
-	wallet, err := u.DB.CreateUserWallet(ctx)
+func (u userServiceV1) CreateUser(ctx context.Context, currency string) responses.Response {
+	wallet, err := u.DB.CreateUserWallet(ctx, currency)
 	if err != nil || wallet == nil {
 		return internalError("failed to create user and wallet", err)
 	}
@@ -41,6 +42,7 @@ func (u userServiceV1) CreateUser(ctx context.Context) responses.Response {
 			{
 				UserID:    wallet.UserID,
 				WalletID:  wallet.ID,
+				Currency:  wallet.Currency,
 				CreatedAt: wallet.CreatedAt,
 			},
 		},
@@ -72,6 +74,7 @@ func (u userServiceV1) GetUsers(ctx context.Context) responses.Response {
 		userWallets = append(userWallets, &responses.UserWallet{
 			UserID:    res.UserID,
 			WalletID:  res.ID,
+			Currency:  res.Currency,
 			CreatedAt: res.CreatedAt,
 		})
 	}
@@ -2,32 +2,42 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2/log"
 
 	"WalletApp/commons"
 	"WalletApp/db"
+	"WalletApp/models"
 	"WalletApp/models/responses"
 )
 
 type walletServiceV1 struct {
-	DB    db.Database
-	Cache db.Cache
+	DB                   db.Database
+	Idempotency          db.IdempotencyStore
+	IdempotencyRetention time.Duration   // commons.IdempotencyResponseTTL when not set
+	Rates                db.RateProvider // cross-currency transfers are rejected when no rate provider is configured
 }
 
-func NewWalletServiceV1(dbClient db.Database, cacheClient db.Cache) WalletService {
+func NewWalletServiceV1(dbClient db.Database, idempotencyStore db.IdempotencyStore, idempotencyRetention time.Duration, rateProvider db.RateProvider) WalletService {
 	return &walletServiceV1{
-		DB:    dbClient,
-		Cache: cacheClient,
+		DB:                   dbClient,
+		Idempotency:          idempotencyStore,
+		IdempotencyRetention: idempotencyRetention,
+		Rates:                rateProvider,
 	}
 }
 
 // Deposit performs a deposit transaction into the specified wallet.
 //
-// It first validates whether the requesting user is authorized to access the wallet.
+// It first validates whether the requesting user is authorized to access the wallet and that the
+// amount precision is valid for the wallet currency.
 // Then it enforces idempotency using a cache-based key to prevent duplicate deposits.
 // If the request is valid and not a duplicate, it inserts a deposit transaction and returns the updated balance.
 //
@@ -40,7 +50,7 @@ func NewWalletServiceV1(dbClient db.Database, cacheClient db.Cache) WalletServic
 //
 // Returns:
 //   - A Response struct containing the updated balance, message, HTTP status code, and any error encountered.
-func (v *walletServiceV1) Deposit(ctx context.Context, idempotencyKey, account string, amount float64, userID string) responses.Response {
+func (v *walletServiceV1) Deposit(ctx context.Context, idempotencyKey, account string, amount commons.Money, userID string) responses.Response {
 	isAuthorized, err := v.isAuthorized(ctx, account, userID)
 	if err != nil {
 		return internalError("error while checking wallet ownership", err)
@@ -49,29 +59,42 @@ func (v *walletServiceV1) Deposit(ctx context.Context, idempotencyKey, account s
 		return unauthorizedResponse()
 	}
 
-	isDuplicateRequest, err := v.setIdempotency(ctx, idempotencyKey, commons.TransactionTypeDeposit, userID, account, amount)
+	wallet, err := v.DB.GetWallet(ctx, account)
 	if err != nil {
-		return internalError("error while checking idempotency for deposit action", err)
+		return internalError("failed to fetch the wallet", err)
 	}
-	if isDuplicateRequest {
-		return duplicateRequestResponse()
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+	if err := commons.ValidateAmountPrecision(amount, wallet.Currency); err != nil {
+		return badRequest(err.Error())
 	}
 
-	updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, account, "", amount, commons.TransactionTypeDeposit)
-	if err != nil {
-		if errors.Is(err, commons.InsufficientBalanceError) {
-			return badRequest(err.Error())
+	return v.idempotent(ctx, idempotencyKey, string(commons.TransactionTypeDeposit), userID, account, "", amount, func(key *models.IdempotencyKey) responses.Response {
+		txn := &models.Transaction{
+			WalletID: account,
+			Type:     string(commons.TransactionTypeDeposit),
+			Amount:   amount,
+			Currency: wallet.Currency,
+		}
+		txn.IdempotencyKey = key
+		updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, txn)
+		if err != nil {
+			if errors.Is(err, commons.InsufficientBalanceError) {
+				return badRequest(err.Error())
+			}
+			return internalError("failed to record the deposit transaction", err)
 		}
-		return internalError("failed to record the deposit transaction", err)
-	}
 
-	return successBalanceResponse(account, updatedBalance, "deposit successful")
+		return successBalanceResponse(account, txn.ID, updatedBalance, wallet.Currency, "deposit successful")
+	})
 }
 
 // Withdraw performs a withdrawal transaction from the specified wallet.
 //
-// It verifies that the user is authorized to access the wallet, ensures the request is not a duplicate
-// using an idempotency key, and checks whether the wallet has sufficient balance for the withdrawal.
+// It verifies that the user is authorized to access the wallet, validates the amount precision for the
+// wallet currency, ensures the request is not a duplicate using an idempotency key, and checks whether
+// the wallet has sufficient balance for the withdrawal.
 // If all checks pass, it records the withdrawal transaction and returns the updated wallet balance.
 //
 // Parameters:
@@ -84,7 +107,7 @@ func (v *walletServiceV1) Deposit(ctx context.Context, idempotencyKey, account s
 // Returns:
 //   - A Response struct containing the updated balance (if successful), a status message,
 //     HTTP status code, and any error that occurred during the operation.
-func (v *walletServiceV1) Withdraw(ctx context.Context, idempotencyKey, account string, amount float64, userID string) responses.Response {
+func (v *walletServiceV1) Withdraw(ctx context.Context, idempotencyKey, account string, amount commons.Money, userID string) responses.Response {
 	isAuthorized, err := v.isAuthorized(ctx, account, userID)
 	if err != nil {
 		return internalError("error while checking wallet ownership", err)
@@ -93,31 +116,45 @@ func (v *walletServiceV1) Withdraw(ctx context.Context, idempotencyKey, account
 		return unauthorizedResponse()
 	}
 
-	isDuplicateRequest, err := v.setIdempotency(ctx, idempotencyKey, commons.TransactionTypeWithdraw, userID, account, amount)
+	wallet, err := v.DB.GetWallet(ctx, account)
 	if err != nil {
-		return internalError("error while checking idempotency for deposit action", err)
+		return internalError("failed to fetch the wallet", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
 	}
-	if isDuplicateRequest {
-		return duplicateRequestResponse()
+	if err := commons.ValidateAmountPrecision(amount, wallet.Currency); err != nil {
+		return badRequest(err.Error())
 	}
 
-	updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, account, "", amount, commons.TransactionTypeWithdraw)
-	if err != nil {
-		if errors.Is(err, commons.InsufficientBalanceError) {
-			return badRequest(err.Error())
+	return v.idempotent(ctx, idempotencyKey, string(commons.TransactionTypeWithdraw), userID, account, "", amount, func(key *models.IdempotencyKey) responses.Response {
+		txn := &models.Transaction{
+			WalletID: account,
+			Type:     string(commons.TransactionTypeWithdraw),
+			Amount:   amount,
+			Currency: wallet.Currency,
+		}
+		txn.IdempotencyKey = key
+		updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, txn)
+		if err != nil {
+			if errors.Is(err, commons.InsufficientBalanceError) {
+				return badRequest(err.Error())
+			}
+			return internalError("failed to perform the withdrawal transaction", err)
 		}
-		return internalError("failed to perform the withdrawal transaction", err)
-	}
 
-	return successBalanceResponse(account, updatedBalance, "withdrawal successful")
+		return successBalanceResponse(account, txn.ID, updatedBalance, wallet.Currency, "withdrawal successful")
+	})
 }
 
 // Transfer performs a fund transfer from one wallet to another.
 //
-// It ensures the user is authorized to use the source wallet, prevents duplicate requests
-// using an idempotency key, verifies sufficient balance in the source wallet, and confirms
-// that the recipient wallet exists. If all validations pass, it records the transfer transaction
-// and returns the updated balance of the source wallet.
+// It ensures the user is authorized to use the source wallet, validates the amount precision for the
+// source wallet currency, prevents duplicate requests using an idempotency key, verifies sufficient
+// balance in the source wallet, and confirms that the recipient wallet exists. When the recipient
+// wallet holds a different currency, the amount is converted using the configured rate provider and
+// the applied rate and fee are recorded with the transaction. If all validations pass, it records the transfer
+// transaction and returns the updated balance of the source wallet.
 //
 // Parameters:
 //   - ctx: Context for managing request lifetime and enforcing timeouts or cancellation.
@@ -130,7 +167,7 @@ func (v *walletServiceV1) Withdraw(ctx context.Context, idempotencyKey, account
 // Returns:
 //   - A Response struct containing the updated balance (if successful), a status message,
 //     HTTP status code, and any error that occurred during the operation.
-func (v *walletServiceV1) Transfer(ctx context.Context, idempotencyKey, fromAccount, toAccount string, amount float64, userID string) responses.Response {
+func (v *walletServiceV1) Transfer(ctx context.Context, idempotencyKey, fromAccount, toAccount string, amount commons.Money, userID string) responses.Response {
 	if fromAccount == toAccount {
 		return badRequest("cannot transfer between same accounts")
 	}
@@ -142,36 +179,368 @@ func (v *walletServiceV1) Transfer(ctx context.Context, idempotencyKey, fromAcco
 		return unauthorizedResponse()
 	}
 
-	isDuplicateRequest, err := v.setIdempotency(ctx, idempotencyKey, commons.TransactionTypeTransfer, userID, fmt.Sprintf("%s-%s", fromAccount, toAccount), amount)
+	wallet, err := v.DB.GetWallet(ctx, fromAccount)
 	if err != nil {
-		return internalError("error while checking idempotency for transfer action", err)
+		return internalError("failed to fetch the wallet", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
 	}
-	if isDuplicateRequest {
-		return duplicateRequestResponse()
+	if err := commons.ValidateAmountPrecision(amount, wallet.Currency); err != nil {
+		return badRequest(err.Error())
 	}
 
-	if exists, err := v.walletExists(ctx, toAccount); err != nil {
-		return internalError("failed to verify recipient wallet", err)
-	} else if !exists {
-		return badRequest("recipient wallet id does not exist")
+	return v.idempotent(ctx, idempotencyKey, string(commons.TransactionTypeTransfer), userID, fromAccount, toAccount, amount, func(key *models.IdempotencyKey) responses.Response {
+		recipient, err := v.DB.GetWallet(ctx, toAccount)
+		if err != nil {
+			return internalError("failed to verify recipient wallet", err)
+		}
+		if recipient == nil {
+			return badRequest("recipient wallet id does not exist")
+		}
+
+		txn := &models.Transaction{
+			WalletID:   fromAccount,
+			ToWalletID: sql.NullString{String: toAccount, Valid: true},
+			Type:       string(commons.TransactionTypeTransfer),
+			Amount:     amount,
+			Currency:   wallet.Currency,
+		}
+		if resp := v.priceTransaction(ctx, txn, recipient.Currency); resp != nil {
+			return *resp
+		}
+
+		txn.IdempotencyKey = key
+		updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, txn)
+		if err != nil {
+			if errors.Is(err, commons.InsufficientBalanceError) {
+				return badRequest(err.Error())
+			}
+			return internalError("failed to record the transfer transaction", err)
+		}
+
+		return successBalanceResponse(fromAccount, txn.ID, updatedBalance, wallet.Currency, "transfer successful")
+	})
+}
+
+// CreateQuote prices a prospective withdrawal or transfer without moving any money.
+//
+// It validates the request the same way as Withdraw and Transfer, computes the fee and, for
+// cross-currency transfers, the converted amount using the current exchange rate. The priced
+// outcome is persisted as a quote that expires after commons.QuoteTTL and can be executed
+// through ExecuteQuote so that the executed transaction matches the quote exactly.
+//
+// Parameters:
+//   - ctx: Context for managing request lifetime and enforcing timeouts or cancellation.
+//   - walletID: Source wallet ID from which the funds would be deducted.
+//   - toAccount: Destination wallet ID for transfers. Ignored for withdrawals.
+//   - trsType: Type of the prospective transaction (withdrawal or transfer).
+//   - amount: Amount to withdraw or transfer, excluding fees (must be > 0).
+//   - userID: ID of the user requesting the quote.
+//
+// Returns:
+//   - A Response struct containing the quote (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) CreateQuote(ctx context.Context, walletID, toAccount string, trsType commons.TransactionType, amount commons.Money, userID string) responses.Response {
+	if trsType == commons.TransactionTypeTransfer && walletID == toAccount {
+		return badRequest("cannot transfer between same accounts")
+	}
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
 	}
 
-	updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, fromAccount, toAccount, amount, commons.TransactionTypeTransfer)
+	wallet, err := v.DB.GetWallet(ctx, walletID)
 	if err != nil {
-		if errors.Is(err, commons.InsufficientBalanceError) {
-			return badRequest(err.Error())
+		return internalError("failed to fetch the wallet", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+	if err := commons.ValidateAmountPrecision(amount, wallet.Currency); err != nil {
+		return badRequest(err.Error())
+	}
+
+	txn := &models.Transaction{
+		WalletID: walletID,
+		Type:     string(trsType),
+		Amount:   amount,
+		Currency: wallet.Currency,
+	}
+	destCurrency := wallet.Currency
+	if trsType == commons.TransactionTypeTransfer {
+		recipient, err := v.DB.GetWallet(ctx, toAccount)
+		if err != nil {
+			return internalError("failed to verify recipient wallet", err)
 		}
-		return internalError("failed to record the transfer transaction", err)
+		if recipient == nil {
+			return badRequest("recipient wallet id does not exist")
+		}
+		txn.ToWalletID = sql.NullString{String: toAccount, Valid: true}
+		destCurrency = recipient.Currency
+	}
+	if resp := v.priceTransaction(ctx, txn, destCurrency); resp != nil {
+		return *resp
+	}
+
+	quote, err := v.DB.CreateQuote(ctx, &models.Quote{
+		WalletID:     txn.WalletID,
+		ToWalletID:   txn.ToWalletID,
+		Type:         txn.Type,
+		Amount:       txn.Amount,
+		Currency:     txn.Currency,
+		Fee:          txn.Fee,
+		DestAmount:   txn.DestAmount,
+		DestCurrency: txn.DestCurrency,
+		FXRate:       txn.FXRate,
+		FXRateAt:     txn.FXRateAt,
+	})
+	if err != nil {
+		return internalError("failed to create the quote", err)
 	}
 
-	return successBalanceResponse(fromAccount, updatedBalance, "transfer successful")
+	return getResponse(quoteResponse(quote), "quote created successfully", http.StatusCreated, nil)
+}
+
+// ExecuteQuote executes a previously created withdrawal or transfer quote.
+//
+// It verifies that the user is authorized to access the wallet, that the quote belongs to the wallet
+// and matches the requested transaction type, and prevents duplicate requests using an idempotency key.
+// The transaction is recorded with exactly the quoted amounts, fee and rate. Execution fails when the
+// quote has expired or has already been executed.
+//
+// Parameters:
+//   - ctx: Context for managing request lifetime and enforcing timeouts or cancellation.
+//   - idempotencyKey: Unique key provided by the client to prevent duplicate executions.
+//   - walletID: Source wallet ID of the quote.
+//   - quoteID: ID of the quote to execute.
+//   - trsType: Transaction type expected by the caller (withdrawal or transfer).
+//   - userID: ID of the user executing the quote.
+//
+// Returns:
+//   - A Response struct containing the updated balance (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) ExecuteQuote(ctx context.Context, idempotencyKey, walletID, quoteID string, trsType commons.TransactionType, userID string) responses.Response {
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	quote, err := v.DB.GetQuote(ctx, quoteID)
+	if err != nil {
+		return internalError("failed to fetch the quote", err)
+	}
+	if quote == nil || quote.WalletID != walletID {
+		return badRequest("quote does not exist")
+	}
+	if commons.TransactionType(quote.Type) != trsType {
+		return badRequest(fmt.Sprintf("quote is not for a %s", trsType))
+	}
+
+	return v.idempotent(ctx, idempotencyKey, string(trsType), userID, walletID, quoteID, quote.Amount, func(key *models.IdempotencyKey) responses.Response {
+		txn := quote.Transaction()
+		txn.IdempotencyKey = key
+		updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, txn)
+		if err != nil {
+			if errors.Is(err, commons.InsufficientBalanceError) || errors.Is(err, commons.QuoteExpiredError) || errors.Is(err, commons.QuoteAlreadyExecutedError) {
+				return badRequest(err.Error())
+			}
+			return internalError(fmt.Sprintf("failed to record the %s transaction", trsType), err)
+		}
+
+		return successBalanceResponse(walletID, txn.ID, updatedBalance, quote.Currency, fmt.Sprintf("%s successful", trsType))
+	})
+}
+
+// CreateHold reserves funds of a wallet without debiting them.
+//
+// It verifies that the user is authorized to use the wallet, validates the amount precision for the
+// wallet currency and, when a recipient is given, that the recipient wallet exists. The held amount is
+// deducted from the available balance until the hold is captured, voided or expires.
+//
+// Parameters:
+//   - ctx: Context for managing request lifetime and enforcing timeouts or cancellation.
+//   - idempotencyKey: Unique key provided by the client to prevent duplicate holds.
+//   - walletID: Wallet ID whose funds are reserved.
+//   - toAccount: Optional recipient wallet ID. The hold is captured as a transfer to this wallet when set,
+//     otherwise as a withdrawal.
+//   - amount: Amount to reserve (must be > 0).
+//   - ttl: Duration after which the hold expires and its funds are released.
+//   - userID: ID of the user creating the hold.
+//
+// Returns:
+//   - A Response struct containing the created hold (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) CreateHold(ctx context.Context, idempotencyKey, walletID, toAccount string, amount commons.Money, ttl time.Duration, userID string) responses.Response {
+	if walletID == toAccount {
+		return badRequest("cannot hold funds for the same account")
+	}
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	wallet, err := v.DB.GetWallet(ctx, walletID)
+	if err != nil {
+		return internalError("failed to fetch the wallet", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+	if err := commons.ValidateAmountPrecision(amount, wallet.Currency); err != nil {
+		return badRequest(err.Error())
+	}
+	if toAccount != "" {
+		recipient, err := v.DB.GetWallet(ctx, toAccount)
+		if err != nil {
+			return internalError("failed to verify recipient wallet", err)
+		}
+		if recipient == nil {
+			return badRequest("recipient wallet id does not exist")
+		}
+	}
+
+	return v.idempotent(ctx, idempotencyKey, string(commons.OperationActionHold), userID, walletID, toAccount, amount, func(key *models.IdempotencyKey) responses.Response {
+		hold, err := v.DB.CreateHold(ctx, &models.Hold{
+			WalletID:       walletID,
+			ToWalletID:     sql.NullString{String: toAccount, Valid: toAccount != ""},
+			Amount:         amount,
+			Currency:       wallet.Currency,
+			IdempotencyKey: key,
+		}, ttl)
+		if err != nil {
+			if errors.Is(err, commons.InsufficientBalanceError) {
+				return badRequest(err.Error())
+			}
+			return internalError("failed to create the hold", err)
+		}
+		return getResponse(holdResponse(hold), "hold created successfully", http.StatusCreated, nil)
+	})
+}
+
+// CaptureHold settles an active hold as a withdrawal, or as a transfer when the hold has a recipient.
+//
+// The captured amount defaults to the full held amount and can be lower for a partial capture. The
+// remainder of the hold is released. Transfers to a wallet of another currency are converted and charged
+// like regular transfers.
+//
+// Parameters:
+//   - ctx: Context for managing request lifetime and enforcing timeouts or cancellation.
+//   - idempotencyKey: Unique key provided by the client to prevent duplicate captures.
+//   - walletID: Wallet ID of the hold.
+//   - holdID: ID of the hold to capture.
+//   - amount: Amount to capture. Zero captures the full held amount.
+//   - userID: ID of the user capturing the hold.
+//
+// Returns:
+//   - A Response struct containing the updated balance (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) CaptureHold(ctx context.Context, idempotencyKey, walletID, holdID string, amount commons.Money, userID string) responses.Response {
+	hold, resp := v.getHold(ctx, walletID, holdID, userID)
+	if resp != nil {
+		return *resp
+	}
+	if amount.GreaterThan(hold.Amount) {
+		return badRequest(commons.HoldAmountExceededError.Error())
+	}
+	if err := commons.ValidateAmountPrecision(amount, hold.Currency); err != nil {
+		return badRequest(err.Error())
+	}
+
+	// The state of the hold is checked under the idempotency key, so that a retry of a completed capture gets the
+	// stored response instead of "hold is not active"
+	return v.idempotent(ctx, idempotencyKey, string(commons.OperationActionCapture), userID, walletID, holdID, amount, func(key *models.IdempotencyKey) responses.Response {
+		if resp := checkHoldActive(hold); resp != nil {
+			return *resp
+		}
+		captured := amount
+		if captured.IsZero() {
+			captured = hold.Amount
+		}
+		trsType := hold.TransactionType()
+		txn := &models.Transaction{
+			WalletID:   walletID,
+			ToWalletID: hold.ToWalletID,
+			Type:       string(trsType),
+			Amount:     captured,
+			Currency:   hold.Currency,
+			HoldID:     sql.NullString{String: holdID, Valid: true},
+		}
+		if trsType == commons.TransactionTypeTransfer {
+			recipient, err := v.DB.GetWallet(ctx, hold.ToWalletID.String)
+			if err != nil {
+				return internalError("failed to verify recipient wallet", err)
+			}
+			if recipient == nil {
+				return badRequest("recipient wallet id does not exist")
+			}
+			if resp := v.priceTransaction(ctx, txn, recipient.Currency); resp != nil {
+				return *resp
+			}
+		}
+
+		txn.IdempotencyKey = key
+		updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, txn)
+		if err != nil {
+			if errors.Is(err, commons.InsufficientBalanceError) || errors.Is(err, commons.HoldNotActiveError) ||
+				errors.Is(err, commons.HoldExpiredError) || errors.Is(err, commons.HoldAmountExceededError) {
+				return badRequest(err.Error())
+			}
+			return internalError(fmt.Sprintf("failed to record the %s transaction", trsType), err)
+		}
+
+		return successBalanceResponse(walletID, txn.ID, updatedBalance, hold.Currency, "hold captured successfully")
+	})
+}
+
+// VoidHold releases an active hold without moving any money.
+//
+// Parameters:
+//   - ctx: Context for managing request lifetime and enforcing timeouts or cancellation.
+//   - idempotencyKey: Unique key provided by the client to prevent a retry from failing once the hold is voided.
+//   - walletID: Wallet ID of the hold.
+//   - holdID: ID of the hold to void.
+//   - userID: ID of the user voiding the hold.
+//
+// Returns:
+//   - A Response struct containing the voided hold (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) VoidHold(ctx context.Context, idempotencyKey, walletID, holdID, userID string) responses.Response {
+	hold, resp := v.getHold(ctx, walletID, holdID, userID)
+	if resp != nil {
+		return *resp
+	}
+
+	// As for captures, a retry of a completed void gets the stored response instead of "hold is not active"
+	return v.idempotent(ctx, idempotencyKey, string(commons.OperationActionVoid), userID, walletID, holdID, commons.ZeroMoney, func(key *models.IdempotencyKey) responses.Response {
+		if resp := checkHoldActive(hold); resp != nil {
+			return *resp
+		}
+		voided, err := v.DB.VoidHold(ctx, holdID, key)
+		if err != nil {
+			if errors.Is(err, commons.HoldNotActiveError) || errors.Is(err, commons.HoldExpiredError) {
+				return badRequest(err.Error())
+			}
+			return internalError("failed to void the hold", err)
+		}
+		return getResponse(holdResponse(voided), "hold voided successfully", http.StatusOK, nil)
+	})
 }
 
 // GetBalance retrieves the current balance of a specified wallet.
 //
 // It first verifies that the requesting user is authorized to access the wallet.
-// If authorized, it fetches the wallet's balance from the database and returns it
-// in the response.
+// If authorized, it fetches the wallet's balance and currency from the database and
+// returns them in the response.
 //
 // Parameters:
 //   - ctx: Context used to manage request timeout and cancellation.
@@ -190,32 +559,103 @@ func (v *walletServiceV1) GetBalance(ctx context.Context, walletID, userID strin
 		return unauthorizedResponse()
 	}
 
-	dbBalance, err := v.DB.GetWalletBalance(ctx, walletID)
+	wallet, err := v.DB.GetWallet(ctx, walletID)
+	if err != nil {
+		return internalError("failed to fetch balance", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+
+	return getResponse(responses.WalletBalanceResponse{
+		WalletID:         walletID,
+		Balance:          wallet.Balance,
+		AvailableBalance: &wallet.AvailableBalance,
+		Currency:         wallet.Currency,
+		Seq:              &wallet.Seq,
+	}, "success", http.StatusOK, nil)
+}
+
+// GetBalanceAsOf retrieves the balance of a wallet at a point in time.
+//
+// It is authorized like GetBalance. The balance is the one left by the latest transaction recorded on
+// the wallet at or before the given time, which every ledger posting of the wallet stores, so no
+// replay of the history is needed. A wallet without transactions by then has a zero balance.
+//
+// Parameters:
+//   - ctx: Context used to manage request timeout and cancellation.
+//   - walletID: ID of the wallet whose balance is being requested.
+//   - userID: ID of the user making the request.
+//   - asOf: Point in time of the balance, inclusive.
+//
+// Returns:
+//   - A Response struct containing the balance, the last transaction included and its sequence number
+//     (if successful), a status message, HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) GetBalanceAsOf(ctx context.Context, walletID, userID string, asOf time.Time) responses.Response {
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	wallet, err := v.DB.GetWallet(ctx, walletID)
+	if err != nil {
+		return internalError("failed to fetch balance", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+	posting, err := v.DB.GetLatestWalletPosting(ctx, walletID, asOf)
 	if err != nil {
 		return internalError("failed to fetch balance", err)
 	}
 
-	return successBalanceResponse(walletID, dbBalance, "success")
+	balance := responses.WalletBalanceResponse{
+		WalletID: walletID,
+		Balance:  commons.ZeroMoney,
+		Currency: wallet.Currency,
+		Seq:      new(int64),
+		AsOf:     &asOf,
+	}
+	if posting != nil {
+		balance.TransactionID = posting.TransactionID
+		balance.Balance = *posting.BalanceAfter
+		balance.Seq = &posting.Seq.Int64
+	}
+	return getResponse(balance, "success", http.StatusOK, nil)
 }
 
 // GetTransactionHistory retrieves a paginated list of transactions for a given wallet.
 //
 // The method first ensures the requesting user is authorized to access the specified wallet.
-// If authorized, it queries the database for transactions associated with the wallet using
-// the provided limit and offset for pagination. The transactions are then mapped to a
-// summarized response format.
+// If authorized, it queries the database for transactions associated with the wallet that match
+// the filter, using the provided limit and offset for pagination. The transactions are then mapped
+// to a summarized response format.
+//
+// A cursor in the filter takes precedence over the offset: the page starts right after (or, for a
+// backward cursor, ends right before) the cursor position. The response carries a next cursor when
+// more transactions follow the page and a previous cursor when transactions precede it.
+//
+// The response also carries the latest sequence number of the wallet, read before the page, so that
+// clients can sync incrementally by asking for the transactions after the last sequence number they saw.
 //
 // Parameters:
 //   - ctx: Context for managing timeouts and cancellations.
 //   - walletID: The ID of the wallet whose transactions are being retrieved.
 //   - userID: The ID of the user making the request.
-//   - limit: The maximum number of transactions to return.
+//   - filter: Optional filters and sort order. Nil returns all transactions, newest first.
+//   - limit: The maximum number of transactions to return, at most commons.MaxHistoryLimit.
 //   - offset: The number of transactions to skip before starting to return results.
 //
 // Returns:
 //   - A Response struct containing a list of transaction summaries (if successful), a status message,
 //     HTTP status code, and any error that occurred during the operation.
-func (v *walletServiceV1) GetTransactionHistory(ctx context.Context, walletID, userID string, limit, offset int32) responses.Response {
+func (v *walletServiceV1) GetTransactionHistory(ctx context.Context, walletID, userID string, filter *models.TransactionFilter, limit, offset int32) responses.Response {
+	if limit > commons.MaxHistoryLimit {
+		return badRequest(fmt.Sprintf("limit must not exceed %d", commons.MaxHistoryLimit))
+	}
 	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
 	if err != nil {
 		return internalError("error while checking wallet ownership", err)
@@ -224,27 +664,495 @@ func (v *walletServiceV1) GetTransactionHistory(ctx context.Context, walletID, u
 		return unauthorizedResponse()
 	}
 
-	transactions, err := v.DB.GetTransactions(ctx, walletID, limit, offset)
+	wallet, err := v.DB.GetWallet(ctx, walletID)
+	if err != nil {
+		return internalError("failed to fetch the wallet", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+
+	var cursor *models.TransactionCursor
+	if filter != nil && filter.Cursor != nil {
+		cursor, offset = filter.Cursor, 0
+	}
+
+	// One extra transaction tells whether another page follows in the paging direction
+	fetched := int(limit) + 1
+	transactions, err := v.DB.GetTransactions(ctx, walletID, filter, int32(fetched), offset)
 	if err != nil {
 		return internalError("failed to fetch transactions", err)
 	}
+	backward := cursor != nil && cursor.Backward
+	hasMore := len(transactions) == fetched
+	if hasMore {
+		if backward {
+			transactions = transactions[len(transactions)-int(limit):]
+		} else {
+			transactions = transactions[:limit]
+		}
+	}
 
 	summary := make([]*responses.TransactionHistory, 0, len(transactions))
 	for _, tx := range transactions {
-		summary = append(summary, &responses.TransactionHistory{
-			Type:       tx.Type,
-			Amount:     tx.Amount,
-			ToWalletID: tx.ToWalletID.String,
-			CreatedAt:  tx.CreatedAt,
-		})
+		summary = append(summary, transactionHistory(tx, walletID))
 	}
 
-	return getResponse(responses.TransactionHistoryResponse{
+	history := responses.TransactionHistoryResponse{
 		WalletID:     walletID,
 		Transactions: summary,
 		Limit:        limit,
 		Offset:       offset,
-	}, "transaction history retrieval successful", http.StatusOK, nil)
+		LatestSeq:    wallet.Seq,
+	}
+	// Incremental syncs page on the sequence number instead of cursors
+	if len(transactions) > 0 && (filter == nil || filter.AfterSeq == nil) {
+		ascending := filter != nil && filter.Ascending
+		if backward || hasMore {
+			last := transactions[len(transactions)-1]
+			history.NextCursor = (&models.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID, Ascending: ascending}).Encode()
+		}
+		if (backward && hasMore) || (!backward && (cursor != nil || offset > 0)) {
+			first := transactions[0]
+			history.PrevCursor = (&models.TransactionCursor{CreatedAt: first.CreatedAt, ID: first.ID, Ascending: ascending, Backward: true}).Encode()
+		}
+	}
+
+	return getResponse(history, "transaction history retrieval successful", http.StatusOK, nil)
+}
+
+// GetTransaction retrieves the details of a transaction from the point of view of the given wallet.
+//
+// The user must own the wallet, and the wallet must be a side of the transaction: the wallet of a deposit or
+// withdrawal, or the sender or recipient of a transfer. The response tells whether the transaction credits or
+// debits the wallet, the counterparty wallet of a transfer and the wallet balance right after the transaction.
+//
+// Parameters:
+//   - ctx: Context for managing timeouts and cancellations.
+//   - walletID: The ID of the wallet from whose point of view the transaction is described.
+//   - txnID: The ID of the transaction.
+//   - userID: The ID of the user making the request.
+//
+// Returns:
+//   - A Response struct containing the transaction details (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) GetTransaction(ctx context.Context, walletID, txnID, userID string) responses.Response {
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	tx, err := v.DB.GetTransaction(ctx, txnID)
+	if err != nil {
+		return internalError("failed to fetch the transaction", err)
+	}
+	if tx == nil || (tx.WalletID != walletID && tx.ToWalletID.String != walletID) {
+		return getResponse(nil, "transaction does not exist", http.StatusNotFound, nil)
+	}
+
+	posting, err := v.DB.GetWalletPosting(ctx, walletID, txnID)
+	if err != nil || posting == nil {
+		return internalError("failed to fetch the balance after the transaction", err)
+	}
+	tx.Seq, tx.BalanceAfter = posting.Seq, posting.BalanceAfter
+
+	return getResponse(responses.TransactionDetailResponse{
+		TransactionHistory: transactionHistory(tx, walletID),
+		WalletID:           walletID,
+	}, "transaction retrieval successful", http.StatusOK, nil)
+}
+
+// GetOperation tells whether the request made on the wallet with the idempotency token is pending, completed or
+// failed. It lets a client find out the outcome of a request whose response was lost, e.g. after a timeout.
+//
+// A request is completed once its transaction is recorded, as the token is stored on the transaction row. Otherwise
+// the idempotency store tells whether it is still in flight or was rejected. A request that is neither recorded nor
+// known to the store was not applied, e.g. because its commit failed, and is reported as failed.
+//
+// Parameters:
+//   - ctx: Context for managing timeouts and cancellations.
+//   - walletID: The ID of the wallet the request was made on.
+//   - idempotencyToken: The idempotency token of the request.
+//   - userID: The ID of the user making the request.
+//
+// Returns:
+//   - A Response struct containing the operation status (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) GetOperation(ctx context.Context, walletID, idempotencyToken, userID string) responses.Response {
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	operation := responses.OperationResponse{WalletID: walletID, IdempotencyToken: idempotencyToken}
+	txn, err := v.DB.GetTransactionByIdempotencyToken(ctx, walletID, idempotencyToken)
+	if err != nil {
+		return internalError("failed to fetch the transaction", err)
+	}
+	if txn != nil {
+		posting, err := v.DB.GetWalletPosting(ctx, walletID, txn.ID)
+		if err != nil || posting == nil {
+			return internalError("failed to fetch the balance after the transaction", err)
+		}
+		operation.Status = string(commons.OperationStatusCompleted)
+		operation.TransactionID, operation.Type = txn.ID, txn.Type
+		operation.BalanceAfter, operation.Currency = posting.BalanceAfter, txn.Currency
+		return getResponse(operation, "operation retrieval successful", http.StatusOK, nil)
+	}
+
+	record, err := v.getIdempotencyRecord(ctx, userID, idempotencyToken)
+	if err != nil {
+		return internalError("error while reading the idempotency token", err)
+	}
+	switch {
+	case record == nil || record.WalletID != walletID:
+		operation.Status = string(commons.OperationStatusFailed)
+		operation.Reason = "no operation was applied with the idempotency token"
+	case record.Status == 0:
+		operation.Status = string(commons.OperationStatusPending)
+	case record.Status < http.StatusBadRequest:
+		operation.Status = string(commons.OperationStatusCompleted)
+	default:
+		operation.Status = string(commons.OperationStatusFailed)
+		operation.Reason = record.Message
+	}
+	return getResponse(operation, "operation retrieval successful", http.StatusOK, nil)
+}
+
+// GetStatement builds the statement of a wallet over a period.
+//
+// The statement starts from the balance of the wallet right before the period, lists every transaction
+// of the period oldest first with the running balance, sums the credits and debits of each transaction
+// type and ends with the closing balance. Transactions are read page by page through GetTransactions.
+//
+// Once the period is closed, i.e. its end lies StatementSettlementDelay in the past, the statement is
+// persisted the first time it is requested and returned unchanged afterwards.
+//
+// Parameters:
+//   - ctx: Context for managing timeouts and cancellations.
+//   - walletID: The ID of the wallet.
+//   - userID: The ID of the user making the request.
+//   - periodStart: Start of the period, inclusive.
+//   - periodEnd: End of the period, exclusive.
+//
+// Returns:
+//   - A Response struct containing the statement (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) GetStatement(ctx context.Context, walletID, userID string, periodStart, periodEnd time.Time) responses.Response {
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	closed := !periodEnd.Add(commons.StatementSettlementDelay).After(time.Now())
+	if closed {
+		stored, err := v.DB.GetStatement(ctx, walletID, periodStart, periodEnd)
+		if err != nil {
+			return internalError("failed to fetch the statement", err)
+		}
+		if stored != nil {
+			return statementResponse(stored)
+		}
+	}
+
+	wallet, err := v.DB.GetWallet(ctx, walletID)
+	if err != nil {
+		return internalError("failed to fetch the wallet", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+	opening, err := v.balanceBefore(ctx, walletID, periodStart)
+	if err != nil {
+		return internalError("failed to fetch the opening balance", err)
+	}
+
+	var transactions []*models.Transaction
+	filter := &models.TransactionFilter{From: &periodStart, To: &periodEnd, Ascending: true}
+	for {
+		page, err := v.DB.GetTransactions(ctx, walletID, filter, commons.StatementPageSize, 0)
+		if err != nil {
+			return internalError("failed to fetch transactions", err)
+		}
+		transactions = append(transactions, page...)
+		if len(page) < commons.StatementPageSize {
+			break
+		}
+		last := page[len(page)-1]
+		filter.Cursor = &models.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID, Ascending: true}
+	}
+
+	statement := buildStatement(wallet, opening, transactions)
+	statement.PeriodStart, statement.PeriodEnd, statement.Closed = periodStart, periodEnd, closed
+	if !closed {
+		return getResponse(*statement, "statement retrieval successful", http.StatusOK, nil)
+	}
+
+	content, err := json.Marshal(statement)
+	if err != nil {
+		return internalError("failed to save the statement", err)
+	}
+	saved, err := v.DB.SaveStatement(ctx, &models.Statement{WalletID: walletID, PeriodStart: periodStart, PeriodEnd: periodEnd, Content: content})
+	if err != nil || saved == nil {
+		return internalError("failed to save the statement", err)
+	}
+	return statementResponse(saved)
+}
+
+// ExportTransactions prepares the export of the transactions of a wallet over a period in CSV, OFX or
+// camt.053 format.
+//
+// The wallet ownership and the opening and closing balances are checked before anything is written, so
+// that errors are still reported as regular responses. The transactions themselves are streamed, oldest
+// first, by the Write function of the returned export, one database row at a time.
+//
+// Parameters:
+//   - ctx: Context for managing timeouts and cancellations.
+//   - walletID: The ID of the wallet.
+//   - userID: The ID of the user making the request.
+//   - format: The export format.
+//   - periodStart: Start of the period, inclusive.
+//   - periodEnd: End of the period, exclusive.
+//
+// Returns:
+//   - A Response struct containing the export to stream (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) ExportTransactions(ctx context.Context, walletID, userID string, format commons.ExportFormat, periodStart, periodEnd time.Time) responses.Response {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return badRequest("format must be csv, ofx or camt053")
+	}
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	wallet, err := v.DB.GetWallet(ctx, walletID)
+	if err != nil {
+		return internalError("failed to fetch the wallet", err)
+	}
+	if wallet == nil {
+		return walletNotFoundResponse()
+	}
+	opening, err := v.balanceBefore(ctx, walletID, periodStart)
+	if err != nil {
+		return internalError("failed to fetch the balances of the period", err)
+	}
+	closing, err := v.balanceBefore(ctx, walletID, periodEnd)
+	if err != nil {
+		return internalError("failed to fetch the balances of the period", err)
+	}
+	info := exportInfo{
+		walletID:       walletID,
+		currency:       wallet.Currency,
+		from:           periodStart,
+		to:             periodEnd,
+		openingBalance: opening,
+		closingBalance: closing,
+		generatedAt:    time.Now(),
+	}
+
+	export := responses.ExportResponse{
+		ContentType: contentType[0],
+		FileName:    fmt.Sprintf("%s_%s_%s.%s", walletID, periodStart.Format("20060102"), periodEnd.Format("20060102"), contentType[1]),
+		Write: func(ctx context.Context, w io.Writer) error {
+			exporter := newTransactionExporter(format, w, info)
+			if err := exporter.begin(); err != nil {
+				return err
+			}
+			filter := &models.TransactionFilter{From: &periodStart, To: &periodEnd, Ascending: true}
+			err := v.DB.StreamTransactions(ctx, walletID, filter, func(tx *models.Transaction) error {
+				return exporter.write(transactionHistory(tx, walletID))
+			})
+			if err != nil {
+				return err
+			}
+			return exporter.end()
+		},
+	}
+	return getResponse(export, "transaction export ready", http.StatusOK, nil)
+}
+
+// CreateWallet opens an additional wallet for an existing user.
+//
+// The wallet is created with a zero balance in the given currency and can optionally be given
+// a label to tell it apart from the user's other wallets.
+//
+// Parameters:
+//   - ctx: Context for managing timeouts and cancellations.
+//   - userID: ID of the user opening the wallet.
+//   - label: Optional name of the wallet. Empty when not provided.
+//   - currency: ISO 4217 currency code of the wallet. Must be a supported currency.
+//
+// Returns:
+//   - A Response struct containing the created wallet (if successful), a status message,
+//     HTTP status code 201, or an unauthorized response if the user does not exist.
+func (v *walletServiceV1) CreateWallet(ctx context.Context, userID, label, currency string) responses.Response {
+	wallet, err := v.DB.CreateWallet(ctx, userID, label, currency)
+	if err != nil {
+		return internalError("failed to create wallet", err)
+	}
+	if wallet == nil {
+		return getResponse(nil, "user does not exist", http.StatusUnauthorized, nil)
+	}
+	return getResponse(walletResponse(wallet), "wallet created successfully", http.StatusCreated, nil)
+}
+
+// GetWallets retrieves all wallets owned by a user together with their balances.
+//
+// Parameters:
+//   - ctx: Context for managing timeouts and cancellations.
+//   - userID: ID of the user whose wallets are being retrieved.
+//
+// Returns:
+//   - A Response struct containing the user's wallets in creation order (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) GetWallets(ctx context.Context, userID string) responses.Response {
+	wallets, err := v.DB.GetUserWallets(ctx, userID)
+	if err != nil {
+		return internalError("failed to fetch wallets", err)
+	}
+
+	userWallets := make([]*responses.WalletResponse, 0, len(wallets))
+	for _, wallet := range wallets {
+		userWallets = append(userWallets, walletResponse(wallet))
+	}
+	return getResponse(responses.WalletListResponse{
+		UserID:  userID,
+		Wallets: userWallets,
+	}, "wallet retrieval successful", http.StatusOK, nil)
+}
+
+// ReverseTransaction refunds a deposit or transfer, fully or partially, with a compensating transaction linked to it.
+//
+// A deposit is reversed by withdrawing the amount from the wallet, and a transfer by transferring the amount back
+// from the recipient to the sender. Only the owner of the wallet that received the funds can reverse them. The
+// reversal goes through InsertTxnAndGetWalletBalance, which locks the reversed transaction so that the reversals
+// never exceed its amount, and fails with insufficient balance if the funds have already been spent. Fees are not
+// refunded, and the reversal of a cross-currency transfer debits the recipient its share of the converted amount.
+//
+// Parameters:
+//   - ctx: Context for managing request lifetime and enforcing timeouts or cancellation.
+//   - idempotencyKey: Unique key provided by the client to prevent duplicate reversals.
+//   - walletID: Wallet ID that received the funds of the reversed transaction.
+//   - txnID: ID of the transaction to reverse.
+//   - amount: Amount to reverse in the currency of the transaction. Zero reverses the amount left to reverse.
+//   - userID: ID of the user reversing the transaction.
+//
+// Returns:
+//   - A Response struct containing the updated balance of the wallet (if successful), a status message,
+//     HTTP status code, and any error that occurred during the operation.
+func (v *walletServiceV1) ReverseTransaction(ctx context.Context, idempotencyKey, walletID, txnID string, amount commons.Money, userID string) responses.Response {
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		return internalError("error while checking wallet ownership", err)
+	}
+	if !isAuthorized {
+		return unauthorizedResponse()
+	}
+
+	original, err := v.DB.GetTransaction(ctx, txnID)
+	if err != nil {
+		return internalError("failed to fetch the transaction", err)
+	}
+	if original == nil || (original.WalletID != walletID && original.ToWalletID.String != walletID) {
+		return badRequest("transaction does not exist")
+	}
+	if original.ReversalOf.Valid {
+		return badRequest("a reversal cannot be reversed")
+	}
+	trsType := commons.TransactionType(original.Type)
+	if trsType == commons.TransactionTypeWithdraw {
+		return badRequest("withdrawals cannot be reversed")
+	}
+	if trsType == commons.TransactionTypeTransfer && original.ToWalletID.String != walletID {
+		return badRequest("only the recipient of a transfer can reverse it")
+	}
+
+	if err := commons.ValidateAmountPrecision(amount, original.Currency); err != nil {
+		return badRequest(err.Error())
+	}
+
+	// The amount left to reverse is checked under the idempotency key, so that a retry of a completed reversal gets
+	// the stored response instead of "already reversed"
+	return v.idempotent(ctx, idempotencyKey, string(commons.OperationActionReversal), userID, walletID, txnID, amount, func(key *models.IdempotencyKey) responses.Response {
+		remaining := original.ReversibleAmount()
+		if !remaining.IsPositive() {
+			return badRequest(commons.TransactionAlreadyReversedError.Error())
+		}
+		reversed := amount
+		if reversed.IsZero() {
+			reversed = remaining
+		}
+		if reversed.GreaterThan(remaining) {
+			return badRequest(commons.ReversalAmountExceededError.Error())
+		}
+
+		txn, err := reversalTransaction(original, reversed)
+		if err != nil {
+			return badRequest(err.Error())
+		}
+		txn.IdempotencyKey = key
+		updatedBalance, err := v.DB.InsertTxnAndGetWalletBalance(ctx, txn)
+		if err != nil {
+			if errors.Is(err, commons.InsufficientBalanceError) || errors.Is(err, commons.TransactionAlreadyReversedError) ||
+				errors.Is(err, commons.ReversalAmountExceededError) {
+				return badRequest(err.Error())
+			}
+			return internalError("failed to record the reversal transaction", err)
+		}
+
+		return successBalanceResponse(walletID, txn.ID, updatedBalance, txn.Currency, "reversal successful")
+	})
+}
+
+// getHold returns the hold if the user owns its wallet, whatever its state. Otherwise, it returns the response to send.
+func (v *walletServiceV1) getHold(ctx context.Context, walletID, holdID, userID string) (*models.Hold, *responses.Response) {
+	isAuthorized, err := v.isAuthorized(ctx, walletID, userID)
+	if err != nil {
+		resp := internalError("error while checking wallet ownership", err)
+		return nil, &resp
+	}
+	if !isAuthorized {
+		resp := unauthorizedResponse()
+		return nil, &resp
+	}
+
+	hold, err := v.DB.GetHold(ctx, holdID)
+	if err != nil {
+		resp := internalError("failed to fetch the hold", err)
+		return nil, &resp
+	}
+	if hold == nil || hold.WalletID != walletID {
+		resp := badRequest("hold does not exist")
+		return nil, &resp
+	}
+	return hold, nil
+}
+
+// checkHoldActive returns the response to send if the hold no longer reserves funds, or nil.
+func checkHoldActive(hold *models.Hold) *responses.Response {
+	if hold.Status != string(commons.HoldStatusActive) {
+		resp := badRequest(commons.HoldNotActiveError.Error())
+		return &resp
+	}
+	if !hold.IsActive(time.Now()) {
+		resp := badRequest(commons.HoldExpiredError.Error())
+		return &resp
+	}
+	return nil
 }
 
 func (v *walletServiceV1) isAuthorized(ctx context.Context, walletID, userID string) (bool, error) {
@@ -256,18 +1164,55 @@ func (v *walletServiceV1) isAuthorized(ctx context.Context, walletID, userID str
 	return isOwner, err
 }
 
-func (v *walletServiceV1) setIdempotency(ctx context.Context, key string, action commons.TransactionType, userID, walletID string, amount float64) (bool, error) {
-	cacheKey := fmt.Sprintf("idm-%s-%s-%s-%s-%f", string(action), key, userID, walletID, amount)
-	ok, err := v.Cache.SetWithExpirationIfKeyIsNotSet(ctx, cacheKey, cacheKey, commons.IdempotencyCacheTTL)
+// priceTransaction converts the amount into the recipient currency on cross-currency transfers and applies
+// the fee schedule to the transaction. It returns a non-nil response when the transaction cannot be priced.
+func (v *walletServiceV1) priceTransaction(ctx context.Context, txn *models.Transaction, destCurrency string) *responses.Response {
+	if commons.TransactionType(txn.Type) != commons.TransactionTypeTransfer || destCurrency == txn.Currency {
+		return nil
+	}
+	if resp := v.applyExchangeRate(ctx, txn, destCurrency); resp != nil {
+		return resp
+	}
+	places, err := commons.CurrencyDecimalPlaces(txn.Currency)
 	if err != nil {
-		log.Errorf("idempotency check failed for key '%s': %v", cacheKey, err)
-		return false, err
+		resp := internalError("wallet has an unsupported currency", err)
+		return &resp
 	}
-	// SetWithExpirationIfKeyIsNotSet returns true if the key is set, so here we have to return false
-	return !ok, err
+	txn.Fee = txn.Amount.MulRate(commons.FXFeeRate, places)
+	return nil
 }
 
-func (v *walletServiceV1) walletExists(ctx context.Context, walletID string) (bool, error) {
-	w, err := v.DB.GetWallet(ctx, walletID)
-	return w != nil, err
+// applyExchangeRate converts the transfer amount into the recipient currency and records the applied rate
+// on the transaction. It returns a non-nil response when the conversion cannot be performed.
+func (v *walletServiceV1) applyExchangeRate(ctx context.Context, txn *models.Transaction, destCurrency string) *responses.Response {
+	if v.Rates == nil {
+		resp := badRequest(commons.CurrencyMismatchError.Error())
+		return &resp
+	}
+	rate, err := v.Rates.GetRate(ctx, txn.Currency, destCurrency)
+	if err != nil {
+		log.Errorf("exchange rate lookup failed for %s/%s: %v", txn.Currency, destCurrency, err)
+		resp := internalError("failed to fetch the exchange rate", err)
+		return &resp
+	}
+	if rate == nil {
+		resp := badRequest(fmt.Sprintf("exchange rate from %s to %s is not available", txn.Currency, destCurrency))
+		return &resp
+	}
+	places, err := commons.CurrencyDecimalPlaces(destCurrency)
+	if err != nil {
+		resp := internalError("recipient wallet has an unsupported currency", err)
+		return &resp
+	}
+	destAmount := txn.Amount.MulRate(rate.Rate, places)
+	if !destAmount.IsPositive() {
+		resp := badRequest("amount is too small to be converted")
+		return &resp
+	}
+
+	txn.DestAmount = &destAmount
+	txn.DestCurrency = sql.NullString{String: destCurrency, Valid: true}
+	txn.FXRate = &rate.Rate
+	txn.FXRateAt = sql.NullTime{Time: rate.UpdatedAt, Valid: true}
+	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"testing"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"go.uber.org/mock/gomock"
 
 	"WalletApp/commons"
+	"WalletApp/db"
 	mocks2 "WalletApp/db/mocks"
 	"WalletApp/models"
 	"WalletApp/models/responses"
@@ -20,7 +23,7 @@ import (
 func TestWalletServiceV1_Deposit(t *testing.T) {
 	idempotencyKey := "test-key"
 	walletID := "1234"
-	amount := 120.0
+	amount := commons.MustMoney("120")
 	userID := "2222"
 
 	t.Run("should return bad request response if the user is not authorized for the wallet", func(t *testing.T) {
@@ -48,28 +51,43 @@ func TestWalletServiceV1_Deposit(t *testing.T) {
 		assert.Equal(t, "test db error", resp.Error.Error())
 	})
 
+	t.Run("should return not found response if the wallet no longer exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(nil, nil)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
+		assert.Equal(t, http.StatusNotFound, resp.Status)
+		assert.Equal(t, "wallet does not exist", resp.Message)
+		assert.Nil(t, resp.Error)
+	})
+
 	t.Run("should return conflict response if there is an idempotency token already", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		expectIdempotencyKeyInFlight(idempotencyMock)
 		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusConflict, resp.Status)
-		assert.Equal(t, "duplicate request: idempotency key already exists", resp.Message)
+		assert.Equal(t, "duplicate request: a request with this idempotency key is still in progress", resp.Message)
 	})
 
 	t.Run("should return internal server error response upon errors when checking the idempotency token", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
 		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusInternalServerError, resp.Status)
@@ -77,16 +95,34 @@ func TestWalletServiceV1_Deposit(t *testing.T) {
 		assert.Equal(t, "test db error", resp.Error.Error())
 	})
 
+	t.Run("should return bad request response if the amount precision is not valid for the wallet currency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "JPY"}, nil)
+		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, commons.MustMoney("120.5"), userID)
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "amount cannot have more than 0 decimal places for JPY", resp.Message)
+	})
+
 	t.Run("should return success response after successfully recording the transaction", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(amount, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+			txn.ID = "txn-1"
+			return amount, nil
+		})
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
 
 		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
 
@@ -96,6 +132,7 @@ func TestWalletServiceV1_Deposit(t *testing.T) {
 		if userWalletResp, ok := resp.Data.(responses.WalletBalanceResponse); ok {
 			assert.Equal(t, amount, userWalletResp.Balance)
 			assert.Equal(t, walletID, userWalletResp.WalletID)
+			assert.Equal(t, "txn-1", userWalletResp.TransactionID)
 		} else {
 			assert.Fail(t, "cannot cast the response")
 		}
@@ -106,11 +143,13 @@ func TestWalletServiceV1_Deposit(t *testing.T) {
 		defer ctrl.Finish()
 
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(-1.0, errors.New("test db error"))
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, errors.New("test db error"))
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 		resp := walletService.Deposit(context.Background(), idempotencyKey, walletID, amount, userID)
 
@@ -124,7 +163,7 @@ func TestWalletServiceV1_Deposit(t *testing.T) {
 func TestWalletServiceV1_Withdraw(t *testing.T) {
 	idempotencyKey := "test-key"
 	walletID := "1234"
-	amount := 120.0
+	amount := commons.MustMoney("120")
 	userID := "2222"
 
 	t.Run("should return bad request response if the user is not authorized for the wallet", func(t *testing.T) {
@@ -156,28 +195,30 @@ func TestWalletServiceV1_Withdraw(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		expectIdempotencyKeyInFlight(idempotencyMock)
 		resp := walletService.Withdraw(context.Background(), idempotencyKey, walletID, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusConflict, resp.Status)
-		assert.Equal(t, "duplicate request: idempotency key already exists", resp.Message)
+		assert.Equal(t, "duplicate request: a request with this idempotency key is still in progress", resp.Message)
 	})
 
 	t.Run("should return internal server error response upon errors when checking the idempotency token", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
 		resp := walletService.Withdraw(context.Background(), idempotencyKey, walletID, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusInternalServerError, resp.Status)
-		assert.Equal(t, "error while checking idempotency for deposit action", resp.Message)
+		assert.Equal(t, "error while checking idempotency for withdrawal action", resp.Message)
 		assert.Equal(t, "test db error", resp.Error.Error())
 	})
 
@@ -185,11 +226,13 @@ func TestWalletServiceV1_Withdraw(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(-1.0, commons.InsufficientBalanceError)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, commons.InsufficientBalanceError)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
 		resp := walletService.Withdraw(context.Background(), idempotencyKey, walletID, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusBadRequest, resp.Status)
@@ -201,11 +244,16 @@ func TestWalletServiceV1_Withdraw(t *testing.T) {
 		defer ctrl.Finish()
 
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(amount, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, txn *models.Transaction) (commons.Money, error) {
+			txn.ID = "txn-1"
+			return amount, nil
+		})
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
 
 		resp := walletService.Withdraw(context.Background(), idempotencyKey, walletID, amount, userID)
 
@@ -215,6 +263,7 @@ func TestWalletServiceV1_Withdraw(t *testing.T) {
 		if userWalletResp, ok := resp.Data.(responses.WalletBalanceResponse); ok {
 			assert.Equal(t, amount, userWalletResp.Balance)
 			assert.Equal(t, walletID, userWalletResp.WalletID)
+			assert.Equal(t, "txn-1", userWalletResp.TransactionID)
 		} else {
 			assert.Fail(t, "cannot cast the response")
 		}
@@ -225,11 +274,13 @@ func TestWalletServiceV1_Withdraw(t *testing.T) {
 		defer ctrl.Finish()
 
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(-1.0, errors.New("test db error"))
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, errors.New("test db error"))
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 		resp := walletService.Withdraw(context.Background(), idempotencyKey, walletID, amount, userID)
 
@@ -244,7 +295,7 @@ func TestWalletServiceV1_Transfer(t *testing.T) {
 	idempotencyKey := "test-key"
 	fromAccount := "1234"
 	toAccount := "4321"
-	amount := 120.0
+	amount := commons.MustMoney("120")
 	userID := "2222"
 
 	t.Run("should return bad request response if the to account and from account is same", func(t *testing.T) {
@@ -274,12 +325,14 @@ func TestWalletServiceV1_Transfer(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(-1.0, commons.InsufficientBalanceError)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(&models.Wallet{}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, commons.InsufficientBalanceError)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(&models.Wallet{ID: toAccount, Currency: "USD"}, nil)
 		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusBadRequest, resp.Status)
@@ -303,24 +356,26 @@ func TestWalletServiceV1_Transfer(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		expectIdempotencyKeyInFlight(idempotencyMock)
 		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusConflict, resp.Status)
-		assert.Equal(t, "duplicate request: idempotency key already exists", resp.Message)
+		assert.Equal(t, "duplicate request: a request with this idempotency key is still in progress", resp.Message)
 	})
 
 	t.Run("should return internal server error response upon errors when checking the idempotency token", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
 		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusInternalServerError, resp.Status)
@@ -332,11 +387,13 @@ func TestWalletServiceV1_Transfer(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(nil, errors.New("test db error"))
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(nil, errors.New("test db error"))
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusInternalServerError, resp.Status)
@@ -348,28 +405,96 @@ func TestWalletServiceV1_Transfer(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(nil, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(nil, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
 		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusBadRequest, resp.Status)
 		assert.Equal(t, "recipient wallet id does not exist", resp.Message)
 	})
 
+	t.Run("should return bad request response if the recipient wallet has a different currency and no rate provider is configured", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(&models.Wallet{ID: toAccount, Currency: "EUR"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "cross-currency transfers are not supported", resp.Message)
+	})
+
+	t.Run("should return bad request response if there is no exchange rate for the currency pair", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock, Rates: db.NewInMemoryRateProvider(nil)}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(&models.Wallet{ID: toAccount, Currency: "EUR"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "exchange rate from USD to EUR is not available", resp.Message)
+	})
+
+	t.Run("should convert the amount and record the applied rate on cross-currency transfers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		rateTime := time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)
+		rates := db.NewInMemoryRateProvider([]*models.ExchangeRate{
+			{BaseCurrency: "USD", QuoteCurrency: "JPY", Rate: commons.MustRate("149.537"), UpdatedAt: rateTime},
+		})
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock, Rates: rates}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(&models.Wallet{ID: toAccount, Currency: "JPY"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			// 120 USD * 149.537 = 17944.44 JPY, rounded to 0 decimal places
+			return txn.Amount.Equal(amount) &&
+				txn.DestAmount != nil && txn.DestAmount.Equal(commons.MustMoney("17944")) &&
+				txn.DestCurrency.String == "JPY" &&
+				txn.FXRate != nil && txn.FXRate.Equal(commons.MustRate("149.537")) &&
+				txn.FXRateAt.Time.Equal(rateTime) &&
+				txn.Fee.Equal(commons.MustMoney("0.6")) // 0.5% of 120 USD
+		})).Return(commons.MustMoney("30"), nil)
+		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "transfer successful", resp.Message)
+	})
+
 	t.Run("should return success response after successfully recording the transaction", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(&models.Wallet{}, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(amount, nil)
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(&models.Wallet{ID: toAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(amount, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
 
 		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
 
@@ -389,12 +514,14 @@ func TestWalletServiceV1_Transfer(t *testing.T) {
 		defer ctrl.Finish()
 
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		cacheMock := mocks2.NewMockCache(ctrl)
-		walletService := walletServiceV1{DB: databaseMock, Cache: cacheMock}
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(&models.Wallet{}, nil)
-		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(-1.0, errors.New("test db error"))
-		cacheMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(&models.Wallet{ID: toAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, errors.New("test db error"))
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 		resp := walletService.Transfer(context.Background(), idempotencyKey, fromAccount, toAccount, amount, userID)
 
@@ -405,10 +532,144 @@ func TestWalletServiceV1_Transfer(t *testing.T) {
 	})
 }
 
+func TestWalletServiceV1_CreateQuote(t *testing.T) {
+	fromAccount := "1234"
+	toAccount := "4321"
+	amount := commons.MustMoney("120")
+	userID := "2222"
+
+	t.Run("should return unauthorized response if the user is not authorized for the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		resp := walletService.CreateQuote(context.Background(), fromAccount, toAccount, commons.TransactionTypeTransfer, amount, userID)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	})
+
+	t.Run("should quote the debit, fee and credit of a cross-currency transfer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		rates := db.NewInMemoryRateProvider([]*models.ExchangeRate{
+			{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: commons.MustRate("0.92"), UpdatedAt: time.Now()},
+		})
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Rates: rates}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), toAccount).Return(&models.Wallet{ID: toAccount, Currency: "EUR"}, nil)
+		databaseMock.EXPECT().CreateQuote(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, quote *models.Quote) (*models.Quote, error) {
+			created := *quote
+			created.ID = "quote-1"
+			created.ExpiresAt = time.Now().Add(commons.QuoteTTL)
+			return &created, nil
+		})
+		resp := walletService.CreateQuote(context.Background(), fromAccount, toAccount, commons.TransactionTypeTransfer, amount, userID)
+		assert.Equal(t, http.StatusCreated, resp.Status)
+		if quoteResp, ok := resp.Data.(responses.QuoteResponse); ok {
+			assert.Equal(t, "quote-1", quoteResp.QuoteID)
+			assert.True(t, commons.MustMoney("0.6").Equal(quoteResp.Fee))
+			assert.True(t, commons.MustMoney("120.6").Equal(quoteResp.DebitAmount))
+			assert.True(t, commons.MustMoney("110.4").Equal(quoteResp.CreditAmount))
+			assert.Equal(t, "EUR", quoteResp.CreditCurrency)
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
+	})
+
+	t.Run("should quote a withdrawal without a fee", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), fromAccount).Return(&models.Wallet{ID: fromAccount, Currency: "USD"}, nil)
+		databaseMock.EXPECT().CreateQuote(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, quote *models.Quote) (*models.Quote, error) {
+			return quote, nil
+		})
+		resp := walletService.CreateQuote(context.Background(), fromAccount, "", commons.TransactionTypeWithdraw, amount, userID)
+		assert.Equal(t, http.StatusCreated, resp.Status)
+		if quoteResp, ok := resp.Data.(responses.QuoteResponse); ok {
+			assert.True(t, quoteResp.Fee.IsZero())
+			assert.True(t, amount.Equal(quoteResp.DebitAmount))
+			assert.True(t, amount.Equal(quoteResp.CreditAmount))
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
+	})
+}
+
+func TestWalletServiceV1_ExecuteQuote(t *testing.T) {
+	idempotencyKey := "test-key"
+	walletID := "1234"
+	quoteID := "9876"
+	userID := "2222"
+	quote := &models.Quote{ID: quoteID, WalletID: walletID, Type: "withdrawal", Amount: commons.MustMoney("50"), Currency: "USD"}
+
+	t.Run("should return bad request response if the quote belongs to another wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetQuote(gomock.Any(), quoteID).Return(&models.Quote{ID: quoteID, WalletID: "other"}, nil)
+		resp := walletService.ExecuteQuote(context.Background(), idempotencyKey, walletID, quoteID, commons.TransactionTypeWithdraw, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "quote does not exist", resp.Message)
+	})
+
+	t.Run("should return bad request response if the quote type does not match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetQuote(gomock.Any(), quoteID).Return(quote, nil)
+		resp := walletService.ExecuteQuote(context.Background(), idempotencyKey, walletID, quoteID, commons.TransactionTypeTransfer, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "quote is not for a transfer", resp.Message)
+	})
+
+	t.Run("should return bad request response if the quote has expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetQuote(gomock.Any(), quoteID).Return(quote, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, commons.QuoteExpiredError)
+		resp := walletService.ExecuteQuote(context.Background(), idempotencyKey, walletID, quoteID, commons.TransactionTypeWithdraw, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "quote expired", resp.Message)
+	})
+
+	t.Run("should execute the quote with the quoted amounts", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetQuote(gomock.Any(), quoteID).Return(quote, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			return txn.QuoteID.String == quoteID && txn.Amount.Equal(quote.Amount) && txn.Type == quote.Type
+		})).Return(commons.MustMoney("10"), nil)
+		resp := walletService.ExecuteQuote(context.Background(), idempotencyKey, walletID, quoteID, commons.TransactionTypeWithdraw, userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "withdrawal successful", resp.Message)
+	})
+}
+
 func TestWalletServiceV1_GetBalance(t *testing.T) {
 	fromAccount := "1234"
 	userID := "2222"
-	amount := 120.0
+	amount := commons.MustMoney("120")
 
 	t.Run("should return bad request response if the user is not authorized for the wallet", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -441,7 +702,7 @@ func TestWalletServiceV1_GetBalance(t *testing.T) {
 		databaseMock := mocks2.NewMockDatabase(ctrl)
 		walletService := walletServiceV1{DB: databaseMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetWalletBalance(gomock.Any(), gomock.Any()).Return(-1.0, errors.New("test db error"))
+		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(nil, errors.New("test db error"))
 		resp := walletService.GetBalance(context.Background(), fromAccount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusInternalServerError, resp.Status)
@@ -449,111 +710,1061 @@ func TestWalletServiceV1_GetBalance(t *testing.T) {
 		assert.Equal(t, "test db error", resp.Error.Error())
 	})
 
+	t.Run("should return not found response if the wallet no longer exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(nil, nil)
+		resp := walletService.GetBalance(context.Background(), fromAccount, userID)
+		assert.Equal(t, http.StatusNotFound, resp.Status)
+		assert.Equal(t, "wallet does not exist", resp.Message)
+	})
+
 	t.Run("should return success response after retrieving the balance", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
 		walletService := walletServiceV1{DB: databaseMock}
 		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetWalletBalance(gomock.Any(), gomock.Any()).Return(amount, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(&models.Wallet{ID: fromAccount, Balance: amount, AvailableBalance: commons.MustMoney("100"), Currency: "USD", Seq: 12}, nil)
 		resp := walletService.GetBalance(context.Background(), fromAccount, userID)
 		assert.NotNil(t, resp)
 		assert.Equal(t, http.StatusOK, resp.Status)
 		assert.Equal(t, "success", resp.Message)
+		if balanceResp, ok := resp.Data.(responses.WalletBalanceResponse); ok {
+			assert.True(t, amount.Equal(balanceResp.Balance))
+			assert.True(t, commons.MustMoney("100").Equal(*balanceResp.AvailableBalance))
+			assert.Equal(t, int64(12), *balanceResp.Seq)
+			assert.Equal(t, "USD", balanceResp.Currency)
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
 	})
 
 }
 
-func TestWalletServiceV1_GetTransactionHistory(t *testing.T) {
+func TestWalletServiceV1_CreateHold(t *testing.T) {
+	idempotencyKey := "test-key"
+	walletID := "1234"
+	recipientID := "5678"
+	amount := commons.MustMoney("120")
 	userID := "2222"
-	walletID := "1234567"
 
-	t.Run("should return bad request response if the user is not authorized for the wallet", func(t *testing.T) {
+	t.Run("should return bad request response if the wallet balance is less than the amount", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
-		walletService := walletServiceV1{DB: databaseMock}
-		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
-		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, 10, 0)
-		assert.NotNil(t, resp)
-		assert.Equal(t, http.StatusUnauthorized, resp.Status)
-		assert.Equal(t, "unauthorized access to the wallet", resp.Message)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().CreateHold(gomock.Any(), gomock.Any(), commons.DefaultHoldTTL).Return(nil, commons.InsufficientBalanceError)
+		resp := walletService.CreateHold(context.Background(), idempotencyKey, walletID, "", amount, commons.DefaultHoldTTL, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "insufficient balance error", resp.Message)
 	})
 
-	t.Run("should return internal server error upon errors when checking the wallet ownership", func(t *testing.T) {
+	t.Run("should return bad request response if the recipient wallet does not exist", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
 		walletService := walletServiceV1{DB: databaseMock}
-		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
-		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, 10, 0)
-		assert.NotNil(t, resp)
-		assert.Equal(t, http.StatusInternalServerError, resp.Status)
-		assert.Equal(t, "error while checking wallet ownership", resp.Message)
-		assert.Equal(t, "test db error", resp.Error.Error())
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), recipientID).Return(nil, nil)
+		resp := walletService.CreateHold(context.Background(), idempotencyKey, walletID, recipientID, amount, commons.DefaultHoldTTL, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "recipient wallet id does not exist", resp.Message)
 	})
 
-	t.Run("should return internal server error upon errors when retrieving the transaction history", func(t *testing.T) {
+	t.Run("should return created response with the hold", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "USD"}, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), recipientID).Return(&models.Wallet{ID: recipientID, Currency: "USD"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().CreateHold(gomock.Any(), gomock.Cond(func(h *models.Hold) bool {
+			return h.WalletID == walletID && h.ToWalletID.String == recipientID && h.Amount.Equal(amount) && h.Currency == "USD"
+		}), time.Hour).DoAndReturn(func(_ context.Context, h *models.Hold, _ time.Duration) (*models.Hold, error) {
+			h.ID = "hold-1"
+			h.Status = string(commons.HoldStatusActive)
+			return h, nil
+		})
+		resp := walletService.CreateHold(context.Background(), idempotencyKey, walletID, recipientID, amount, time.Hour, userID)
+		assert.Equal(t, http.StatusCreated, resp.Status)
+		assert.Equal(t, "hold created successfully", resp.Message)
+		if holdResp, ok := resp.Data.(*responses.HoldResponse); ok {
+			assert.Equal(t, "hold-1", holdResp.HoldID)
+			assert.Equal(t, recipientID, holdResp.RecipientWalletID)
+			assert.Equal(t, "active", holdResp.Status)
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
+	})
+}
+
+func TestWalletServiceV1_CaptureHold(t *testing.T) {
+	idempotencyKey := "test-key"
+	walletID := "1234"
+	holdID := "hold-1"
+	userID := "2222"
+	activeHold := func() *models.Hold {
+		return &models.Hold{
+			ID:        holdID,
+			WalletID:  walletID,
+			Amount:    commons.MustMoney("100"),
+			Currency:  "USD",
+			Status:    string(commons.HoldStatusActive),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("should return bad request response if the hold belongs to another wallet", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
 		walletService := walletServiceV1{DB: databaseMock}
-		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetTransactions(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("test db error"))
-		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, 10, 0)
-		assert.NotNil(t, resp)
-		assert.Equal(t, http.StatusInternalServerError, resp.Status)
-		assert.Equal(t, "failed to fetch transactions", resp.Message)
-		assert.Equal(t, "test db error", resp.Error.Error())
+		hold := activeHold()
+		hold.WalletID = "9999"
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		resp := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, holdID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "hold does not exist", resp.Message)
 	})
 
-	t.Run("should return success response after retrieving the balance", func(t *testing.T) {
+	t.Run("should return bad request response if the hold is not active", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		hold := activeHold()
+		hold.Status = string(commons.HoldStatusVoided)
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		resp := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, holdID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "hold is not active", resp.Message)
+	})
 
-		transactions := []*models.Transaction{
-			{
-				WalletID:  "aa4d319f-2b7a-436e-a582-b862fc930e2b",
-				Type:      "deposit",
-				Amount:    100,
-				CreatedAt: time.Now(),
-			},
-			{
-				WalletID:  "d8e02c06-339a-49b2-8ffd-310fd2ec1947",
-				Type:      "withdrawal",
-				Amount:    20,
-				CreatedAt: time.Now(),
-			},
-			{
-				WalletID:   "a5fd3b19-8caa-412a-94e6-4b68a1fcac4f",
-				Type:       "transfer",
-				ToWalletID: sql.NullString{String: "e0925d9a-03ce-4639-b78b-1a52fec87ac4", Valid: true},
-				Amount:     40,
-				CreatedAt:  time.Now(),
-			},
+	t.Run("should return bad request response if the hold expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		hold := activeHold()
+		hold.ExpiresAt = time.Now().Add(-time.Minute)
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		resp := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, holdID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "hold expired", resp.Message)
+	})
+
+	t.Run("should return bad request response if the capture amount exceeds the held amount", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(activeHold(), nil)
+		resp := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, holdID, commons.MustMoney("100.01"), userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "capture amount exceeds the held amount", resp.Message)
+	})
+
+	t.Run("should capture the full held amount as a withdrawal when no amount is given", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(activeHold(), nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			return txn.Type == string(commons.TransactionTypeWithdraw) && txn.Amount.Equal(commons.MustMoney("100")) &&
+				txn.HoldID.String == holdID && txn.Currency == "USD"
+		})).Return(commons.MustMoney("20"), nil)
+		resp := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, holdID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "hold captured successfully", resp.Message)
+		if balanceResp, ok := resp.Data.(responses.WalletBalanceResponse); ok {
+			assert.True(t, commons.MustMoney("20").Equal(balanceResp.Balance))
+		} else {
+			assert.Fail(t, "cannot cast the response")
 		}
+	})
+
+	t.Run("should capture a partial amount as a transfer to the recipient of the hold", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		hold := activeHold()
+		hold.ToWalletID = sql.NullString{String: "5678", Valid: true}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), "5678").Return(&models.Wallet{ID: "5678", Currency: "USD"}, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			return txn.Type == string(commons.TransactionTypeTransfer) && txn.Amount.Equal(commons.MustMoney("40")) &&
+				txn.ToWalletID.String == "5678" && txn.HoldID.String == holdID
+		})).Return(commons.MustMoney("80"), nil)
+		resp := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, holdID, commons.MustMoney("40"), userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "hold captured successfully", resp.Message)
+	})
+
+	t.Run("should return bad request response if the hold was captured concurrently", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(activeHold(), nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Any()).Return(commons.ZeroMoney, commons.HoldNotActiveError)
+		resp := walletService.CaptureHold(context.Background(), idempotencyKey, walletID, holdID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "hold is not active", resp.Message)
+	})
+}
+
+func TestWalletServiceV1_VoidHold(t *testing.T) {
+	idempotencyKey := "test-key"
+	walletID := "1234"
+	holdID := "hold-1"
+	userID := "2222"
 
+	t.Run("should return unauthorized response if the user does not own the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
 		databaseMock := mocks2.NewMockDatabase(ctrl)
 		walletService := walletServiceV1{DB: databaseMock}
-		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
-		databaseMock.EXPECT().GetTransactions(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(transactions, nil)
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(false, nil)
+		resp := walletService.VoidHold(context.Background(), idempotencyKey, walletID, holdID, userID)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	})
 
-		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, 10, 0)
+	t.Run("should return bad request response if the hold does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(nil, nil)
+		resp := walletService.VoidHold(context.Background(), idempotencyKey, walletID, holdID, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "hold does not exist", resp.Message)
+	})
 
-		assert.NotNil(t, resp)
+	t.Run("should return success response with the voided hold", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		hold := &models.Hold{ID: holdID, WalletID: walletID, Amount: commons.MustMoney("10"), Currency: "USD",
+			Status: string(commons.HoldStatusActive), ExpiresAt: time.Now().Add(time.Hour)}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), userID, idempotencyKey, gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), userID, idempotencyKey, gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().VoidHold(gomock.Any(), holdID, gomock.Cond(func(key *models.IdempotencyKey) bool {
+			return key.UserID == userID && key.Token == idempotencyKey
+		})).DoAndReturn(func(_ context.Context, _ string, _ *models.IdempotencyKey) (*models.Hold, error) {
+			voided := *hold
+			voided.Status = string(commons.HoldStatusVoided)
+			return &voided, nil
+		})
+		resp := walletService.VoidHold(context.Background(), idempotencyKey, walletID, holdID, userID)
 		assert.Equal(t, http.StatusOK, resp.Status)
-		assert.Equal(t, "transaction history retrieval successful", resp.Message)
-		if trsResp, ok := resp.Data.(responses.TransactionHistoryResponse); ok {
-			assert.Equal(t, walletID, trsResp.WalletID)
-			assert.Equal(t, len(transactions), len(trsResp.Transactions))
-			for i, tr := range transactions {
-				assert.Equal(t, tr.ToWalletID.String, trsResp.Transactions[i].ToWalletID)
-				assert.Equal(t, tr.Type, trsResp.Transactions[i].Type)
-				assert.Equal(t, tr.CreatedAt.UnixNano(), trsResp.Transactions[i].CreatedAt.UnixNano())
-			}
+		assert.Equal(t, "hold voided successfully", resp.Message)
+		if holdResp, ok := resp.Data.(*responses.HoldResponse); ok {
+			assert.Equal(t, "voided", holdResp.Status)
 		} else {
-			assert.Fail(t, "cannot cast into response")
+			assert.Fail(t, "cannot cast the response")
 		}
 	})
 
+	t.Run("should return the stored response to a retry of a completed void", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		hold := &models.Hold{ID: holdID, WalletID: walletID, Status: string(commons.HoldStatusVoided), ExpiresAt: time.Now().Add(time.Hour)}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), userID, idempotencyKey, gomock.Any(), gomock.Any()).Return(false, nil)
+		idempotencyMock.EXPECT().Get(gomock.Any(), userID, idempotencyKey).
+			Return(`{"fingerprint":"void-1234-hold-1-0","wallet_id":"1234","status":200,"message":"hold voided successfully"}`, true, nil)
+		resp := walletService.VoidHold(context.Background(), idempotencyKey, walletID, holdID, userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "hold voided successfully", resp.Message)
+	})
+
+	t.Run("should return bad request response if the hold is not active", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		hold := &models.Hold{ID: holdID, WalletID: walletID, Status: string(commons.HoldStatusCaptured), ExpiresAt: time.Now().Add(time.Hour)}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		resp := walletService.VoidHold(context.Background(), idempotencyKey, walletID, holdID, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "hold is not active", resp.Message)
+	})
+
+	t.Run("should return bad request response if the hold expired in the meantime", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		hold := &models.Hold{ID: holdID, WalletID: walletID, Status: string(commons.HoldStatusActive), ExpiresAt: time.Now().Add(time.Hour)}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetHold(gomock.Any(), holdID).Return(hold, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().VoidHold(gomock.Any(), holdID, gomock.Any()).Return(nil, commons.HoldExpiredError)
+		resp := walletService.VoidHold(context.Background(), idempotencyKey, walletID, holdID, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "hold expired", resp.Message)
+	})
+}
+
+func TestWalletServiceV1_ReverseTransaction(t *testing.T) {
+	idempotencyKey := "test-key"
+	senderID := "1234"
+	recipientID := "5678"
+	txnID := "txn-1"
+	userID := "2222"
+	deposit := func() *models.Transaction {
+		return &models.Transaction{ID: txnID, WalletID: recipientID, Type: string(commons.TransactionTypeDeposit),
+			Amount: commons.MustMoney("100"), Currency: "USD"}
+	}
+	transfer := func() *models.Transaction {
+		return &models.Transaction{ID: txnID, WalletID: senderID, ToWalletID: sql.NullString{String: recipientID, Valid: true},
+			Type: string(commons.TransactionTypeTransfer), Amount: commons.MustMoney("100"), Currency: "USD",
+			DestCurrency: sql.NullString{String: "USD", Valid: true}}
+	}
+
+	t.Run("should return bad request response if the transaction does not involve the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), "9999", userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(transfer(), nil)
+		resp := walletService.ReverseTransaction(context.Background(), idempotencyKey, "9999", txnID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "transaction does not exist", resp.Message)
+	})
+
+	t.Run("should return bad request response if the sender tries to reverse a transfer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), senderID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(transfer(), nil)
+		resp := walletService.ReverseTransaction(context.Background(), idempotencyKey, senderID, txnID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "only the recipient of a transfer can reverse it", resp.Message)
+	})
+
+	t.Run("should return bad request response if the transaction is already fully reversed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		original := deposit()
+		original.ReversedAmount = original.Amount
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), recipientID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(original, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		resp := walletService.ReverseTransaction(context.Background(), idempotencyKey, recipientID, txnID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "transaction is already fully reversed", resp.Message)
+	})
+
+	t.Run("should return bad request response if the amount exceeds the amount left to reverse", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		original := deposit()
+		original.ReversedAmount = commons.MustMoney("60")
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), recipientID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(original, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		resp := walletService.ReverseTransaction(context.Background(), idempotencyKey, recipientID, txnID, commons.MustMoney("50"), userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "reversal amount exceeds the amount left to reverse", resp.Message)
+	})
+
+	t.Run("should reverse the rest of a deposit with a linked withdrawal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		original := deposit()
+		original.ReversedAmount = commons.MustMoney("60")
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), recipientID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(original, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			return txn.Type == string(commons.TransactionTypeWithdraw) && txn.WalletID == recipientID &&
+				txn.Amount.Equal(commons.MustMoney("40")) && txn.ReversalOf.String == txnID
+		})).Return(commons.MustMoney("10"), nil)
+		resp := walletService.ReverseTransaction(context.Background(), idempotencyKey, recipientID, txnID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "reversal successful", resp.Message)
+	})
+
+	t.Run("should return bad request response if the recipient has spent the funds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), recipientID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(transfer(), nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			return txn.Type == string(commons.TransactionTypeTransfer) && txn.WalletID == recipientID &&
+				txn.ToWalletID.String == senderID && txn.Amount.Equal(commons.MustMoney("30"))
+		})).Return(commons.ZeroMoney, commons.InsufficientBalanceError)
+		resp := walletService.ReverseTransaction(context.Background(), idempotencyKey, recipientID, txnID, commons.MustMoney("30"), userID)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+		assert.Equal(t, "insufficient balance error", resp.Message)
+	})
+
+	t.Run("should debit the recipient its share of the converted amount for cross-currency transfers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		idempotencyMock := mocks2.NewMockIdempotencyStore(ctrl)
+		walletService := walletServiceV1{DB: databaseMock, Idempotency: idempotencyMock}
+		original := transfer()
+		destAmount := commons.MustMoney("92.15")
+		original.DestAmount = &destAmount
+		original.DestCurrency = sql.NullString{String: "EUR", Valid: true}
+		original.ReversedAmount = commons.MustMoney("50")
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), recipientID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(original, nil)
+		idempotencyMock.EXPECT().SetWithExpirationIfKeyIsNotSet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		idempotencyMock.EXPECT().SetWithExpiration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), commons.IdempotencyResponseTTL).Return(nil)
+		databaseMock.EXPECT().InsertTxnAndGetWalletBalance(gomock.Any(), gomock.Cond(func(txn *models.Transaction) bool {
+			// 92.15 - round(92.15 / 2) = 46.07 left to give back for the remaining 50 USD
+			return txn.Currency == "EUR" && txn.Amount.Equal(commons.MustMoney("46.07")) &&
+				txn.CreditedAmount().Equal(commons.MustMoney("50")) && txn.DestCurrency.String == "USD"
+		})).Return(commons.ZeroMoney, nil)
+		resp := walletService.ReverseTransaction(context.Background(), idempotencyKey, recipientID, txnID, commons.ZeroMoney, userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		if balanceResp, ok := resp.Data.(responses.WalletBalanceResponse); ok {
+			assert.Equal(t, "EUR", balanceResp.Currency)
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
+	})
+}
+
+func TestWalletServiceV1_GetTransaction(t *testing.T) {
+	senderID := "1234"
+	recipientID := "5678"
+	txnID := "txn-1"
+	userID := "2222"
+	transfer := &models.Transaction{ID: txnID, WalletID: senderID, ToWalletID: sql.NullString{String: recipientID, Valid: true},
+		Type: string(commons.TransactionTypeTransfer), Amount: commons.MustMoney("100"), Currency: "USD"}
+
+	t.Run("should return unauthorized response if the user does not own the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), senderID, userID).Return(false, nil)
+		resp := walletService.GetTransaction(context.Background(), senderID, txnID, userID)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	})
+
+	t.Run("should return not found response if the transaction does not involve the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), "9999", userID).Return(true, nil)
+		databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(transfer, nil)
+		resp := walletService.GetTransaction(context.Background(), "9999", txnID, userID)
+		assert.Equal(t, http.StatusNotFound, resp.Status)
+		assert.Equal(t, "transaction does not exist", resp.Message)
+	})
+
+	t.Run("should describe the transfer from the point of view of either side", func(t *testing.T) {
+		for walletID, expected := range map[string][2]string{
+			senderID:    {"debit", recipientID},
+			recipientID: {"credit", senderID},
+		} {
+			ctrl := gomock.NewController(t)
+			databaseMock := mocks2.NewMockDatabase(ctrl)
+			walletService := walletServiceV1{DB: databaseMock}
+			databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+			databaseMock.EXPECT().GetTransaction(gomock.Any(), txnID).Return(transfer, nil)
+			balanceAfter := commons.MustMoney("50")
+			databaseMock.EXPECT().GetWalletPosting(gomock.Any(), walletID, txnID).
+				Return(&models.Posting{AccountID: walletID, Seq: sql.NullInt64{Int64: 7, Valid: true}, BalanceAfter: &balanceAfter}, nil)
+			resp := walletService.GetTransaction(context.Background(), walletID, txnID, userID)
+			assert.Equal(t, http.StatusOK, resp.Status)
+			if detail, ok := resp.Data.(responses.TransactionDetailResponse); ok {
+				assert.Equal(t, txnID, detail.ID)
+				assert.Equal(t, expected[0], detail.Direction)
+				assert.Equal(t, expected[1], detail.CounterpartyWalletID)
+				assert.Equal(t, int64(7), detail.Seq)
+				assert.True(t, commons.MustMoney("50").Equal(*detail.BalanceAfter))
+			} else {
+				assert.Fail(t, "cannot cast the response")
+			}
+			ctrl.Finish()
+		}
+	})
+}
+
+func TestWalletServiceV1_GetBalanceAsOf(t *testing.T) {
+	walletID := "1234"
+	userID := "2222"
+	asOf := time.Date(2026, 9, 30, 23, 59, 59, 999999000, time.UTC)
+
+	t.Run("should return unauthorized response if the user does not own the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(false, nil)
+		resp := walletService.GetBalanceAsOf(context.Background(), walletID, userID, asOf)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	})
+
+	t.Run("should return the balance left by the last transaction before the point in time", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		balanceAfter := commons.MustMoney("72.5")
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "EUR", Balance: commons.MustMoney("10")}, nil)
+		databaseMock.EXPECT().GetLatestWalletPosting(gomock.Any(), walletID, asOf).
+			Return(&models.Posting{TransactionID: "txn-9", Seq: sql.NullInt64{Int64: 9, Valid: true}, BalanceAfter: &balanceAfter}, nil)
+
+		resp := walletService.GetBalanceAsOf(context.Background(), walletID, userID, asOf)
+
+		assert.Equal(t, http.StatusOK, resp.Status)
+		balance := resp.Data.(responses.WalletBalanceResponse)
+		assert.True(t, balanceAfter.Equal(balance.Balance))
+		assert.Equal(t, "EUR", balance.Currency)
+		assert.Equal(t, "txn-9", balance.TransactionID)
+		assert.Equal(t, int64(9), *balance.Seq)
+		assert.Equal(t, asOf, *balance.AsOf)
+		assert.Nil(t, balance.AvailableBalance)
+	})
+
+	t.Run("should return a zero balance before the first transaction", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "EUR"}, nil)
+		databaseMock.EXPECT().GetLatestWalletPosting(gomock.Any(), walletID, asOf).Return(nil, nil)
+
+		resp := walletService.GetBalanceAsOf(context.Background(), walletID, userID, asOf)
+
+		assert.Equal(t, http.StatusOK, resp.Status)
+		balance := resp.Data.(responses.WalletBalanceResponse)
+		assert.True(t, balance.Balance.IsZero())
+		assert.Empty(t, balance.TransactionID)
+		assert.Equal(t, int64(0), *balance.Seq)
+	})
+}
+
+func TestWalletServiceV1_GetTransactionHistory(t *testing.T) {
+	userID := "2222"
+	walletID := "1234567"
+
+	t.Run("should return bad request response if the user is not authorized for the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, nil, 10, 0)
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+		assert.Equal(t, "unauthorized access to the wallet", resp.Message)
+	})
+
+	t.Run("should return bad request response without reading the history if the limit is too large", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		walletService := walletServiceV1{DB: mocks2.NewMockDatabase(ctrl)}
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, nil, math.MaxInt32, 0)
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+	})
+
+	t.Run("should return internal server error upon errors when checking the wallet ownership", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("test db error"))
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, nil, 10, 0)
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, resp.Status)
+		assert.Equal(t, "error while checking wallet ownership", resp.Message)
+		assert.Equal(t, "test db error", resp.Error.Error())
+	})
+
+	t.Run("should return internal server error upon errors when retrieving the transaction history", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Seq: 42}, nil)
+		databaseMock.EXPECT().GetTransactions(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("test db error"))
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, nil, 10, 0)
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, resp.Status)
+		assert.Equal(t, "failed to fetch transactions", resp.Message)
+		assert.Equal(t, "test db error", resp.Error.Error())
+	})
+
+	t.Run("should return success response after retrieving the balance", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		transactions := []*models.Transaction{
+			{
+				WalletID:  "aa4d319f-2b7a-436e-a582-b862fc930e2b",
+				Type:      "deposit",
+				Amount:    commons.NewMoneyFromInt(100),
+				CreatedAt: time.Now(),
+			},
+			{
+				WalletID:  "d8e02c06-339a-49b2-8ffd-310fd2ec1947",
+				Type:      "withdrawal",
+				Amount:    commons.NewMoneyFromInt(20),
+				CreatedAt: time.Now(),
+			},
+			{
+				WalletID:   "a5fd3b19-8caa-412a-94e6-4b68a1fcac4f",
+				Type:       "transfer",
+				ToWalletID: sql.NullString{String: "e0925d9a-03ce-4639-b78b-1a52fec87ac4", Valid: true},
+				Amount:     commons.NewMoneyFromInt(40),
+				CreatedAt:  time.Now(),
+			},
+		}
+
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Seq: 42}, nil)
+		databaseMock.EXPECT().GetTransactions(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(transactions, nil)
+
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, nil, 10, 0)
+
+		assert.NotNil(t, resp)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "transaction history retrieval successful", resp.Message)
+		if trsResp, ok := resp.Data.(responses.TransactionHistoryResponse); ok {
+			assert.Equal(t, walletID, trsResp.WalletID)
+			assert.Equal(t, int64(42), trsResp.LatestSeq)
+			assert.Equal(t, len(transactions), len(trsResp.Transactions))
+			for i, tr := range transactions {
+				assert.Equal(t, tr.ToWalletID.String, trsResp.Transactions[i].ToWalletID)
+				assert.Equal(t, tr.Type, trsResp.Transactions[i].Type)
+				assert.Equal(t, tr.CreatedAt.UnixNano(), trsResp.Transactions[i].CreatedAt.UnixNano())
+			}
+		} else {
+			assert.Fail(t, "cannot cast into response")
+		}
+	})
+
+	t.Run("should show incoming and outgoing transfers from the perspective of the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		destAmount := commons.MustMoney("92.15")
+		transactions := []*models.Transaction{
+			{
+				WalletID:     "a5fd3b19-8caa-412a-94e6-4b68a1fcac4f",
+				ToWalletID:   sql.NullString{String: walletID, Valid: true},
+				Type:         "transfer",
+				Amount:       commons.NewMoneyFromInt(100),
+				Fee:          commons.MustMoney("0.5"),
+				Currency:     "USD",
+				DestAmount:   &destAmount,
+				DestCurrency: sql.NullString{String: "EUR", Valid: true},
+			},
+			{
+				WalletID:   walletID,
+				ToWalletID: sql.NullString{String: "e0925d9a-03ce-4639-b78b-1a52fec87ac4", Valid: true},
+				Type:       "transfer",
+				Amount:     commons.NewMoneyFromInt(40),
+				Fee:        commons.MustMoney("0.2"),
+				Currency:   "EUR",
+			},
+			{
+				WalletID: walletID,
+				Type:     "deposit",
+				Amount:   commons.NewMoneyFromInt(10),
+				Currency: "EUR",
+			},
+		}
+
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Seq: 42}, nil)
+		databaseMock.EXPECT().GetTransactions(gomock.Any(), walletID, gomock.Any(), gomock.Any(), gomock.Any()).Return(transactions, nil)
+
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, nil, 10, 0)
+
+		assert.Equal(t, http.StatusOK, resp.Status)
+		if trsResp, ok := resp.Data.(responses.TransactionHistoryResponse); ok {
+			incoming, outgoing, deposit := trsResp.Transactions[0], trsResp.Transactions[1], trsResp.Transactions[2]
+			assert.Equal(t, "credit", incoming.Direction)
+			assert.Equal(t, "a5fd3b19-8caa-412a-94e6-4b68a1fcac4f", incoming.CounterpartyWalletID)
+			assert.True(t, commons.MustMoney("92.15").Equal(incoming.SignedAmount))
+			assert.Equal(t, "debit", outgoing.Direction)
+			assert.Equal(t, "e0925d9a-03ce-4639-b78b-1a52fec87ac4", outgoing.CounterpartyWalletID)
+			assert.True(t, commons.MustMoney("-40.2").Equal(outgoing.SignedAmount))
+			assert.Equal(t, "credit", deposit.Direction)
+			assert.Empty(t, deposit.CounterpartyWalletID)
+			assert.True(t, commons.MustMoney("10").Equal(deposit.SignedAmount))
+		} else {
+			assert.Fail(t, "cannot cast into response")
+		}
+	})
+
+	t.Run("should page with cursors and fetch one extra transaction to detect the next page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		createdAt := time.Date(2026, 9, 14, 10, 30, 0, 0, time.UTC)
+		transactions := []*models.Transaction{
+			{ID: "tx-3", WalletID: walletID, Type: "deposit", Amount: commons.NewMoneyFromInt(30), CreatedAt: createdAt.Add(2 * time.Minute)},
+			{ID: "tx-2", WalletID: walletID, Type: "deposit", Amount: commons.NewMoneyFromInt(20), CreatedAt: createdAt.Add(time.Minute)},
+			{ID: "tx-1", WalletID: walletID, Type: "deposit", Amount: commons.NewMoneyFromInt(10), CreatedAt: createdAt},
+		}
+		cursor := &models.TransactionCursor{CreatedAt: createdAt.Add(3 * time.Minute), ID: "tx-4"}
+
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Seq: 42}, nil)
+		databaseMock.EXPECT().GetTransactions(gomock.Any(), walletID, gomock.Any(), int32(3), int32(0)).Return(transactions, nil)
+
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, &models.TransactionFilter{Cursor: cursor}, 2, 5)
+
+		assert.Equal(t, http.StatusOK, resp.Status)
+		trsResp, ok := resp.Data.(responses.TransactionHistoryResponse)
+		if !assert.True(t, ok, "cannot cast into response") {
+			return
+		}
+		assert.Len(t, trsResp.Transactions, 2)
+		assert.Equal(t, int32(0), trsResp.Offset)
+		next, err := models.DecodeTransactionCursor(trsResp.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "tx-2", next.ID)
+		assert.False(t, next.Backward)
+		prev, err := models.DecodeTransactionCursor(trsResp.PrevCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "tx-3", prev.ID)
+		assert.True(t, prev.Backward)
+	})
+
+	t.Run("should omit the cursors on a single page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		transactions := []*models.Transaction{{ID: "tx-1", WalletID: walletID, Type: "deposit", Amount: commons.NewMoneyFromInt(10), CreatedAt: time.Now()}}
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Seq: 42}, nil)
+		databaseMock.EXPECT().GetTransactions(gomock.Any(), walletID, gomock.Any(), int32(11), int32(0)).Return(transactions, nil)
+
+		resp := walletService.GetTransactionHistory(context.Background(), walletID, userID, nil, 10, 0)
+
+		trsResp := resp.Data.(responses.TransactionHistoryResponse)
+		assert.Empty(t, trsResp.NextCursor)
+		assert.Empty(t, trsResp.PrevCursor)
+	})
+}
+
+func TestWalletServiceV1_GetStatement(t *testing.T) {
+	walletID := "1234"
+	userID := "2222"
+	periodStart := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+
+	t.Run("should return unauthorized response if the user does not own the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(false, nil)
+		resp := walletService.GetStatement(context.Background(), walletID, userID, periodStart, periodEnd)
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	})
+
+	t.Run("should return the persisted statement of a closed period", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetStatement(gomock.Any(), walletID, periodStart, periodEnd).Return(&models.Statement{
+			WalletID: walletID, PeriodStart: periodStart, PeriodEnd: periodEnd,
+			Content: []byte(`{"wallet_id":"1234","closed":true,"opening_balance":"10","closing_balance":"25","transactions":[]}`),
+		}, nil)
+
+		resp := walletService.GetStatement(context.Background(), walletID, userID, periodStart, periodEnd)
+
+		assert.Equal(t, http.StatusOK, resp.Status)
+		statement := resp.Data.(responses.StatementResponse)
+		assert.True(t, statement.Closed)
+		assert.True(t, commons.MustMoney("25").Equal(statement.ClosingBalance))
+	})
+
+	t.Run("should build and persist the statement of a closed period", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		opening := commons.MustMoney("100")
+		transactions := []*models.Transaction{
+			{ID: "tx-1", WalletID: walletID, Type: "deposit", Amount: commons.MustMoney("50"), Currency: "EUR"},
+			{ID: "tx-2", WalletID: walletID, Type: "withdrawal", Amount: commons.MustMoney("20"), Fee: commons.MustMoney("0.5"), Currency: "EUR"},
+			{ID: "tx-3", WalletID: "5678", ToWalletID: sql.NullString{String: walletID, Valid: true}, Type: "transfer", Amount: commons.MustMoney("5"), Currency: "EUR"},
+			{ID: "tx-4", WalletID: walletID, Type: "deposit", Amount: commons.MustMoney("1"), Currency: "EUR"},
+		}
+
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetStatement(gomock.Any(), walletID, periodStart, periodEnd).Return(nil, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "EUR"}, nil)
+		databaseMock.EXPECT().GetLatestWalletPosting(gomock.Any(), walletID, periodStart.Add(-time.Microsecond)).
+			Return(&models.Posting{BalanceAfter: &opening}, nil)
+		databaseMock.EXPECT().GetTransactions(gomock.Any(), walletID, gomock.Cond(func(filter *models.TransactionFilter) bool {
+			return filter.From.Equal(periodStart) && filter.To.Equal(periodEnd) && filter.Ascending
+		}), int32(commons.StatementPageSize), int32(0)).Return(transactions, nil)
+		databaseMock.EXPECT().SaveStatement(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, statement *models.Statement) (*models.Statement, error) {
+			return statement, nil
+		})
+
+		resp := walletService.GetStatement(context.Background(), walletID, userID, periodStart, periodEnd)
+
+		assert.Equal(t, http.StatusOK, resp.Status)
+		statement := resp.Data.(responses.StatementResponse)
+		assert.True(t, statement.Closed)
+		assert.Equal(t, "EUR", statement.Currency)
+		assert.True(t, opening.Equal(statement.OpeningBalance))
+		assert.True(t, commons.MustMoney("135.5").Equal(statement.ClosingBalance))
+		assert.Len(t, statement.Transactions, 4)
+		assert.True(t, commons.MustMoney("129.5").Equal(*statement.Transactions[1].BalanceAfter))
+		assert.Len(t, statement.Totals, 3)
+		deposits, withdrawals, transfers := statement.Totals[0], statement.Totals[1], statement.Totals[2]
+		assert.Equal(t, "deposit", deposits.Type)
+		assert.Equal(t, 2, deposits.Count)
+		assert.True(t, commons.MustMoney("51").Equal(deposits.Credits))
+		assert.True(t, commons.MustMoney("20.5").Equal(withdrawals.Debits))
+		assert.True(t, commons.MustMoney("5").Equal(transfers.Credits))
+	})
+
+	t.Run("should not persist the statement of an open period", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		start := time.Now().UTC().Truncate(24 * time.Hour)
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "EUR"}, nil)
+		databaseMock.EXPECT().GetLatestWalletPosting(gomock.Any(), walletID, gomock.Any()).Return(nil, nil)
+		databaseMock.EXPECT().GetTransactions(gomock.Any(), walletID, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		resp := walletService.GetStatement(context.Background(), walletID, userID, start, start.AddDate(0, 0, 1))
+
+		assert.Equal(t, http.StatusOK, resp.Status)
+		statement := resp.Data.(responses.StatementResponse)
+		assert.False(t, statement.Closed)
+		assert.True(t, statement.OpeningBalance.IsZero())
+		assert.True(t, statement.ClosingBalance.IsZero())
+		assert.Empty(t, statement.Transactions)
+	})
+
+	t.Run("should read the transactions page by page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		createdAt := periodStart.Add(time.Hour)
+		fullPage := make([]*models.Transaction, commons.StatementPageSize)
+		for i := range fullPage {
+			fullPage[i] = &models.Transaction{ID: fmt.Sprintf("tx-%d", i), WalletID: walletID, Type: "deposit", Amount: commons.MustMoney("1"), CreatedAt: createdAt}
+		}
+
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CheckWalletOwner(gomock.Any(), walletID, userID).Return(true, nil)
+		databaseMock.EXPECT().GetStatement(gomock.Any(), walletID, periodStart, periodEnd).Return(nil, nil)
+		databaseMock.EXPECT().GetWallet(gomock.Any(), walletID).Return(&models.Wallet{ID: walletID, Currency: "EUR"}, nil)
+		databaseMock.EXPECT().GetLatestWalletPosting(gomock.Any(), walletID, gomock.Any()).Return(nil, nil)
+		gomock.InOrder(
+			databaseMock.EXPECT().GetTransactions(gomock.Any(), walletID, gomock.Any(), gomock.Any(), gomock.Any()).Return(fullPage, nil),
+			databaseMock.EXPECT().GetTransactions(gomock.Any(), walletID, gomock.Cond(func(filter *models.TransactionFilter) bool {
+				return filter.Cursor != nil && filter.Cursor.ID == fullPage[len(fullPage)-1].ID && !filter.Cursor.Backward
+			}), gomock.Any(), gomock.Any()).Return(fullPage[:1], nil),
+		)
+		databaseMock.EXPECT().SaveStatement(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, statement *models.Statement) (*models.Statement, error) {
+			return statement, nil
+		})
+
+		resp := walletService.GetStatement(context.Background(), walletID, userID, periodStart, periodEnd)
+
+		statement := resp.Data.(responses.StatementResponse)
+		assert.Len(t, statement.Transactions, commons.StatementPageSize+1)
+		assert.True(t, commons.NewMoneyFromInt(commons.StatementPageSize+1).Equal(statement.ClosingBalance))
+	})
+}
+
+func TestWalletServiceV1_CreateWallet(t *testing.T) {
+	userID := "2222"
+
+	t.Run("should return unauthorized response if the user does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CreateWallet(gomock.Any(), userID, "Savings", "EUR").Return(nil, nil)
+		resp := walletService.CreateWallet(context.Background(), userID, "Savings", "EUR")
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+		assert.Equal(t, "user does not exist", resp.Message)
+	})
+
+	t.Run("should return internal server error upon errors when creating the wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CreateWallet(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("test db error"))
+		resp := walletService.CreateWallet(context.Background(), userID, "", "USD")
+		assert.Equal(t, http.StatusInternalServerError, resp.Status)
+		assert.Equal(t, "failed to create wallet", resp.Message)
+	})
+
+	t.Run("should return the created wallet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().CreateWallet(gomock.Any(), userID, "Savings", "EUR").Return(&models.Wallet{
+			ID:       "5678",
+			UserID:   userID,
+			Label:    sql.NullString{String: "Savings", Valid: true},
+			Currency: "EUR",
+		}, nil)
+		resp := walletService.CreateWallet(context.Background(), userID, "Savings", "EUR")
+		assert.Equal(t, http.StatusCreated, resp.Status)
+		if wallet, ok := resp.Data.(*responses.WalletResponse); ok {
+			assert.Equal(t, "5678", wallet.WalletID)
+			assert.Equal(t, "Savings", wallet.Label)
+			assert.Equal(t, "EUR", wallet.Currency)
+			assert.True(t, wallet.Balance.IsZero())
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
+	})
+}
+
+func TestWalletServiceV1_GetWallets(t *testing.T) {
+	userID := "2222"
+
+	t.Run("should return internal server error upon errors when retrieving the wallets", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().GetUserWallets(gomock.Any(), userID).Return(nil, errors.New("test db error"))
+		resp := walletService.GetWallets(context.Background(), userID)
+		assert.Equal(t, http.StatusInternalServerError, resp.Status)
+		assert.Equal(t, "failed to fetch wallets", resp.Message)
+	})
+
+	t.Run("should return the wallets of the user with their balances", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().GetUserWallets(gomock.Any(), userID).Return([]*models.Wallet{
+			{ID: "1234", UserID: userID, Currency: "USD", Balance: commons.MustMoney("10.5")},
+			{ID: "5678", UserID: userID, Label: sql.NullString{String: "Travel", Valid: true}, Currency: "EUR", Balance: commons.MustMoney("3")},
+		}, nil)
+		resp := walletService.GetWallets(context.Background(), userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		if list, ok := resp.Data.(responses.WalletListResponse); ok {
+			assert.Equal(t, userID, list.UserID)
+			assert.Len(t, list.Wallets, 2)
+			assert.True(t, commons.MustMoney("10.5").Equal(list.Wallets[0].Balance))
+			assert.Equal(t, "Travel", list.Wallets[1].Label)
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
+	})
+
+	t.Run("should return an empty list if the user has no wallets", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		databaseMock := mocks2.NewMockDatabase(ctrl)
+		walletService := walletServiceV1{DB: databaseMock}
+		databaseMock.EXPECT().GetUserWallets(gomock.Any(), userID).Return(nil, nil)
+		resp := walletService.GetWallets(context.Background(), userID)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		if list, ok := resp.Data.(responses.WalletListResponse); ok {
+			assert.NotNil(t, list.Wallets)
+			assert.Empty(t, list.Wallets)
+		} else {
+			assert.Fail(t, "cannot cast the response")
+		}
+	})
 }